@@ -2,16 +2,15 @@ package main
 
 import (
 	"bufio"
-	"dbService/utils"
+	"context"
+	"dbService/dbclient"
+	"errors"
 	"fmt"
 	"github.com/joho/godotenv"
 	"log"
-	"math/rand"
-	"net/rpc"
 	"os"
 	"strconv"
 	"strings"
-	"time"
 )
 
 var (
@@ -33,16 +32,16 @@ func init() {
 
 func main() {
 
-	serverIndex := GetRandomIndex()
-	fmt.Printf("Connecting to server: %d\n", BasePort+serverIndex)
-
-	// Connessione al server RPC
-	client, err := rpc.Dial("tcp", "localhost:"+strconv.Itoa(BasePort+serverIndex))
+	// Il client mantiene una connessione verso ogni replica: le richieste sono inoltrate sempre alla stessa replica,
+	// scelta a caso, e passano a un'altra replica se questa non è più raggiungibile
+	endpoints := dbclient.LocalEndpoints(BasePort, NumReplicas)
+	client, err := dbclient.Dial(context.Background(), dbclient.Config{Endpoints: endpoints, Balancer: dbclient.NewSticky()})
 	if err != nil {
 		log.Fatal("Error in dialing: ", err)
 	}
+	fmt.Printf("Connected to servers: %s\n", strings.Join(endpoints, ", "))
 
-	defer func(client *rpc.Client) {
+	defer func(client *dbclient.Client) {
 		err := client.Close()
 		if err != nil {
 			log.Fatal("Error while closing connection:", err)
@@ -67,8 +66,7 @@ func main() {
 			continue
 		}
 
-		args := utils.Args{}
-		var reply utils.Result
+		ctx := context.Background()
 
 		switch choice {
 		case 1: // GET
@@ -76,14 +74,17 @@ func main() {
 			key, _ := reader.ReadString('\n')
 			key = strings.TrimSpace(key)
 
-			args.Key = key
-
-			err := client.Call("Datastore.Get", args, &reply)
+			reply, err := client.Get(ctx, key)
+			if errors.Is(err, dbclient.ErrNotFound) {
+				fmt.Println("Risultato: NOT FOUND")
+				continue
+			}
 			if err != nil {
-				log.Fatal("Error while executing GET:", err)
+				fmt.Println("Error while executing GET:", err)
+				continue
 			}
 
-			fmt.Print("Risultato: " + reply.Value + "\n")
+			fmt.Print("Risultato: " + string(reply.Value) + "\n")
 
 		case 2: // PUT
 			fmt.Print("Inserisci la chiave: ")
@@ -94,39 +95,29 @@ func main() {
 			value, _ := reader.ReadString('\n')
 			value = strings.TrimSpace(value)
 
-			args.Key = key
-			args.Value = value
-
-			err := client.Call("Datastore.Put", args, &reply)
+			err := client.Put(ctx, key, []byte(value))
 			if err != nil {
-				log.Fatal("Error while executing PUT:", err)
+				fmt.Println("Error while executing PUT:", err)
+				continue
 			}
 
-			fmt.Print("Risultato: " + reply.Value)
+			fmt.Println("Risultato: OK")
 
 		case 3: // DELETE
 			fmt.Print("Inserisci la chiave: ")
 			key, _ := reader.ReadString('\n')
 			key = strings.TrimSpace(key)
 
-			args.Key = key
-
-			err := client.Call("Datastore.Delete", args, &reply)
+			err := client.Delete(ctx, key)
 			if err != nil {
-				log.Fatal("Error while executing DELETE:", err)
+				fmt.Println("Error while executing DELETE:", err)
+				continue
 			}
 
-			fmt.Print("Risultato: " + reply.Value)
+			fmt.Println("Risultato: OK")
 
 		default:
 			fmt.Println("Scelta non valida, riprova.")
 		}
 	}
 }
-
-// GetRandomIndex ritorna un indice casuale tra 0 e NumReplicas - 1 (inclusi)
-func GetRandomIndex() int {
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	return r.Intn(NumReplicas)
-
-}
@@ -0,0 +1,6 @@
+// Package datastorepb contiene la definizione protobuf del servizio Datastore (datastore.proto) e il codice Go generato da essa.
+// Il codice generato è incluso nel repository, così che la compilazione non richieda protoc né i suoi plugin.
+// Dopo aver modificato datastore.proto va rigenerato con go generate, avendo installato protoc, protoc-gen-go e protoc-gen-go-grpc.
+package datastorepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative datastore.proto
@@ -0,0 +1,48 @@
+package dbclient
+
+import (
+	"context"
+	"dbService/utils"
+)
+
+// CreateNamespace crea un namespace con la configurazione indicata
+func (client *Client) CreateNamespace(ctx context.Context, config utils.NamespaceArgs) (utils.NamespaceInfo, error) {
+	var result utils.NamespaceInfo
+	err := client.call(ctx, "CreateNamespace", config, &result)
+	return result, err
+}
+
+// ListNamespaces restituisce i namespace presenti sulla replica
+func (client *Client) ListNamespaces(ctx context.Context) ([]utils.NamespaceInfo, error) {
+	var result utils.NamespaceList
+	err := client.call(ctx, "ListNamespaces", utils.NamespaceArgs{}, &result)
+	return result.Namespaces, err
+}
+
+// DropNamespace rimuove un namespace con tutte le sue chiavi
+func (client *Client) DropNamespace(ctx context.Context, name string) error {
+	return client.call(ctx, "DropNamespace", utils.NamespaceArgs{Name: name}, &utils.NamespaceInfo{})
+}
+
+// Status restituisce lo stato interno della replica a cui è inoltrata la richiesta: clock, code di messaggi, numeri di sequenza e dimensione dei namespace.
+// Per conoscere lo stato di una replica specifica il client deve essere configurato con il solo indirizzo di quella replica.
+func (client *Client) Status(ctx context.Context) (utils.ReplicaStatus, error) {
+	var result utils.ReplicaStatus
+	err := client.call(ctx, "Status", utils.StatusArgs{}, &result)
+	return result, err
+}
+
+// Dump restituisce tutte le entry del namespace del client presenti sulla replica a cui è inoltrata la richiesta, in ordine lessicografico
+func (client *Client) Dump(ctx context.Context) ([]utils.Result, error) {
+	var result utils.DumpResult
+	err := client.call(ctx, "Dump", utils.DumpArgs{Namespace: client.namespace}, &result)
+	return result.Entries, err
+}
+
+// Backup restituisce una snapshot consistente di tutti i namespace presenti sulla replica a cui è inoltrata la richiesta,
+// con la posizione delle scritture incluse: una scrittura è inclusa se la sua versione precede o coincide con Cut
+func (client *Client) Backup(ctx context.Context) (utils.BackupResult, error) {
+	var result utils.BackupResult
+	err := client.call(ctx, "Backup", utils.BackupArgs{}, &result)
+	return result, err
+}
@@ -0,0 +1,111 @@
+package dbclient
+
+import (
+	"context"
+	"dbService/utils"
+)
+
+// Future rappresenta l'esito di un'operazione inviata senza attenderne la risposta.
+// Le operazioni asincrone di una sessione sono eseguite dalla replica nell'ordine con cui sono state inviate,
+// anche se le loro risposte possono essere ricevute in un ordine diverso.
+type Future[T any] struct {
+	done   chan struct{}
+	result T
+	err    error
+}
+
+// newFuture crea un Future che viene completato con l'esito della richiesta inviata su done
+func newFuture[T any](done <-chan error, result *T, complete func(T, error) (T, error)) *Future[T] {
+	future := &Future[T]{done: make(chan struct{})}
+	go func() {
+		err := <-done
+		future.result, future.err = complete(*result, err)
+		close(future.done)
+	}()
+	return future
+}
+
+// Done restituisce un canale che viene chiuso al completamento dell'operazione
+func (future *Future[T]) Done() <-chan struct{} {
+	return future.done
+}
+
+// Wait attende il completamento dell'operazione e ne restituisce il risultato, con gli stessi errori della corrispondente operazione sincrona
+func (future *Future[T]) Wait() (T, error) {
+	<-future.done
+	return future.result, future.err
+}
+
+// Then invoca la callback con l'esito dell'operazione, al suo completamento, in una nuova goroutine
+func (future *Future[T]) Then(callback func(T, error)) {
+	go func() {
+		callback(future.Wait())
+	}()
+}
+
+// unchanged restituisce l'esito di una richiesta così com'è
+func unchanged[T any](result T, err error) (T, error) {
+	return result, err
+}
+
+// GetAsync invia una Get senza attenderne la risposta.
+// Con consistenza causale la replica attende che la chiave sia presente, ritardando anche le operazioni successive della sessione.
+func (client *Client) GetAsync(ctx context.Context, key string) *Future[utils.Result] {
+	var result utils.Result
+	done := client.goCall(ctx, "Get", utils.Args{Namespace: client.namespace, Key: key}, &result)
+	return newFuture(done, &result, func(result utils.Result, err error) (utils.Result, error) {
+		if err != nil {
+			return utils.Result{}, err
+		}
+		if isNotFound(result) {
+			return result, &Error{Method: "Get", Endpoint: client.Endpoint(), Message: "key " + key + " not found", Kind: ErrNotFound}
+		}
+		return result, nil
+	})
+}
+
+// PutAsync invia una Put senza attenderne la risposta
+func (client *Client) PutAsync(ctx context.Context, key string, value []byte, options ...WriteOption) *Future[utils.Result] {
+	args := utils.Args{Namespace: client.namespace, Key: key, Value: value}
+	for _, option := range options {
+		option(&args)
+	}
+	var result utils.Result
+	return newFuture(client.goCall(ctx, "Put", args, &result), &result, unchanged[utils.Result])
+}
+
+// DeleteAsync invia una Delete senza attenderne la risposta
+func (client *Client) DeleteAsync(ctx context.Context, key string, options ...WriteOption) *Future[utils.Result] {
+	args := utils.Args{Namespace: client.namespace, Key: key}
+	for _, option := range options {
+		option(&args)
+	}
+	var result utils.Result
+	return newFuture(client.goCall(ctx, "Delete", args, &result), &result, unchanged[utils.Result])
+}
+
+// MultiGetAsync invia una MultiGet senza attenderne la risposta
+func (client *Client) MultiGetAsync(ctx context.Context, keys ...string) *Future[utils.BatchResult] {
+	args := utils.BatchArgs{Namespace: client.namespace}
+	for _, key := range keys {
+		args.Entries = append(args.Entries, utils.Args{Key: key})
+	}
+	var result utils.BatchResult
+	return newFuture(client.goCall(ctx, "MultiGet", args, &result), &result, unchanged[utils.BatchResult])
+}
+
+// MultiPutAsync invia una MultiPut senza attenderne la risposta
+func (client *Client) MultiPutAsync(ctx context.Context, entries []utils.Args) *Future[utils.BatchResult] {
+	var result utils.BatchResult
+	return newFuture(client.goCall(ctx, "MultiPut", utils.BatchArgs{Namespace: client.namespace, Entries: entries}, &result), &result, unchanged[utils.BatchResult])
+}
+
+// MultiDeleteAsync invia una MultiDelete senza attenderne la risposta
+func (client *Client) MultiDeleteAsync(ctx context.Context, keys ...string) *Future[utils.BatchResult] {
+	args := utils.BatchArgs{Namespace: client.namespace}
+	for _, key := range keys {
+		args.Entries = append(args.Entries, utils.Args{Key: key})
+	}
+	var result utils.BatchResult
+	return newFuture(client.goCall(ctx, "MultiDelete", args, &result), &result, unchanged[utils.BatchResult])
+}
@@ -0,0 +1,74 @@
+package dbclient
+
+import (
+	"math/rand"
+	"sync/atomic"
+)
+
+// EndpointInfo descrive una replica disponibile, tra cui il balancer sceglie quella a cui inoltrare una richiesta
+type EndpointInfo struct {
+	Address     string // Indirizzo (host:porta) della replica
+	Outstanding int    // Numero di richieste inoltrate alla replica e in attesa di risposta
+}
+
+// Balancer sceglie la replica a cui inoltrare una richiesta
+type Balancer interface {
+	// Pick restituisce l'indice della replica scelta tra quelle disponibili, che sono sempre almeno una.
+	// last è l'indirizzo della replica a cui è stata inoltrata la richiesta precedente della sessione, vuoto se assente.
+	Pick(endpoints []EndpointInfo, last string) int
+}
+
+// roundRobin inoltra le richieste a turno a tutte le repliche disponibili
+type roundRobin struct {
+	next atomic.Uint64
+}
+
+// NewRoundRobin restituisce un balancer che inoltra le richieste a turno a tutte le repliche disponibili
+func NewRoundRobin() Balancer {
+	return &roundRobin{}
+}
+
+func (balancer *roundRobin) Pick(endpoints []EndpointInfo, last string) int {
+	return int(balancer.next.Add(1)-1) % len(endpoints)
+}
+
+// leastOutstanding inoltra le richieste alla replica con meno richieste in attesa di risposta
+type leastOutstanding struct {
+	next atomic.Uint64
+}
+
+// NewLeastOutstanding restituisce un balancer che inoltra ogni richiesta alla replica con meno richieste in attesa di risposta.
+// A parità di richieste in attesa le repliche sono scelte a turno.
+func NewLeastOutstanding() Balancer {
+	return &leastOutstanding{}
+}
+
+func (balancer *leastOutstanding) Pick(endpoints []EndpointInfo, last string) int {
+	start := int(balancer.next.Add(1)-1) % len(endpoints)
+	best := start
+	for i := range endpoints {
+		index := (start + i) % len(endpoints)
+		if endpoints[index].Outstanding < endpoints[best].Outstanding {
+			best = index
+		}
+	}
+	return best
+}
+
+// sticky inoltra tutte le richieste di una sessione alla stessa replica
+type sticky struct{}
+
+// NewSticky restituisce un balancer che inoltra tutte le richieste di una sessione alla stessa replica, finché questa è disponibile.
+// La replica di una nuova sessione, o di una sessione la cui replica non è più disponibile, è scelta a caso.
+func NewSticky() Balancer {
+	return sticky{}
+}
+
+func (sticky) Pick(endpoints []EndpointInfo, last string) int {
+	for i, endpoint := range endpoints {
+		if endpoint.Address == last {
+			return i
+		}
+	}
+	return rand.Intn(len(endpoints))
+}
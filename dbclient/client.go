@@ -0,0 +1,208 @@
+// Package dbclient fornisce un client Go per il servizio Datastore esposto dalle repliche tramite RPC.
+// Ogni operazione è un metodo tipato che accetta un context.Context, con cui il chiamante ne limita la durata,
+// e restituisce errori tipati, confrontabili con errors.Is.
+package dbclient
+
+import (
+	"context"
+	"errors"
+	"net/rpc"
+	"strconv"
+	"time"
+)
+
+// serviceName è il nome con cui le repliche registrano il servizio RPC
+const serviceName = "Datastore"
+
+const (
+	defaultDialTimeout         = 5 * time.Second // Tempo massimo di connessione a una replica, se non indicato nella configurazione
+	defaultHealthCheckInterval = 2 * time.Second // Intervallo tra due health check delle repliche, se non indicato nella configurazione
+)
+
+// Config contiene la configurazione del client
+type Config struct {
+	Endpoints           []string      // Indirizzi (host:porta) delle repliche a cui il client può connettersi
+	DialTimeout         time.Duration // Tempo massimo di connessione a una singola replica, 5 secondi se non indicato
+	Balancer            Balancer      // Politica con cui scegliere la replica a cui inoltrare ogni richiesta, NewRoundRobin se non indicata
+	HealthCheckInterval time.Duration // Intervallo tra due health check delle repliche, 2 secondi se non indicato
+}
+
+// Client è un client del servizio Datastore, che mantiene un pool di connessioni verso le repliche indicate nella configurazione.
+// Ogni richiesta è inoltrata alla replica scelta dal balancer e, in caso di errore di connessione, a un'altra replica disponibile.
+// Un client rappresenta una sessione: con consistenza causale le sue letture osservano sempre le sue scritture, anche se inoltrate a repliche diverse.
+// Le richieste di una sessione sono eseguite dalle repliche nell'ordine di invio, anche quando sono inviate senza attenderne la risposta con le operazioni asincrone.
+// Può essere usato da più goroutine contemporaneamente.
+type Client struct {
+	pool      *pool
+	session   *session
+	namespace string // Namespace delle operazioni del client, vuoto per il namespace di default
+}
+
+// LocalEndpoints restituisce gli indirizzi delle repliche in esecuzione in locale, in ascolto su porte consecutive a partire da basePort
+func LocalEndpoints(basePort int, numReplicas int) []string {
+	endpoints := make([]string, numReplicas)
+	for i := range endpoints {
+		endpoints[i] = "localhost:" + strconv.Itoa(basePort+i)
+	}
+	return endpoints
+}
+
+// Dial si connette a tutte le repliche raggiungibili tra quelle indicate nella configurazione, e avvia l'health check periodico.
+// Restituisce un errore ErrUnavailable se nessuna replica è raggiungibile.
+func Dial(ctx context.Context, config Config) (*Client, error) {
+	if len(config.Endpoints) == 0 {
+		return nil, &Error{Method: "Dial", Message: "no endpoints configured", Kind: ErrInvalidArgument}
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = defaultDialTimeout
+	}
+	if config.HealthCheckInterval <= 0 {
+		config.HealthCheckInterval = defaultHealthCheckInterval
+	}
+	if config.Balancer == nil {
+		config.Balancer = NewRoundRobin()
+	}
+
+	pool := newPool(ctx, config)
+	if ctx.Err() != nil {
+		_ = pool.close()
+		return nil, ctx.Err()
+	}
+	if !pool.available() {
+		_ = pool.close()
+		return nil, &Error{Method: "Dial", Message: "no replica is reachable", Kind: ErrUnavailable}
+	}
+	pool.wg.Add(1)
+	go pool.healthCheck()
+	return &Client{pool: pool, session: &session{}}, nil
+}
+
+// Endpoint restituisce l'indirizzo della replica a cui è stata inoltrata l'ultima richiesta della sessione
+func (client *Client) Endpoint() string {
+	return client.session.lastEndpoint()
+}
+
+// Namespace restituisce un client che esegue le operazioni sul namespace indicato, condividendo il pool e la sessione con il client originale
+func (client *Client) Namespace(name string) *Client {
+	scoped := *client
+	scoped.namespace = name
+	return &scoped
+}
+
+// Session restituisce un client che condivide il pool con il client originale, ma appartiene a una nuova sessione
+func (client *Client) Session() *Client {
+	scoped := *client
+	scoped.session = &session{}
+	return &scoped
+}
+
+// Close chiude le connessioni con tutte le repliche. Va chiamata una sola volta, anche se il pool è condiviso da più client.
+func (client *Client) Close() error {
+	return client.pool.close()
+}
+
+// pendingCall rappresenta una richiesta della sessione, già numerata e assegnata a una replica, in attesa di essere inoltrata
+type pendingCall struct {
+	method   string
+	args     any
+	seq      int         // Numero di sequenza della richiesta nella sessione, 0 se la richiesta non è numerata
+	endpoint *endpoint   // Replica scelta per la richiesta, nil se nessuna replica è raggiungibile
+	conn     *rpc.Client // Connessione con la replica scelta, nil se la replica è stata scollegata
+	err      error       // Errore con cui la richiesta è stata interrotta prima di essere numerata
+}
+
+// call invoca il metodo RPC indicato su una replica scelta dal balancer, e ne attende la risposta, oppure la scadenza o la cancellazione del context.
+// In caso di errore di connessione la richiesta è inoltrata a un'altra replica, se il metodo può essere ripetuto senza effetti indesiderati.
+// In caso di scadenza restituisce l'errore del context: la richiesta potrebbe essere comunque eseguita dalla replica.
+func (client *Client) call(ctx context.Context, method string, args any, reply any) error {
+	return client.invoke(ctx, client.prepare(ctx, method, args), reply)
+}
+
+// goCall invia la richiesta come call, ma senza attenderne la risposta: l'esito è restituito sul canale.
+// La richiesta è numerata prima di restituire il canale, quindi la replica la esegue dopo le richieste inviate in precedenza dalla sessione.
+func (client *Client) goCall(ctx context.Context, method string, args any, reply any) <-chan error {
+	pending := client.prepare(ctx, method, args)
+	done := make(chan error, 1)
+	go func() {
+		done <- client.invoke(ctx, pending, reply)
+	}()
+	return done
+}
+
+// prepare numera la richiesta nella sessione e sceglie la replica a cui inoltrarla.
+// Se la sessione ha richieste in attesa di risposta, la richiesta è inoltrata alla stessa replica, che la esegue dopo di esse.
+// Una richiesta di un metodo non numerato è invece preparata solo dopo la risposta di tutte le richieste precedenti della sessione.
+func (client *Client) prepare(ctx context.Context, method string, args any) *pendingCall {
+	pending := &pendingCall{method: method}
+	if !orderedMethods[method] {
+		if err := client.session.wait(ctx); err != nil {
+			pending.err = err
+			return pending
+		}
+	}
+
+	client.session.submit.Lock()
+	defer client.session.submit.Unlock()
+	var pinned string
+	pending.args, pending.seq, pinned = client.session.begin(method, client.session.withClock(args))
+	if pinned != "" {
+		pending.endpoint, pending.conn = client.pool.pickAddress(pinned)
+	}
+	if pending.conn == nil {
+		pending.endpoint, pending.conn = client.pool.pick(client.session.lastEndpoint(), nil)
+	}
+	if pending.endpoint != nil {
+		client.session.setEndpoint(pending.endpoint.address)
+	}
+	return pending
+}
+
+// invoke inoltra una richiesta preparata alla replica scelta, o a un'altra replica in caso di errore di connessione, e ne attende la risposta
+func (client *Client) invoke(ctx context.Context, pending *pendingCall, reply any) error {
+	if pending.err != nil {
+		return pending.err
+	}
+	defer client.session.end(pending.seq)
+
+	method := pending.method
+	endpoint, conn := pending.endpoint, pending.conn
+	excluded := make(map[string]bool)
+	var errs []error
+	for {
+		if endpoint == nil {
+			return &Error{Method: method, Message: "no replica is reachable", Kind: ErrUnavailable, Err: errors.Join(errs...)}
+		}
+		if conn == nil {
+			excluded[endpoint.address] = true
+			endpoint, conn = client.pool.pick(client.session.lastEndpoint(), excluded)
+			continue
+		}
+		client.session.setEndpoint(endpoint.address)
+
+		call := conn.Go(serviceName+"."+method, pending.args, reply, make(chan *rpc.Call, 1))
+		var err error
+		select {
+		case <-ctx.Done():
+			endpoint.done()
+			return ctx.Err()
+		case <-call.Done:
+			endpoint.done()
+			err = call.Error
+		}
+
+		if !isConnectionError(err) {
+			if err == nil {
+				client.session.observe(reply)
+			}
+			return wrapError(method, endpoint.address, err)
+		}
+		// La replica non è raggiungibile: viene esclusa fino al prossimo health check
+		endpoint.disconnect(conn)
+		if !retryable(method, err) {
+			return wrapError(method, endpoint.address, err)
+		}
+		excluded[endpoint.address] = true
+		errs = append(errs, err)
+		endpoint, conn = client.pool.pick(client.session.lastEndpoint(), excluded)
+	}
+}
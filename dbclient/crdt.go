@@ -0,0 +1,76 @@
+package dbclient
+
+import (
+	"context"
+	"dbService/utils"
+)
+
+// Le operazioni sui tipi di dato CRDT sono supportate solo con consistenza causale, e non appartengono ad alcun namespace
+
+// Increment incrementa (o decrementa, se delta è negativo) un PN-counter e ne restituisce il valore
+func (client *Client) Increment(ctx context.Context, key string, delta int) (int, error) {
+	var result utils.CounterResult
+	err := client.call(ctx, "Increment", utils.CounterArgs{Key: key, Delta: delta}, &result)
+	return result.Value, err
+}
+
+// GetCounter restituisce il valore di un PN-counter
+func (client *Client) GetCounter(ctx context.Context, key string) (int, error) {
+	var result utils.CounterResult
+	err := client.call(ctx, "GetCounter", utils.Args{Key: key}, &result)
+	return result.Value, err
+}
+
+// SetAdd aggiunge un elemento a un OR-set e ne restituisce gli elementi
+func (client *Client) SetAdd(ctx context.Context, key string, element string) ([]string, error) {
+	var result utils.SetResult
+	err := client.call(ctx, "SetAdd", utils.SetArgs{Key: key, Element: element}, &result)
+	return result.Elements, err
+}
+
+// SetRemove rimuove un elemento da un OR-set e ne restituisce gli elementi
+func (client *Client) SetRemove(ctx context.Context, key string, element string) ([]string, error) {
+	var result utils.SetResult
+	err := client.call(ctx, "SetRemove", utils.SetArgs{Key: key, Element: element}, &result)
+	return result.Elements, err
+}
+
+// SetMembers restituisce gli elementi di un OR-set
+func (client *Client) SetMembers(ctx context.Context, key string) ([]string, error) {
+	var result utils.SetResult
+	err := client.call(ctx, "SetMembers", utils.Args{Key: key}, &result)
+	return result.Elements, err
+}
+
+// RegisterSet scrive il valore di un registro last-writer-wins
+func (client *Client) RegisterSet(ctx context.Context, key string, value []byte) error {
+	return client.call(ctx, "RegisterSet", utils.Args{Key: key, Value: value}, &utils.Result{})
+}
+
+// RegisterGet restituisce il valore di un registro last-writer-wins
+func (client *Client) RegisterGet(ctx context.Context, key string) (utils.Result, error) {
+	var result utils.Result
+	err := client.call(ctx, "RegisterGet", utils.Args{Key: key}, &result)
+	return result, err
+}
+
+// MapPut scrive un campo di una mappa multi-valore e ne restituisce i campi
+func (client *Client) MapPut(ctx context.Context, key string, field string, value string) (map[string][]string, error) {
+	var result utils.MapResult
+	err := client.call(ctx, "MapPut", utils.MapArgs{Key: key, Field: field, Value: value}, &result)
+	return result.Fields, err
+}
+
+// MapRemove rimuove un campo di una mappa multi-valore e ne restituisce i campi
+func (client *Client) MapRemove(ctx context.Context, key string, field string) (map[string][]string, error) {
+	var result utils.MapResult
+	err := client.call(ctx, "MapRemove", utils.MapArgs{Key: key, Field: field}, &result)
+	return result.Fields, err
+}
+
+// MapGet restituisce i campi di una mappa multi-valore
+func (client *Client) MapGet(ctx context.Context, key string) (map[string][]string, error) {
+	var result utils.MapResult
+	err := client.call(ctx, "MapGet", utils.Args{Key: key}, &result)
+	return result.Fields, err
+}
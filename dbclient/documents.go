@@ -0,0 +1,55 @@
+package dbclient
+
+import (
+	"context"
+	"dbService/utils"
+)
+
+// GetPath recupera il valore di un percorso (JSON pointer) all'interno di un documento JSON.
+// Restituisce ErrNotFound se la chiave o il percorso non esistono.
+func (client *Client) GetPath(ctx context.Context, key string, path string) (utils.Result, error) {
+	var result utils.Result
+	if err := client.call(ctx, "GetPath", utils.PathArgs{Namespace: client.namespace, Key: key, Path: path}, &result); err != nil {
+		return utils.Result{}, err
+	}
+	if isNotFound(result) {
+		return result, &Error{Method: "GetPath", Endpoint: client.Endpoint(), Message: "path " + path + " of key " + key + " not found", Kind: ErrNotFound}
+	}
+	return result, nil
+}
+
+// SetPath scrive il valore JSON di un percorso all'interno di un documento JSON
+func (client *Client) SetPath(ctx context.Context, key string, path string, value []byte) error {
+	return client.call(ctx, "SetPath", utils.PathArgs{Namespace: client.namespace, Key: key, Path: path, Value: value}, &utils.Result{})
+}
+
+// DeletePath rimuove un percorso all'interno di un documento JSON
+func (client *Client) DeletePath(ctx context.Context, key string, path string) error {
+	return client.call(ctx, "DeletePath", utils.PathArgs{Namespace: client.namespace, Key: key, Path: path}, &utils.Result{})
+}
+
+// CreateIndex crea un indice secondario sul campo indicato (ad esempio $.email) dei documenti con le chiavi indicate (ad esempio user:*)
+func (client *Client) CreateIndex(ctx context.Context, name string, keys string, field string) (utils.IndexInfo, error) {
+	var result utils.IndexInfo
+	err := client.call(ctx, "CreateIndex", utils.IndexArgs{Namespace: client.namespace, Name: name, Keys: keys, Field: field}, &result)
+	return result, err
+}
+
+// ListIndexes restituisce gli indici secondari del namespace
+func (client *Client) ListIndexes(ctx context.Context) ([]utils.IndexInfo, error) {
+	var result utils.IndexList
+	err := client.call(ctx, "ListIndexes", utils.IndexArgs{Namespace: client.namespace}, &result)
+	return result.Indexes, err
+}
+
+// DropIndex rimuove un indice secondario
+func (client *Client) DropIndex(ctx context.Context, name string) error {
+	return client.call(ctx, "DropIndex", utils.IndexArgs{Namespace: client.namespace, Name: name}, &utils.IndexInfo{})
+}
+
+// QueryIndex restituisce le chiavi il cui campo indicizzato ha il valore JSON indicato
+func (client *Client) QueryIndex(ctx context.Context, index string, value []byte) ([]string, error) {
+	var result utils.QueryResult
+	err := client.call(ctx, "QueryIndex", utils.QueryArgs{Namespace: client.namespace, Index: index, Value: value}, &result)
+	return result.Keys, err
+}
@@ -0,0 +1,134 @@
+package dbclient
+
+import (
+	"dbService/utils"
+	"errors"
+	"fmt"
+	"net/rpc"
+	"strings"
+)
+
+// Errori restituiti dal client, confrontabili con errors.Is
+var (
+	ErrNotFound          = errors.New("key not found")
+	ErrUnavailable       = errors.New("replica unavailable")
+	ErrNamespaceNotFound = errors.New("namespace not found")
+	ErrIndexNotFound     = errors.New("index not found")
+	ErrAlreadyExists     = errors.New("already exists")
+	ErrQuotaExceeded     = errors.New("namespace quota exceeded")
+	ErrValueTooLarge     = errors.New("value too large")
+	ErrInvalidDocument   = errors.New("invalid JSON document")
+	ErrUnsupported       = errors.New("operation not supported with the active consistency")
+	ErrInvalidArgument   = errors.New("invalid argument")
+	ErrNotDelivered      = errors.New("causal dependencies not delivered by the replica")
+	ErrSessionGuarantees = errors.New("session guarantees can not be met")
+)
+
+// Error descrive l'errore di un'operazione richiesta a una replica
+type Error struct {
+	Method   string // Operazione richiesta
+	Endpoint string // Replica a cui è stata richiesta l'operazione, vuoto se nessuna replica è stata contattata
+	Message  string // Messaggio d'errore restituito dalla replica
+	Kind     error  // Categoria dell'errore, una delle variabili Err di questo package
+	Err      error  // Errore originale, presente per gli errori di connessione
+}
+
+func (e *Error) Error() string {
+	if e.Endpoint == "" {
+		return fmt.Sprintf("dbclient: %s: %s", e.Method, e.Message)
+	}
+	return fmt.Sprintf("dbclient: %s on %s: %s", e.Method, e.Endpoint, e.Message)
+}
+
+// Unwrap restituisce la categoria dell'errore e, se presente, l'errore originale
+func (e *Error) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+// serverErrors associa i messaggi d'errore restituiti dalle repliche alla categoria corrispondente.
+// Il servizio RPC trasmette gli errori come testo, quindi la categoria è riconosciuta dall'inizio e da una parte del messaggio.
+// Vale la prima corrispondenza trovata.
+var serverErrors = []struct {
+	prefix   string
+	contains string
+	kind     error
+}{
+	{"namespace", "does not exist", ErrNamespaceNotFound},
+	{"index", "does not exist", ErrIndexNotFound},
+	{"key", "does not exist", ErrNotFound},
+	{"", "already exists", ErrAlreadyExists},
+	{"", "quota exceeded", ErrQuotaExceeded},
+	{"", "maximum value size", ErrValueTooLarge},
+	{"", "must be written with Put", ErrValueTooLarge},
+	{"", "JSON pointer", ErrInvalidArgument},
+	{"", "JSON", ErrInvalidDocument},
+	{"", "operation supported only with", ErrUnsupported},
+	{"session guarantees", "not delivered by replica", ErrSessionGuarantees},
+	{"", "not delivered by replica", ErrNotDelivered},
+}
+
+// nonIdempotent contiene i metodi che, se ripetuti dopo essere stati eseguiti dalla replica, ne modificano l'effetto o l'esito
+var nonIdempotent = map[string]bool{
+	"Increment":      true,
+	"Txn":            true,
+	"CompareAndSwap": true,
+	"PutIfAbsent":    true,
+	"DeleteIfValue":  true,
+}
+
+// isConnectionError indica se l'errore di una chiamata RPC è dovuto alla connessione con la replica, e non restituito dalla replica stessa
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var serverError rpc.ServerError
+	return !errors.As(err, &serverError)
+}
+
+// retryable indica se una richiesta fallita per un errore di connessione può essere inoltrata a un'altra replica.
+// Se la connessione era già chiusa la richiesta non è stata trasmessa, e può sempre essere ripetuta.
+// Altrimenti la replica potrebbe averla eseguita, quindi sono ripetuti solo i metodi idempotenti.
+func retryable(method string, err error) bool {
+	if errors.Is(err, rpc.ErrShutdown) {
+		return true
+	}
+	return !nonIdempotent[method]
+}
+
+// wrapError converte l'errore di una chiamata RPC inoltrata alla replica indicata in un Error tipato
+func wrapError(method string, endpoint string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var serverError rpc.ServerError
+	if !errors.As(err, &serverError) {
+		// La connessione con la replica è stata chiusa, o la richiesta non è stata trasmessa
+		return &Error{Method: method, Endpoint: endpoint, Message: err.Error(), Kind: ErrUnavailable, Err: err}
+	}
+
+	message := string(serverError)
+	return &Error{Method: method, Endpoint: endpoint, Message: message, Kind: serverErrorKind(message)}
+}
+
+// serverErrorKind restituisce la categoria di un messaggio d'errore restituito da una replica
+func serverErrorKind(message string) error {
+	for _, serverErr := range serverErrors {
+		if strings.HasPrefix(message, serverErr.prefix) && strings.Contains(message, serverErr.contains) {
+			return serverErr.kind
+		}
+	}
+	return ErrInvalidArgument
+}
+
+// EntryError restituisce l'errore dell'operazione sull'i-esima chiave di una richiesta su più chiavi (MultiGet, MultiPut o MultiDelete),
+// nil se l'operazione sulla chiave è andata a buon fine
+func EntryError(method string, result utils.BatchResult, i int) error {
+	if i >= len(result.Errors) || result.Errors[i] == "" {
+		return nil
+	}
+	message := result.Errors[i]
+	return &Error{Method: method, Message: message, Kind: serverErrorKind(message)}
+}
@@ -0,0 +1,171 @@
+package dbclient
+
+import (
+	"context"
+	"dbService/utils"
+	"time"
+)
+
+// WriteOption modifica una scrittura su una singola chiave
+type WriteOption func(args *utils.Args)
+
+// WithTTL indica la durata dopo cui la entry scritta scade e viene rimossa
+func WithTTL(ttl time.Duration) WriteOption {
+	return func(args *utils.Args) {
+		args.TTL = ttl
+	}
+}
+
+// WithCausalContext indica il contesto causale restituito da una Get, con cui la scrittura riconcilia le versioni concorrenti (solo consistenza causale)
+func WithCausalContext(context []int) WriteOption {
+	return func(args *utils.Args) {
+		args.Context = context
+	}
+}
+
+// isNotFound indica se il risultato di una lettura segnala l'assenza della chiave
+func isNotFound(result utils.Result) bool {
+	return string(result.Value) == "NOT FOUND"
+}
+
+// Get recupera il valore corrispondente a una chiave.
+// Restituisce ErrNotFound se la chiave non esiste. Con consistenza causale la replica attende che la chiave sia presente,
+// quindi la durata della richiesta va limitata tramite il context.
+func (client *Client) Get(ctx context.Context, key string) (utils.Result, error) {
+	var result utils.Result
+	if err := client.call(ctx, "Get", utils.Args{Namespace: client.namespace, Key: key}, &result); err != nil {
+		return utils.Result{}, err
+	}
+	if isNotFound(result) {
+		return result, &Error{Method: "Get", Endpoint: client.Endpoint(), Message: "key " + key + " not found", Kind: ErrNotFound}
+	}
+	return result, nil
+}
+
+// Put inserisce una nuova coppia key-value, o aggiorna il valore corrente se la chiave già esiste
+func (client *Client) Put(ctx context.Context, key string, value []byte, options ...WriteOption) error {
+	args := utils.Args{Namespace: client.namespace, Key: key, Value: value}
+	for _, option := range options {
+		option(&args)
+	}
+	return client.call(ctx, "Put", args, &utils.Result{})
+}
+
+// Delete rimuove la entry corrispondente a una chiave
+func (client *Client) Delete(ctx context.Context, key string, options ...WriteOption) error {
+	args := utils.Args{Namespace: client.namespace, Key: key}
+	for _, option := range options {
+		option(&args)
+	}
+	return client.call(ctx, "Delete", args, &utils.Result{})
+}
+
+// SetTTL aggiorna il TTL della versione corrente di una chiave senza riscriverne il valore, con un TTL nullo la entry non scade più.
+// Succeeded è false se la chiave non esiste.
+func (client *Client) SetTTL(ctx context.Context, key string, ttl time.Duration) (utils.CondResult, error) {
+	var result utils.CondResult
+	err := client.call(ctx, "SetTTL", utils.Args{Namespace: client.namespace, Key: key, TTL: ttl}, &result)
+	return result, err
+}
+
+// GetAt recupera il valore di una chiave in corrispondenza di una data versione o timestamp.
+// Restituisce ErrNotFound se la chiave non esisteva in quella versione.
+func (client *Client) GetAt(ctx context.Context, args utils.GetAtArgs) (utils.Result, error) {
+	args.Namespace = client.namespace
+	var result utils.Result
+	if err := client.call(ctx, "GetAt", args, &result); err != nil {
+		return utils.Result{}, err
+	}
+	if isNotFound(result) {
+		return result, &Error{Method: "GetAt", Endpoint: client.Endpoint(), Message: "key " + args.Key + " not found", Kind: ErrNotFound}
+	}
+	return result, nil
+}
+
+// Scan restituisce una pagina delle entry le cui chiavi sono comprese in un intervallo
+func (client *Client) Scan(ctx context.Context, args utils.ScanArgs) (utils.ScanResult, error) {
+	args.Namespace = client.namespace
+	var result utils.ScanResult
+	err := client.call(ctx, "Scan", args, &result)
+	return result, err
+}
+
+// ScanPrefix restituisce una pagina delle entry le cui chiavi hanno un dato prefisso
+func (client *Client) ScanPrefix(ctx context.Context, args utils.ScanArgs) (utils.ScanResult, error) {
+	args.Namespace = client.namespace
+	var result utils.ScanResult
+	err := client.call(ctx, "ScanPrefix", args, &result)
+	return result, err
+}
+
+// MultiGet recupera con un'unica richiesta i valori corrispondenti a più chiavi
+func (client *Client) MultiGet(ctx context.Context, keys ...string) (utils.BatchResult, error) {
+	args := utils.BatchArgs{Namespace: client.namespace}
+	for _, key := range keys {
+		args.Entries = append(args.Entries, utils.Args{Key: key})
+	}
+	var result utils.BatchResult
+	err := client.call(ctx, "MultiGet", args, &result)
+	return result, err
+}
+
+// MultiPut inserisce o aggiorna con un'unica richiesta più coppie key-value.
+// Gli errori delle singole scritture sono restituiti nel risultato, allineati con le entry della richiesta.
+func (client *Client) MultiPut(ctx context.Context, entries []utils.Args) (utils.BatchResult, error) {
+	var result utils.BatchResult
+	err := client.call(ctx, "MultiPut", utils.BatchArgs{Namespace: client.namespace, Entries: entries}, &result)
+	return result, err
+}
+
+// MultiDelete rimuove con un'unica richiesta le entry corrispondenti a più chiavi
+func (client *Client) MultiDelete(ctx context.Context, keys ...string) (utils.BatchResult, error) {
+	args := utils.BatchArgs{Namespace: client.namespace}
+	for _, key := range keys {
+		args.Entries = append(args.Entries, utils.Args{Key: key})
+	}
+	var result utils.BatchResult
+	err := client.call(ctx, "MultiDelete", args, &result)
+	return result, err
+}
+
+// Txn esegue atomicamente una transazione multi-chiave (solo consistenza sequenziale)
+func (client *Client) Txn(ctx context.Context, args utils.TxnArgs) (utils.TxnResult, error) {
+	args.Namespace = client.namespace
+	var result utils.TxnResult
+	err := client.call(ctx, "Txn", args, &result)
+	return result, err
+}
+
+// CompareAndSwap aggiorna il valore di una chiave solo se il valore corrente coincide con quello atteso (solo consistenza sequenziale)
+func (client *Client) CompareAndSwap(ctx context.Context, key string, expected []byte, value []byte) (utils.CondResult, error) {
+	var result utils.CondResult
+	err := client.call(ctx, "CompareAndSwap", utils.CASArgs{Namespace: client.namespace, Key: key, Expected: expected, Value: value}, &result)
+	return result, err
+}
+
+// PutIfAbsent inserisce una coppia key-value solo se la chiave non è già presente (solo consistenza sequenziale)
+func (client *Client) PutIfAbsent(ctx context.Context, key string, value []byte, options ...WriteOption) (utils.CondResult, error) {
+	args := utils.Args{Namespace: client.namespace, Key: key, Value: value}
+	for _, option := range options {
+		option(&args)
+	}
+	var result utils.CondResult
+	err := client.call(ctx, "PutIfAbsent", args, &result)
+	return result, err
+}
+
+// DeleteIfValue rimuove la entry corrispondente a una chiave solo se il valore corrente coincide con quello indicato (solo consistenza sequenziale)
+func (client *Client) DeleteIfValue(ctx context.Context, key string, value []byte) (utils.CondResult, error) {
+	var result utils.CondResult
+	err := client.call(ctx, "DeleteIfValue", utils.Args{Namespace: client.namespace, Key: key, Value: value}, &result)
+	return result, err
+}
+
+// Watch attende le modifiche applicate dalla replica a una chiave, o alle chiavi con un dato prefisso
+// Una revisione restituita permette di riprendere l'osservazione solo sulla stessa replica, mentre la versione restituita, indicata in FromVersion, su qualsiasi replica.
+func (client *Client) Watch(ctx context.Context, args utils.WatchArgs) (utils.WatchResult, error) {
+	args.Namespace = client.namespace
+	var result utils.WatchResult
+	err := client.call(ctx, "Watch", args, &result)
+	return result, err
+}
@@ -0,0 +1,201 @@
+package dbclient
+
+import (
+	"context"
+	"dbService/utils"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+)
+
+// endpoint rappresenta la connessione del pool verso una replica
+type endpoint struct {
+	address     string
+	mutex       sync.Mutex
+	conn        *rpc.Client // Connessione con la replica, nil se la replica non è raggiungibile
+	outstanding int         // Numero di richieste inoltrate alla replica e in attesa di risposta
+}
+
+// pool mantiene una connessione verso ogni replica indicata nella configurazione.
+// Le repliche non raggiungibili sono escluse dalla scelta del balancer, e un health check periodico ne ripristina la connessione.
+type pool struct {
+	config    Config
+	endpoints []*endpoint
+	done      chan struct{} // Chiuso da close per terminare l'health check
+	wg        sync.WaitGroup
+}
+
+// newPool crea il pool e si connette a tutte le repliche raggiungibili
+func newPool(ctx context.Context, config Config) *pool {
+	pool := &pool{config: config, done: make(chan struct{})}
+	for _, address := range config.Endpoints {
+		endpoint := &endpoint{address: address}
+		if conn, err := dialEndpoint(ctx, address, config.DialTimeout); err == nil {
+			endpoint.conn = conn
+		}
+		pool.endpoints = append(pool.endpoints, endpoint)
+	}
+	return pool
+}
+
+// dialEndpoint apre una connessione RPC verso la replica indicata
+func dialEndpoint(ctx context.Context, address string, timeout time.Duration) (*rpc.Client, error) {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	return rpc.NewClient(conn), nil
+}
+
+// available indica se almeno una replica è raggiungibile
+func (pool *pool) available() bool {
+	for _, endpoint := range pool.endpoints {
+		endpoint.mutex.Lock()
+		connected := endpoint.conn != nil
+		endpoint.mutex.Unlock()
+		if connected {
+			return true
+		}
+	}
+	return false
+}
+
+// pick sceglie tramite il balancer la replica a cui inoltrare una richiesta, tra quelle raggiungibili e non escluse.
+// Restituisce la replica scelta e la connessione con essa, oppure nil se nessuna replica è disponibile.
+func (pool *pool) pick(last string, excluded map[string]bool) (*endpoint, *rpc.Client) {
+	var candidates []*endpoint
+	var infos []EndpointInfo
+	for _, endpoint := range pool.endpoints {
+		if excluded[endpoint.address] {
+			continue
+		}
+		endpoint.mutex.Lock()
+		if endpoint.conn != nil {
+			candidates = append(candidates, endpoint)
+			infos = append(infos, EndpointInfo{Address: endpoint.address, Outstanding: endpoint.outstanding})
+		}
+		endpoint.mutex.Unlock()
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	chosen := candidates[pool.config.Balancer.Pick(infos, last)]
+	chosen.mutex.Lock()
+	defer chosen.mutex.Unlock()
+	if chosen.conn == nil {
+		// La replica è stata scollegata dopo la scelta, la richiesta verrà inoltrata a un'altra replica
+		return chosen, nil
+	}
+	chosen.outstanding++
+	return chosen, chosen.conn
+}
+
+// pickAddress restituisce la replica con l'indirizzo indicato e la connessione con essa, se la replica è raggiungibile
+func (pool *pool) pickAddress(address string) (*endpoint, *rpc.Client) {
+	for _, endpoint := range pool.endpoints {
+		if endpoint.address != address {
+			continue
+		}
+		endpoint.mutex.Lock()
+		defer endpoint.mutex.Unlock()
+		if endpoint.conn == nil {
+			return nil, nil
+		}
+		endpoint.outstanding++
+		return endpoint, endpoint.conn
+	}
+	return nil, nil
+}
+
+// done registra il completamento di una richiesta inoltrata alla replica
+func (endpoint *endpoint) done() {
+	endpoint.mutex.Lock()
+	endpoint.outstanding--
+	endpoint.mutex.Unlock()
+}
+
+// disconnect chiude la connessione con la replica, se è ancora quella indicata, e la esclude dalle scelte successive fino al prossimo health check
+func (endpoint *endpoint) disconnect(conn *rpc.Client) {
+	endpoint.mutex.Lock()
+	defer endpoint.mutex.Unlock()
+	if endpoint.conn != conn || conn == nil {
+		return
+	}
+	_ = conn.Close()
+	endpoint.conn = nil
+}
+
+// healthCheck controlla periodicamente lo stato delle repliche, finché il pool non viene chiuso
+func (pool *pool) healthCheck() {
+	defer pool.wg.Done()
+	ticker := time.NewTicker(pool.config.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-pool.done:
+			return
+		case <-ticker.C:
+			for _, endpoint := range pool.endpoints {
+				pool.check(endpoint)
+			}
+		}
+	}
+}
+
+// check verifica che una replica connessa risponda entro DialTimeout, altrimenti la scollega.
+// Per una replica non connessa prova invece a ripristinare la connessione.
+func (pool *pool) check(endpoint *endpoint) {
+	endpoint.mutex.Lock()
+	conn := endpoint.conn
+	endpoint.mutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), pool.config.DialTimeout)
+	defer cancel()
+
+	if conn != nil {
+		// ListNamespaces è eseguita localmente dalla replica, senza coinvolgere le altre
+		call := conn.Go(serviceName+".ListNamespaces", utils.NamespaceArgs{}, &utils.NamespaceList{}, make(chan *rpc.Call, 1))
+		select {
+		case <-ctx.Done():
+			endpoint.disconnect(conn)
+		case <-call.Done:
+			if isConnectionError(call.Error) {
+				endpoint.disconnect(conn)
+			}
+		}
+		return
+	}
+
+	newConn, err := dialEndpoint(ctx, endpoint.address, pool.config.DialTimeout)
+	if err != nil {
+		return
+	}
+	endpoint.mutex.Lock()
+	defer endpoint.mutex.Unlock()
+	if endpoint.conn != nil {
+		_ = newConn.Close()
+		return
+	}
+	endpoint.conn = newConn
+}
+
+// close termina l'health check e chiude le connessioni con tutte le repliche
+func (pool *pool) close() error {
+	close(pool.done)
+	pool.wg.Wait()
+	var closeErr error
+	for _, endpoint := range pool.endpoints {
+		endpoint.mutex.Lock()
+		if endpoint.conn != nil {
+			if err := endpoint.conn.Close(); err != nil && closeErr == nil {
+				closeErr = err
+			}
+			endpoint.conn = nil
+		}
+		endpoint.mutex.Unlock()
+	}
+	return closeErr
+}
@@ -0,0 +1,179 @@
+package dbclient
+
+import (
+	"context"
+	"crypto/rand"
+	"dbService/utils"
+	"encoding/hex"
+	"sync"
+)
+
+// orderedMethods contiene i metodi le cui richieste sono numerate dalla sessione, e che la replica esegue nell'ordine di invio.
+// Prima di inviare la richiesta di un altro metodo il client attende la risposta di tutte le richieste precedenti della sessione.
+var orderedMethods = map[string]bool{
+	"Get":         true,
+	"Put":         true,
+	"Delete":      true,
+	"MultiGet":    true,
+	"MultiPut":    true,
+	"MultiDelete": true,
+}
+
+// session tiene traccia delle operazioni eseguite da un client, anche se inoltrate a repliche diverse.
+// Con consistenza causale il clock della sessione è inviato con le richieste di Get, Put e Delete e con quelle su più chiavi:
+// la replica che le riceve attende di aver applicato le scritture già osservate dalla sessione,
+// così che passando a un'altra replica la sessione continui a leggere le proprie scritture (read-your-writes) e non legga valori più vecchi di quelli già letti (monotonic reads).
+// Con entrambe le consistenze le richieste della sessione sono inoltre numerate, così che la replica le esegua nell'ordine di invio (program order)
+// anche quando la sessione ne invia più di una senza attenderne la risposta.
+type session struct {
+	mutex       sync.Mutex
+	clock       []int         // Unione dei clock vettoriali delle scritture eseguite e delle versioni lette dalla sessione, nil con consistenza sequenziale
+	endpoint    string        // Indirizzo della replica a cui è stata inoltrata l'ultima richiesta della sessione
+	id          string        // Identificatore della sessione inviato con le richieste numerate, assegnato alla prima richiesta
+	nextSeq     int           // Numero di sequenza dell'ultima richiesta numerata della sessione
+	completed   int           // Numero di sequenza fino al quale la sessione ha ricevuto la risposta di tutte le richieste
+	replied     map[int]bool  // Richieste di cui la sessione ha ricevuto la risposta, con numero di sequenza successivo a completed
+	outstanding int           // Numero di richieste inviate dalla sessione e in attesa di risposta
+	idle        chan struct{} // Chiuso quando la sessione non ha richieste in attesa di risposta, nil se non ne ha
+	submit      sync.Mutex    // Rende atomici la numerazione di una richiesta e la scelta della replica a cui inoltrarla
+}
+
+// observe unisce al clock della sessione i clock vettoriali restituiti da una replica in risposta a una richiesta
+func (session *session) observe(reply any) {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	switch result := reply.(type) {
+	case *utils.Result:
+		session.merge(result.Version.VectorClock)
+		session.merge(result.Context)
+	case *utils.BatchResult:
+		for _, entry := range result.Results {
+			session.merge(entry.Version.VectorClock)
+			session.merge(entry.Context)
+		}
+	}
+}
+
+// merge aggiorna il clock della sessione, per ogni k: S[k] = max{S[k], clock[k]}
+func (session *session) merge(clock []int) {
+	if len(clock) == 0 {
+		return
+	}
+	if session.clock == nil {
+		session.clock = make([]int, len(clock))
+	}
+	for k := 0; k < len(clock) && k < len(session.clock); k++ {
+		if session.clock[k] < clock[k] {
+			session.clock[k] = clock[k]
+		}
+	}
+}
+
+// withClock restituisce gli argomenti della richiesta a cui è associato il clock della sessione, se la richiesta lo supporta
+func (session *session) withClock(args any) any {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	if session.clock == nil {
+		return args
+	}
+	switch request := args.(type) {
+	case utils.Args:
+		request.After = append([]int(nil), session.clock...)
+		return request
+	case utils.BatchArgs:
+		request.After = append([]int(nil), session.clock...)
+		return request
+	}
+	return args
+}
+
+// begin registra l'invio di una richiesta della sessione e, se il metodo è numerato, restituisce gli argomenti a cui è associata la sua posizione nella sessione e il suo numero di sequenza.
+// Restituisce anche l'indirizzo della replica a cui inoltrare la richiesta per rispettarne l'ordine, vuoto se la richiesta può essere inoltrata a qualsiasi replica.
+func (session *session) begin(method string, args any) (any, int, string) {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	pinned := ""
+	if session.outstanding > 0 {
+		// La replica attende le richieste precedenti ancora in corso, quindi la richiesta le deve seguire sulla stessa replica
+		pinned = session.endpoint
+	}
+	if session.outstanding == 0 {
+		session.idle = make(chan struct{})
+	}
+	session.outstanding++
+	if !orderedMethods[method] {
+		return args, 0, pinned
+	}
+
+	if session.id == "" {
+		session.id = newSessionID()
+		session.replied = make(map[int]bool)
+	}
+	session.nextSeq++
+	order := utils.SessionOrder{Session: session.id, Seq: session.nextSeq, Completed: session.completed}
+	switch request := args.(type) {
+	case utils.Args:
+		request.Order = order
+		args = request
+	case utils.BatchArgs:
+		request.Order = order
+		args = request
+	}
+	return args, session.nextSeq, pinned
+}
+
+// end registra la risposta, o l'errore, di una richiesta della sessione con il numero di sequenza indicato, 0 se la richiesta non è numerata
+func (session *session) end(seq int) {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	if seq > 0 {
+		session.replied[seq] = true
+		for session.replied[session.completed+1] {
+			delete(session.replied, session.completed+1)
+			session.completed++
+		}
+	}
+	session.outstanding--
+	if session.outstanding == 0 {
+		close(session.idle)
+		session.idle = nil
+	}
+}
+
+// wait attende la risposta di tutte le richieste inviate dalla sessione, oppure la scadenza o la cancellazione del context
+func (session *session) wait(ctx context.Context) error {
+	for {
+		session.mutex.Lock()
+		idle := session.idle
+		session.mutex.Unlock()
+		if idle == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-idle:
+		}
+	}
+}
+
+// newSessionID genera un identificatore casuale per una sessione
+func newSessionID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// lastEndpoint restituisce l'indirizzo della replica a cui è stata inoltrata l'ultima richiesta della sessione
+func (session *session) lastEndpoint() string {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	return session.endpoint
+}
+
+// setEndpoint registra l'indirizzo della replica a cui è stata inoltrata l'ultima richiesta della sessione
+func (session *session) setEndpoint(endpoint string) {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	session.endpoint = endpoint
+}
@@ -0,0 +1,121 @@
+package dbclient
+
+import (
+	"dbService/utils"
+	"reflect"
+	"testing"
+)
+
+// sessionStep è l'invio di una richiesta della sessione (begin) o la ricezione della sua risposta (end)
+type sessionStep struct {
+	begin         string // Metodo della richiesta inviata, vuoto se il passo è la ricezione di una risposta
+	args          any
+	end           int  // Numero di sequenza della richiesta di cui è ricevuta la risposta
+	noSeq         bool // Indica se la richiesta non è numerata, e i suoi argomenti restano invariati
+	wantSeq       int
+	wantCompleted int // Richieste di cui la sessione ha ricevuto tutte le risposte, indicate nella richiesta inviata o dopo la risposta
+	wantPinned    string
+}
+
+func TestSessionOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		steps    []sessionStep
+	}{
+		{
+			name:     "sequential requests",
+			endpoint: "replica1",
+			steps: []sessionStep{
+				{begin: "Put", args: utils.Args{Key: "a"}, wantSeq: 1},
+				{end: 1, wantCompleted: 1},
+				{begin: "Get", args: utils.Args{Key: "a"}, wantSeq: 2, wantCompleted: 1},
+				{end: 2, wantCompleted: 2},
+				{begin: "MultiDelete", args: utils.BatchArgs{Entries: []utils.Args{{Key: "a"}}}, wantSeq: 3, wantCompleted: 2},
+				{end: 3, wantCompleted: 3},
+			},
+		},
+		{
+			name:     "pipelined requests",
+			endpoint: "replica1",
+			steps: []sessionStep{
+				{begin: "Put", args: utils.Args{Key: "a"}, wantSeq: 1},
+				{begin: "Put", args: utils.Args{Key: "b"}, wantSeq: 2, wantPinned: "replica1"},
+				{end: 1, wantCompleted: 1},
+				{begin: "MultiGet", args: utils.BatchArgs{}, wantSeq: 3, wantCompleted: 1, wantPinned: "replica1"},
+				{end: 2, wantCompleted: 2},
+				{end: 3, wantCompleted: 3},
+				{begin: "Get", args: utils.Args{Key: "a"}, wantSeq: 4, wantCompleted: 3},
+			},
+		},
+		{
+			name:     "out of order replies",
+			endpoint: "replica1",
+			steps: []sessionStep{
+				{begin: "Put", args: utils.Args{Key: "a"}, wantSeq: 1},
+				{begin: "Put", args: utils.Args{Key: "b"}, wantSeq: 2, wantPinned: "replica1"},
+				{begin: "Put", args: utils.Args{Key: "c"}, wantSeq: 3, wantPinned: "replica1"},
+				{end: 3, wantCompleted: 0},
+				{end: 2, wantCompleted: 0},
+				{begin: "Delete", args: utils.Args{Key: "a"}, wantSeq: 4, wantCompleted: 0, wantPinned: "replica1"},
+				{end: 1, wantCompleted: 3},
+				{end: 4, wantCompleted: 4},
+			},
+		},
+		{
+			name:     "unordered methods",
+			endpoint: "replica1",
+			steps: []sessionStep{
+				{begin: "Txn", args: utils.TxnArgs{}, noSeq: true},
+				{begin: "Put", args: utils.Args{Key: "a"}, wantSeq: 1, wantPinned: "replica1"},
+				{end: 0},
+				{end: 1, wantCompleted: 1},
+				{begin: "CompareAndSwap", args: utils.CASArgs{Key: "a"}, noSeq: true},
+				{end: 0, wantCompleted: 1},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			session := &session{}
+			session.setEndpoint(test.endpoint)
+			for i, step := range test.steps {
+				if step.begin == "" {
+					session.end(step.end)
+					if session.completed != step.wantCompleted {
+						t.Fatalf("step %d: completed = %d after the reply of request %d, want %d", i, session.completed, step.end, step.wantCompleted)
+					}
+					continue
+				}
+
+				args, seq, pinned := session.begin(step.begin, step.args)
+				if pinned != step.wantPinned {
+					t.Fatalf("step %d: %s pinned to %q, want %q", i, step.begin, pinned, step.wantPinned)
+				}
+				if step.noSeq {
+					if seq != 0 || !reflect.DeepEqual(args, step.args) {
+						t.Fatalf("step %d: %s numbered %d with args %+v, want no sequence number and args unchanged", i, step.begin, seq, args)
+					}
+					continue
+				}
+				if seq != step.wantSeq {
+					t.Fatalf("step %d: %s numbered %d, want %d", i, step.begin, seq, step.wantSeq)
+				}
+				var order utils.SessionOrder
+				switch request := args.(type) {
+				case utils.Args:
+					order = request.Order
+				case utils.BatchArgs:
+					order = request.Order
+				default:
+					t.Fatalf("step %d: %s args of type %T", i, step.begin, args)
+				}
+				want := utils.SessionOrder{Session: session.id, Seq: step.wantSeq, Completed: step.wantCompleted}
+				if order != want || order.Session == "" {
+					t.Fatalf("step %d: %s sent with order %+v, want %+v", i, step.begin, order, want)
+				}
+			}
+		})
+	}
+}
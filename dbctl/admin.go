@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"dbService/dbclient"
+	"dbService/utils"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// adminCommands contiene i sottocomandi di dbctl admin, che interrogano singolarmente ogni replica
+var adminCommands = map[string]func(ctx *cliContext, address string, client *dbclient.Client) error{
+	"status": adminStatus,
+	"dump":   adminDump,
+}
+
+// runAdmin esegue un comando di amministrazione su ogni replica indicata con i flag globali, una replica alla volta.
+// Una replica che non risponde è segnalata come non raggiungibile senza interrompere il comando, che termina con il codice di uscita del primo errore.
+func runAdmin(ctx *cliContext, arguments []string) int {
+	flags := ctx.flagSet()
+	if code, ok := ctx.parse(flags, arguments); !ok {
+		return code
+	}
+	if flags.NArg() != 1 {
+		return ctx.usageError("admin requires a command: status or dump")
+	}
+	run, ok := adminCommands[flags.Arg(0)]
+	if !ok {
+		return ctx.usageError(fmt.Sprintf("unknown admin command %q", flags.Arg(0)))
+	}
+	addresses, err := resolveEndpoints(ctx.endpoints, ctx.replica)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "dbctl:", err)
+		return exitUsage
+	}
+
+	code := exitOK
+	for _, address := range addresses {
+		client, err := ctx.dial([]string{address})
+		if err == nil {
+			err = run(ctx, address, client)
+			_ = client.Close()
+		}
+		if err != nil {
+			ctx.printer.replicaError(address, err)
+			if code == exitOK {
+				code = exitCode(err)
+			}
+		}
+	}
+	return code
+}
+
+// adminStatus stampa lo stato interno della replica
+func adminStatus(ctx *cliContext, address string, client *dbclient.Client) error {
+	opCtx, cancel := ctx.operation()
+	defer cancel()
+	status, err := client.Status(opCtx)
+	if err != nil {
+		return err
+	}
+	ctx.printer.status(address, status)
+	return nil
+}
+
+// adminDump stampa tutte le entry del namespace presenti sulla replica
+func adminDump(ctx *cliContext, address string, client *dbclient.Client) error {
+	opCtx, cancel := ctx.operation()
+	defer cancel()
+	entries, err := client.Dump(opCtx)
+	if err != nil {
+		return err
+	}
+	ctx.printer.dump(address, entries)
+	return nil
+}
+
+// jsonReplica rappresenta nell'output JSON lo stato di una replica, o l'errore con cui non ha risposto
+type jsonReplica struct {
+	Endpoint string               `json:"endpoint"`
+	Up       bool                 `json:"up"`
+	Error    string               `json:"error,omitempty"`
+	Status   *utils.ReplicaStatus `json:"status,omitempty"`
+}
+
+// replicaError stampa l'errore di un comando di amministrazione su una replica, indicando se la replica non è raggiungibile
+func (printer *printer) replicaError(address string, err error) {
+	up := !errors.Is(err, dbclient.ErrUnavailable) && !errors.Is(err, context.DeadlineExceeded)
+	if printer.json {
+		printer.object(jsonReplica{Endpoint: address, Up: up, Error: err.Error()})
+		return
+	}
+	printer.flush()
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = errors.New("operation timed out")
+	}
+	if up {
+		fmt.Fprintf(os.Stderr, "dbctl: replica %s: %v\n", address, err)
+	} else {
+		fmt.Fprintf(os.Stderr, "dbctl: replica %s DOWN: %v\n", address, err)
+	}
+}
+
+// status stampa lo stato interno di una replica
+func (printer *printer) status(address string, status utils.ReplicaStatus) {
+	if printer.json {
+		printer.object(jsonReplica{Endpoint: address, Up: true, Status: &status})
+		return
+	}
+	printer.line(fmt.Sprintf("replica %d %s UP (%s)", status.ServerID, address, status.Consistency))
+	if status.VectorClock != nil {
+		printer.line(fmt.Sprintf("  vector clock:  %v", status.VectorClock))
+	} else {
+		printer.line(fmt.Sprintf("  clock:         %d", status.Clock))
+	}
+	printer.line(fmt.Sprintf("  next seq num:  %d", status.NextSeqNum))
+	printer.line(fmt.Sprintf("  message queue: %s", formatQueue(status.MessageQueue)))
+	for _, namespace := range status.Namespaces {
+		name := namespace.Name
+		if name == utils.DefaultNamespace {
+			name = "default"
+		}
+		printer.line(fmt.Sprintf("  namespace %s: %d keys, %d bytes", name, namespace.Keys, namespace.Size))
+	}
+	for _, peer := range status.Peers {
+		lastReceived := "never"
+		if peer.LastReceived != nil {
+			lastReceived = time.Since(*peer.LastReceived).Round(time.Millisecond).String() + " ago"
+		}
+		printer.line(fmt.Sprintf("  peer %d %s: expected seq num %d, last message %s, FIFO queue %s",
+			peer.ServerID, peer.Address, peer.ExpectedSeqNum, lastReceived, formatQueue(peer.FIFOQueue)))
+	}
+}
+
+// dump stampa le entry di una replica: in formato plain come tabella, preceduta dall'indirizzo della replica
+func (printer *printer) dump(address string, entries []utils.Result) {
+	if printer.json {
+		for _, entry := range entries {
+			value := string(entry.Value)
+			printer.object(jsonResult{Op: "dump", Endpoint: address, Key: entry.Key, Value: &value, Version: newJSONVersion(entry.Version), Siblings: len(entry.Siblings)})
+		}
+		return
+	}
+
+	printer.line(fmt.Sprintf("replica %s: %d entries", address, len(entries)))
+	if len(entries) == 0 {
+		return
+	}
+	rows := [][3]string{{"Key", "Value", "Version"}}
+	for _, entry := range entries {
+		rows = append(rows, [3]string{entry.Key, string(entry.Value), entry.Version.String()})
+		for _, sibling := range entry.Siblings {
+			rows = append(rows, [3]string{"", string(sibling.Value) + " (sibling)", sibling.Version.String()})
+		}
+	}
+	widths := [3]int{}
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+	separator := fmt.Sprintf("+-%s-+-%s-+-%s-+", strings.Repeat("-", widths[0]), strings.Repeat("-", widths[1]), strings.Repeat("-", widths[2]))
+	printer.line(separator)
+	for i, row := range rows {
+		printer.line(fmt.Sprintf("| %-*s | %-*s | %-*s |", widths[0], row[0], widths[1], row[1], widths[2], row[2]))
+		if i == 0 || i == len(rows)-1 {
+			printer.line(separator)
+		}
+	}
+}
+
+// formatQueue descrive una coda di messaggi con la sua lunghezza e il messaggio in testa
+func formatQueue(queue utils.QueueStatus) string {
+	if queue.Head == nil {
+		return "empty"
+	}
+	head := queue.Head
+	description := fmt.Sprintf("%d messages, head %s", queue.Length, head.Op)
+	if head.Type != "" {
+		description = fmt.Sprintf("%d messages, head %s %s", queue.Length, head.Type, head.Op)
+	}
+	if head.Key != "" {
+		description += fmt.Sprintf(" %q", head.Key)
+	}
+	description += fmt.Sprintf(" from replica %d, seq num %d", head.ServerID, head.SeqNum)
+	if head.VectorClock != nil {
+		return description + fmt.Sprintf(", clock %v", head.VectorClock)
+	}
+	return description + fmt.Sprintf(", message %d, clock %d", head.MessageID, head.Clock)
+}
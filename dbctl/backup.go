@@ -0,0 +1,562 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"dbService/dbclient"
+	"dbService/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupHeader è la prima riga di un file prodotto da dbctl backup, seguita dalle entry di tutti i namespace nel formato JSONL di dbctl export
+type backupHeader struct {
+	Consistency string                `json:"consistency"`
+	ServerID    int                   `json:"server_id"`         // Replica da cui è stata presa la snapshot
+	Cut         jsonVersion           `json:"cut"`               // Posizione delle scritture incluse nella snapshot
+	TakenAt     int64                 `json:"taken_at"`          // Istante della snapshot in millisecondi
+	Namespaces  []utils.NamespaceArgs `json:"namespaces"`        // Configurazione dei namespace, compreso quello di default
+	Indexes     []utils.IndexArgs     `json:"indexes,omitempty"` // Indici secondari di tutti i namespace
+	CRDTs       *utils.BackupCRDTs    `json:"crdts,omitempty"`   // Valori dei tipi di dato CRDT (solo consistenza causale)
+}
+
+// pendingWrite rappresenta una scrittura del WAL rieseguita senza attenderne la risposta
+type pendingWrite struct {
+	record utils.CDCRecord
+	future *dbclient.Future[utils.Result]
+	cancel context.CancelFunc
+}
+
+// includes indica se la scrittura con la versione indicata è inclusa nella snapshot del backup
+func (header *backupHeader) includes(version utils.Version) bool {
+	backup := utils.BackupResult{Cut: utils.Version{Clock: header.Cut.Clock, ServerID: header.Cut.ServerID, VectorClock: header.Cut.VectorClock}}
+	return backup.Includes(version)
+}
+
+// runBackup scrive su file una snapshot di tutti i namespace di una replica, presa senza interrompere le scritture.
+// La snapshot contiene tutte e sole le scritture che precedono la posizione registrata nell'intestazione: un prefisso dell'ordine totale
+// con consistenza sequenziale, oppure le scritture incluse nel clock vettoriale della replica con consistenza causale.
+func runBackup(ctx *cliContext, arguments []string) int {
+	flags := ctx.flagSet()
+	if code, ok := ctx.parse(flags, arguments); !ok {
+		return code
+	}
+	if flags.NArg() > 1 {
+		return ctx.usageError("backup accepts at most one file")
+	}
+	path := flags.Arg(0)
+	if path == "" {
+		path = "-"
+	}
+	if code := ctx.connect(); code != exitOK {
+		return code
+	}
+
+	opCtx, cancel := ctx.operation()
+	backup, err := ctx.client.Backup(opCtx)
+	cancel()
+	if err != nil {
+		return reportError(err)
+	}
+
+	writer := ctx.printer.writer
+	var file *os.File
+	if path != "-" {
+		file, err = os.Create(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "dbctl:", err)
+			return exitError
+		}
+		writer = bufio.NewWriter(file)
+	}
+	entries, err := writeBackup(writer, backup)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if file != nil {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "dbctl: error while writing the backup:", err)
+		return exitError
+	}
+
+	// Con il backup su stdout il riepilogo è omesso, così che stdout contenga solo il backup
+	if path != "-" {
+		ctx.printer.backup(ctx.client.Endpoint(), len(backup.Namespaces), entries, backup.Cut)
+	}
+	return exitOK
+}
+
+// writeBackup scrive l'intestazione del backup e le entry di ogni namespace, e restituisce il numero di entry scritte
+func writeBackup(writer *bufio.Writer, backup utils.BackupResult) (int, error) {
+	header := backupHeader{
+		Consistency: backup.Consistency,
+		ServerID:    backup.ServerID,
+		Cut:         jsonVersion{Clock: backup.Cut.Clock, ServerID: backup.Cut.ServerID, VectorClock: backup.Cut.VectorClock},
+		TakenAt:     backup.TakenAt,
+		CRDTs:       backup.CRDTs,
+	}
+	for _, namespace := range backup.Namespaces {
+		header.Namespaces = append(header.Namespaces, namespace.Config)
+		for _, index := range namespace.Indexes {
+			index.Namespace = namespace.Config.Name
+			header.Indexes = append(header.Indexes, index)
+		}
+	}
+	if err := json.NewEncoder(writer).Encode(header); err != nil {
+		return 0, err
+	}
+	entries := 0
+	for _, namespace := range backup.Namespaces {
+		if err := writeJSONL(writer, namespace.Config.Name, namespace.Entries); err != nil {
+			return entries, err
+		}
+		entries += len(namespace.Entries)
+	}
+	return entries, nil
+}
+
+// runRestore riporta un cluster vuoto allo stato di un backup, ed eventualmente a un istante successivo rieseguendo il WAL.
+// Il WAL è il flusso CDC di una replica (la cartella CDC_DIR/BASE_NAME-i): i suoi record non inclusi nella snapshot sono rieseguiti
+// nell'ordine in cui la replica li ha applicati, fino all'ultimo applicato entro -until. Come per dbctl import, le entry sono nuove scritture
+// e le versioni originali non sono mantenute.
+func runRestore(ctx *cliContext, arguments []string) int {
+	flags := ctx.flagSet()
+	wal := flags.String("wal", "", "CDC directory of a replica, whose records following the backup are replayed")
+	until := flags.String("until", "", "replay only the WAL records applied up to this time (RFC 3339), defaults to all the records")
+	batchSize := flags.Int("batch-size", 100, "number of entries written by each MultiPut")
+	window := flags.Int("window", 4, "maximum number of writes waiting for a response")
+	if code, ok := ctx.parse(flags, arguments); !ok {
+		return code
+	}
+	if flags.NArg() != 1 {
+		return ctx.usageError("restore requires a backup file")
+	}
+	if *batchSize <= 0 || *window <= 0 {
+		return ctx.usageError("-batch-size and -window must be positive")
+	}
+	var untilMillis int64
+	if *until != "" {
+		if *wal == "" {
+			return ctx.usageError("-until requires -wal")
+		}
+		untilTime, err := time.Parse(time.RFC3339Nano, *until)
+		if err != nil {
+			return ctx.usageError(fmt.Sprintf("invalid -until %q, it must be an RFC 3339 time such as 2006-01-02T15:04:05Z", *until))
+		}
+		untilMillis = untilTime.UnixMilli()
+	}
+	input := os.Stdin
+	if flags.Arg(0) != "-" {
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "dbctl:", err)
+			return exitError
+		}
+		defer func(file *os.File) {
+			_ = file.Close()
+		}(file)
+		input = file
+	}
+
+	reader := bufio.NewReader(input)
+	header, err := readBackupHeader(reader)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "dbctl:", err)
+		return exitUsage
+	}
+	if untilMillis > 0 && untilMillis < header.TakenAt {
+		fmt.Fprintf(os.Stderr, "dbctl: the backup was taken at %s, after %s\n", time.UnixMilli(header.TakenAt).Format(time.RFC3339), *until)
+		return exitUsage
+	}
+	var records []utils.CDCRecord
+	if *wal != "" {
+		records, err = walRecords(*wal, header, untilMillis)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "dbctl:", err)
+			return exitError
+		}
+	}
+	if code := ctx.connect(); code != exitOK {
+		return code
+	}
+
+	// Crea i namespace del backup, prima di scriverne le entry
+	for _, config := range header.Namespaces {
+		if config.Name == utils.DefaultNamespace {
+			continue
+		}
+		opCtx, cancel := ctx.operation()
+		_, err := ctx.client.CreateNamespace(opCtx, config)
+		cancel()
+		if err != nil {
+			return reportError(fmt.Errorf("namespace %s: %w", config.Name, err))
+		}
+	}
+
+	// Scrive le entry della snapshot a gruppi, ognuno con le entry di un solo namespace
+	importer := &importer{ctx: ctx, client: ctx.client, window: *window, code: exitOK}
+	var batch []importEntry
+	send := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		importer.client = ctx.client.Namespace(batch[0].namespace)
+		err := importer.send(batch)
+		batch = nil
+		return err
+	}
+	read := func(entry importEntry) error {
+		if entry.expiresAt > 0 && entry.expiresAt <= time.Now().UnixMilli() {
+			importer.skipped++
+			return nil
+		}
+		if len(batch) > 0 && batch[0].namespace != entry.namespace {
+			if err := send(); err != nil {
+				return err
+			}
+		}
+		batch = append(batch, entry)
+		if len(batch) < *batchSize {
+			return nil
+		}
+		return send()
+	}
+	// La prima riga del file contiene l'intestazione
+	err = readJSONL(reader, 2, read)
+	if err == nil {
+		err = send()
+	}
+	if drainErr := importer.drain(); err == nil {
+		err = drainErr
+	}
+	if err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, "dbctl:", err)
+			return exitUsage
+		}
+		return reportError(err)
+	}
+
+	// Crea gli indici secondari dopo aver scritto le entry, così che ogni indice sia costruito una sola volta a partire da esse
+	for _, index := range header.Indexes {
+		opCtx, cancel := ctx.operation()
+		_, err := ctx.client.Namespace(index.Namespace).CreateIndex(opCtx, index.Name, index.Keys, index.Field)
+		cancel()
+		if err != nil {
+			return reportError(fmt.Errorf("index %s: %w", index.Name, err))
+		}
+	}
+	restored, dropped, err := restoreCRDTs(ctx, header.CRDTs)
+	if err != nil {
+		return reportError(err)
+	}
+
+	// Riesegue i record del WAL successivi alla snapshot
+	replayer := &replayer{ctx: ctx, window: *window, code: exitOK}
+	for _, record := range records {
+		if err = replayer.replay(record); err != nil {
+			break
+		}
+	}
+	if drainErr := replayer.drain(); err == nil {
+		err = drainErr
+	}
+	if err != nil {
+		return reportError(err)
+	}
+
+	ctx.printer.restore(ctx.client.Endpoint(), importer.imported+restored, replayer.replayed, importer.skipped+replayer.skipped, importer.failed+replayer.failed, dropped)
+	if importer.code != exitOK {
+		return importer.code
+	}
+	return replayer.code
+}
+
+// restoreCRDTs scrive i valori dei CRDT del backup come nuove operazioni, e restituisce il numero di valori scritti e di valori scartati.
+// Le scritture di un unico client non possono essere concorrenti tra loro, quindi di un campo di una mappa con più valori concorrenti
+// viene ripristinato solo il maggiore in ordine lessicografico, e gli altri sono contati come scartati.
+func restoreCRDTs(ctx *cliContext, crdts *utils.BackupCRDTs) (int, int, error) {
+	if crdts == nil {
+		return 0, 0, nil
+	}
+	restored, dropped := 0, 0
+	write := func(kind string, key string, op func(opCtx context.Context) error) error {
+		opCtx, cancel := ctx.operation()
+		defer cancel()
+		if err := op(opCtx); err != nil {
+			return fmt.Errorf("%s %s: %w", kind, key, err)
+		}
+		restored++
+		return nil
+	}
+	for _, key := range sortedKeys(crdts.Counters) {
+		if err := write("counter", key, func(opCtx context.Context) error {
+			_, err := ctx.client.Increment(opCtx, key, crdts.Counters[key])
+			return err
+		}); err != nil {
+			return restored, dropped, err
+		}
+	}
+	for _, key := range sortedKeys(crdts.Sets) {
+		if err := write("set", key, func(opCtx context.Context) error {
+			for _, element := range crdts.Sets[key] {
+				if _, err := ctx.client.SetAdd(opCtx, key, element); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return restored, dropped, err
+		}
+	}
+	for _, key := range sortedKeys(crdts.Registers) {
+		if err := write("register", key, func(opCtx context.Context) error {
+			return ctx.client.RegisterSet(opCtx, key, crdts.Registers[key])
+		}); err != nil {
+			return restored, dropped, err
+		}
+	}
+	for _, key := range sortedKeys(crdts.Maps) {
+		if err := write("map", key, func(opCtx context.Context) error {
+			fields := crdts.Maps[key]
+			for _, field := range sortedKeys(fields) {
+				values := fields[field]
+				if _, err := ctx.client.MapPut(opCtx, key, field, values[len(values)-1]); err != nil {
+					return err
+				}
+				dropped += len(values) - 1
+			}
+			return nil
+		}); err != nil {
+			return restored, dropped, err
+		}
+	}
+	return restored, dropped, nil
+}
+
+// sortedKeys restituisce le chiavi della mappa in ordine lessicografico
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// readBackupHeader legge l'intestazione di un file prodotto da dbctl backup
+func readBackupHeader(reader *bufio.Reader) (backupHeader, error) {
+	var header backupHeader
+	line, err := reader.ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return header, fmt.Errorf("error while reading the backup: %w", err)
+	}
+	if strings.TrimSpace(string(line)) == "" {
+		return header, fmt.Errorf("%w: the backup is empty", errUsage)
+	}
+	if err := json.Unmarshal(line, &header); err != nil {
+		return header, fmt.Errorf("%w: invalid backup header: %v", errUsage, err)
+	}
+	if header.Consistency != "SEQUENTIAL" && header.Consistency != "CAUSAL" {
+		return header, fmt.Errorf("%w: the first line is not a backup header, produced by dbctl backup", errUsage)
+	}
+	return header, nil
+}
+
+// walRecords legge dal WAL i record da rieseguire dopo la snapshot: quelli non inclusi nella snapshot e applicati entro until (tutti se until è 0).
+// Il WAL di una replica contiene le modifiche nell'ordine in cui la replica le ha applicate, che rispetta l'ordine totale (sequenziale) o causale:
+// la lettura si ferma al primo record applicato dopo until, quindi la snapshot e i record rieseguiti formano uno stato consistente.
+func walRecords(dir string, header backupHeader, until int64) ([]utils.CDCRecord, error) {
+	records, err := utils.ReadCDC(dir, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	if (records[0].VectorClock != nil) != (header.Consistency == "CAUSAL") {
+		return nil, fmt.Errorf("the WAL in %s and the backup were written with different consistency types", dir)
+	}
+	// Se la rotazione ha rimosso i segmenti più vecchi, il WAL deve iniziare con una scrittura inclusa nella snapshot
+	if records[0].Offset > 0 && !header.includes(records[0].Version()) {
+		return nil, fmt.Errorf("the WAL in %s starts at offset %d, after the backup: the records in between were removed", dir, records[0].Offset)
+	}
+
+	var replay []utils.CDCRecord
+	for _, record := range records {
+		if until > 0 && record.AppliedAt > until {
+			break
+		}
+		if !header.includes(record.Version()) {
+			replay = append(replay, record)
+		}
+	}
+	return replay, nil
+}
+
+// replayer riesegue i record del WAL nell'ordine in cui sono stati applicati, mantenendo al più window scritture in attesa di risposta.
+// Le richieste di una sessione sono eseguite nell'ordine di invio, quindi le scritture inviate senza attenderne la risposta non vengono riordinate.
+type replayer struct {
+	ctx      *cliContext
+	window   int
+	pending  []pendingWrite
+	replayed int
+	skipped  int // Record EXPIRE, non rieseguiti perché le entry rieseguite mantengono la propria scadenza
+	failed   int // Record rifiutati dalla replica o che non possono essere rieseguiti
+	code     int // Codice di uscita del primo record non rieseguito
+}
+
+// replay riesegue un record del WAL. Le scritture di una chiave sono inviate senza attenderne la risposta,
+// mentre le altre operazioni sono eseguite dopo aver atteso la risposta di tutte le scritture inviate.
+// I record EXPIRE non sono rieseguiti: le PUT rieseguite mantengono la propria scadenza, e la rimozione di una versione scaduta
+// rieseguita come DELETE rimuoverebbe anche le scritture successive della chiave che nel WAL non la riconciliano.
+func (replayer *replayer) replay(record utils.CDCRecord) error {
+	client := replayer.ctx.client.Namespace(record.Namespace)
+	switch record.Op {
+	case utils.PUT:
+		// Una entry già scaduta non è più presente, quindi sostituisce l'eventuale valore precedente della chiave
+		if record.ExpiresAt > 0 && record.ExpiresAt <= time.Now().UnixMilli() {
+			return replayer.send(record, func(opCtx context.Context) *dbclient.Future[utils.Result] {
+				return client.DeleteAsync(opCtx, record.Key)
+			})
+		}
+		var options []dbclient.WriteOption
+		if record.ExpiresAt > 0 {
+			options = append(options, dbclient.WithTTL(time.Until(time.UnixMilli(record.ExpiresAt))))
+		}
+		return replayer.send(record, func(opCtx context.Context) *dbclient.Future[utils.Result] {
+			return client.PutAsync(opCtx, record.Key, record.Value, options...)
+		})
+	case utils.DELETE:
+		return replayer.send(record, func(opCtx context.Context) *dbclient.Future[utils.Result] {
+			return client.DeleteAsync(opCtx, record.Key)
+		})
+	case utils.EXPIRE:
+		replayer.skipped++
+		return nil
+	}
+
+	if err := replayer.drain(); err != nil {
+		return err
+	}
+	opCtx, cancel := replayer.ctx.operation()
+	defer cancel()
+	var err error
+	switch record.Op {
+	case utils.SET_PATH:
+		err = client.SetPath(opCtx, record.Key, record.Path, record.Value)
+	case utils.DELETE_PATH:
+		err = client.DeletePath(opCtx, record.Key, record.Path)
+	case utils.SET_TTL:
+		// Come per le PUT, una scadenza già trascorsa rimuove la chiave
+		if record.ExpiresAt > 0 && record.ExpiresAt <= time.Now().UnixMilli() {
+			err = client.Delete(opCtx, record.Key)
+			break
+		}
+		var ttl time.Duration
+		if record.ExpiresAt > 0 {
+			ttl = time.Until(time.UnixMilli(record.ExpiresAt))
+		}
+		_, err = client.SetTTL(opCtx, record.Key, ttl)
+	case utils.INCREMENT:
+		_, err = client.Increment(opCtx, record.Key, record.CRDT.Delta)
+	case utils.SET_ADD:
+		_, err = client.SetAdd(opCtx, record.Key, record.CRDT.Element)
+	case utils.SET_REMOVE:
+		// La rimozione elimina le aggiunte dell'elemento osservate nel cluster ripristinato, ossia quelle che la precedono nel WAL
+		_, err = client.SetRemove(opCtx, record.Key, record.CRDT.Element)
+	case utils.REGISTER_SET:
+		err = client.RegisterSet(opCtx, record.Key, record.Value)
+	case utils.MAP_PUT:
+		_, err = client.MapPut(opCtx, record.Key, record.CRDT.Field, string(record.Value))
+	case utils.MAP_REMOVE:
+		_, err = client.MapRemove(opCtx, record.Key, record.CRDT.Field)
+	case utils.CREATE_NAMESPACE:
+		config := utils.NamespaceArgs{Name: record.Key}
+		if record.NamespaceConfig != nil {
+			config = *record.NamespaceConfig
+		}
+		_, err = replayer.ctx.client.CreateNamespace(opCtx, config)
+		// Con consistenza causale una creazione concorrente dello stesso namespace può essere già inclusa nella snapshot
+		if errors.Is(err, dbclient.ErrAlreadyExists) && record.VectorClock != nil {
+			err = nil
+		}
+	case utils.DROP_NAMESPACE:
+		err = replayer.ctx.client.DropNamespace(opCtx, record.Key)
+	case utils.CREATE_INDEX:
+		if record.Index == nil {
+			err = fmt.Errorf("the record has no index definition")
+			break
+		}
+		_, err = client.CreateIndex(opCtx, record.Key, record.Index.Keys, record.Index.Field)
+		// Come per i namespace, una creazione concorrente dello stesso indice può essere già inclusa nella snapshot
+		if errors.Is(err, dbclient.ErrAlreadyExists) && record.VectorClock != nil {
+			err = nil
+		}
+	case utils.DROP_INDEX:
+		err = client.DropIndex(opCtx, record.Key)
+	default:
+		err = fmt.Errorf("%s operations can not be replayed", record.Op)
+	}
+	return replayer.done(record, err)
+}
+
+// send invia una scrittura, dopo aver atteso la risposta della più vecchia se ne sono già in attesa window
+func (replayer *replayer) send(record utils.CDCRecord, write func(opCtx context.Context) *dbclient.Future[utils.Result]) error {
+	if len(replayer.pending) == replayer.window {
+		if err := replayer.wait(); err != nil {
+			return err
+		}
+	}
+	opCtx, cancel := replayer.ctx.operation()
+	replayer.pending = append(replayer.pending, pendingWrite{record: record, future: write(opCtx), cancel: cancel})
+	return nil
+}
+
+// wait attende la risposta della scrittura inviata per prima
+func (replayer *replayer) wait() error {
+	write := replayer.pending[0]
+	replayer.pending = replayer.pending[1:]
+	_, err := write.future.Wait()
+	write.cancel()
+	return replayer.done(write.record, err)
+}
+
+// drain attende la risposta di tutte le scritture inviate
+func (replayer *replayer) drain() error {
+	for len(replayer.pending) > 0 {
+		if err := replayer.wait(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// done conta l'esito di un record rieseguito. Un record rifiutato è segnalato senza interrompere il restore,
+// mentre una replica non raggiungibile o un timeout lo interrompono.
+func (replayer *replayer) done(record utils.CDCRecord, err error) error {
+	if err == nil {
+		replayer.replayed++
+		return nil
+	}
+	if errors.Is(err, dbclient.ErrUnavailable) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	replayer.failed++
+	if replayer.code == exitOK {
+		replayer.code = exitCode(err)
+	}
+	replayer.ctx.printer.flush()
+	fmt.Fprintf(os.Stderr, "dbctl: WAL offset %d: %s key %s: %v\n", record.Offset, record.Op, record.Key, err)
+	return nil
+}
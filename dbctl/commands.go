@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"dbService/dbclient"
+	"dbService/utils"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// scanPageSize è il numero di entry richieste per ogni pagina di una scansione
+const scanPageSize = 100
+
+// cliContext contiene la configurazione indicata con i flag globali e lo stato condiviso dai comandi
+type cliContext struct {
+	name      string // Nome del comando eseguito
+	usage     string // Sintassi del comando eseguito
+	endpoints string
+	replica   int
+	namespace string
+	timeout   time.Duration // Durata massima di ogni operazione
+	printer   *printer
+	client    *dbclient.Client // Client connesso alle repliche, nil finché il comando non chiama connect
+}
+
+// flagSet restituisce l'insieme dei flag del comando, che stampa la sintassi del comando in caso di errore
+func (ctx *cliContext) flagSet() *flag.FlagSet {
+	flags := flag.NewFlagSet("dbctl "+ctx.name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: dbctl [global flags] %s\n", ctx.usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parse interpreta i flag del comando. Restituisce false, insieme al codice di uscita, se i flag non sono validi o se è stato richiesto l'help.
+func (ctx *cliContext) parse(flags *flag.FlagSet, arguments []string) (int, bool) {
+	err := flags.Parse(arguments)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK, false
+	}
+	if err != nil {
+		return exitUsage, false
+	}
+	return exitOK, true
+}
+
+// usageError stampa l'errore e la sintassi del comando, e restituisce il codice di uscita per un uso non valido
+func (ctx *cliContext) usageError(message string) int {
+	fmt.Fprintln(os.Stderr, "dbctl:", message)
+	fmt.Fprintf(os.Stderr, "Usage: dbctl [global flags] %s\n", ctx.usage)
+	return exitUsage
+}
+
+// connect si connette alle repliche indicate con i flag globali, restituisce exitOK oppure il codice di uscita dell'errore
+func (ctx *cliContext) connect() int {
+	addresses, err := resolveEndpoints(ctx.endpoints, ctx.replica)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "dbctl:", err)
+		return exitUsage
+	}
+	client, err := ctx.dial(addresses)
+	if err != nil {
+		return reportError(err)
+	}
+	ctx.client = client
+	return exitOK
+}
+
+// dial si connette alle repliche indicate, e restituisce un client che opera sul namespace indicato con -namespace
+func (ctx *cliContext) dial(addresses []string) (*dbclient.Client, error) {
+	dialCtx, cancel := context.WithTimeout(context.Background(), ctx.timeout)
+	defer cancel()
+	client, err := dbclient.Dial(dialCtx, dbclient.Config{Endpoints: addresses, Balancer: dbclient.NewSticky()})
+	if err != nil {
+		return nil, err
+	}
+	if ctx.namespace != "" {
+		return client.Namespace(ctx.namespace), nil
+	}
+	return client, nil
+}
+
+// operation restituisce il context con cui eseguire un'operazione, limitato dal timeout indicato con -timeout
+func (ctx *cliContext) operation() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), ctx.timeout)
+}
+
+// runGet stampa il valore delle chiavi indicate, nello stesso ordine.
+// Le letture sono eseguite con un'unica MultiGet, che con entrambe le consistenze segnala subito le chiavi non presenti;
+// con -wait ogni chiave è letta con Get, che con consistenza causale attende che la chiave sia presente.
+func runGet(ctx *cliContext, arguments []string) int {
+	flags := ctx.flagSet()
+	wait := flags.Bool("wait", false, "with causal consistency, wait until the keys exist instead of reporting them as not found")
+	if code, ok := ctx.parse(flags, arguments); !ok {
+		return code
+	}
+	if flags.NArg() == 0 {
+		return ctx.usageError("get requires at least one key")
+	}
+	if code := ctx.connect(); code != exitOK {
+		return code
+	}
+
+	if *wait {
+		code := exitOK
+		for _, key := range flags.Args() {
+			err := ctx.get(key)
+			if errors.Is(err, dbclient.ErrNotFound) {
+				code = exitNotFound
+			} else if err != nil {
+				return reportError(err)
+			}
+		}
+		return code
+	}
+
+	opCtx, cancel := ctx.operation()
+	defer cancel()
+	result, err := ctx.client.MultiGet(opCtx, flags.Args()...)
+	if err != nil {
+		return reportError(err)
+	}
+	code := exitOK
+	for i, key := range flags.Args() {
+		err := ctx.printLookup(key, result, i)
+		if err != nil && !errors.Is(err, dbclient.ErrNotFound) {
+			fmt.Fprintf(os.Stderr, "dbctl: get %s: %v\n", key, err)
+		}
+		if err != nil && code == exitOK {
+			code = exitCode(err)
+		}
+	}
+	return code
+}
+
+// get legge una chiave con Get, che con consistenza causale attende che la chiave sia presente, e ne stampa il valore
+func (ctx *cliContext) get(key string) error {
+	opCtx, cancel := ctx.operation()
+	defer cancel()
+	result, err := ctx.client.Get(opCtx, key)
+	if errors.Is(err, dbclient.ErrNotFound) {
+		ctx.printer.notFound(key)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	ctx.printer.value(key, result)
+	return nil
+}
+
+// lookup legge una chiave con MultiGet, che segnala subito l'assenza della chiave, e ne stampa il valore
+func (ctx *cliContext) lookup(key string) error {
+	opCtx, cancel := ctx.operation()
+	defer cancel()
+	result, err := ctx.client.MultiGet(opCtx, key)
+	if err != nil {
+		return err
+	}
+	return ctx.printLookup(key, result, 0)
+}
+
+// printLookup stampa il valore letto per l'i-esima chiave di una MultiGet, oppure l'assenza della chiave, nel qual caso restituisce ErrNotFound
+func (ctx *cliContext) printLookup(key string, result utils.BatchResult, i int) error {
+	if err := dbclient.EntryError("MultiGet", result, i); err != nil {
+		return err
+	}
+	if string(result.Results[i].Value) == "NOT FOUND" {
+		ctx.printer.notFound(key)
+		return dbclient.ErrNotFound
+	}
+	ctx.printer.value(key, result.Results[i])
+	return nil
+}
+
+// runPut scrive una chiave, con il valore indicato come argomento oppure letto da stdin
+func runPut(ctx *cliContext, arguments []string) int {
+	flags := ctx.flagSet()
+	ttl := flags.Duration("ttl", 0, "duration after which the entry expires, 0 for the default TTL of the namespace")
+	if code, ok := ctx.parse(flags, arguments); !ok {
+		return code
+	}
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		return ctx.usageError("put requires a key and an optional value")
+	}
+	var value []byte
+	if flags.NArg() == 1 || flags.Arg(1) == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "dbctl: error while reading the value from stdin:", err)
+			return exitError
+		}
+		value = data
+	} else {
+		value = []byte(flags.Arg(1))
+	}
+	if code := ctx.connect(); code != exitOK {
+		return code
+	}
+
+	if err := ctx.put(flags.Arg(0), value, *ttl); err != nil {
+		return reportError(err)
+	}
+	return exitOK
+}
+
+// put scrive una chiave e ne stampa l'esito
+func (ctx *cliContext) put(key string, value []byte, ttl time.Duration) error {
+	opCtx, cancel := ctx.operation()
+	defer cancel()
+	var options []dbclient.WriteOption
+	if ttl > 0 {
+		options = append(options, dbclient.WithTTL(ttl))
+	}
+	if err := ctx.client.Put(opCtx, key, value, options...); err != nil {
+		return err
+	}
+	ctx.printer.done("put", key)
+	return nil
+}
+
+// runDelete rimuove le chiavi indicate, una alla volta e nell'ordine indicato
+func runDelete(ctx *cliContext, arguments []string) int {
+	flags := ctx.flagSet()
+	if code, ok := ctx.parse(flags, arguments); !ok {
+		return code
+	}
+	if flags.NArg() == 0 {
+		return ctx.usageError("del requires at least one key")
+	}
+	if code := ctx.connect(); code != exitOK {
+		return code
+	}
+
+	for _, key := range flags.Args() {
+		if err := ctx.delete(key); err != nil {
+			return reportError(err)
+		}
+	}
+	return exitOK
+}
+
+// delete rimuove una chiave e ne stampa l'esito
+func (ctx *cliContext) delete(key string) error {
+	opCtx, cancel := ctx.operation()
+	defer cancel()
+	if err := ctx.client.Delete(opCtx, key); err != nil {
+		return err
+	}
+	ctx.printer.done("del", key)
+	return nil
+}
+
+// runScan stampa le entry le cui chiavi hanno il prefisso indicato, oppure quelle comprese nell'intervallo indicato con -start e -end.
+// La scansione è richiesta una pagina alla volta, ognuna con il timeout indicato con -timeout.
+func runScan(ctx *cliContext, arguments []string) int {
+	flags := ctx.flagSet()
+	limit := flags.Int("limit", 0, "maximum number of entries printed, 0 to print all of them")
+	start := flags.String("start", "", "first key of the range, ignored if PREFIX is given")
+	end := flags.String("end", "", "key following the last key of the range, ignored if PREFIX is given")
+	keysOnly := flags.Bool("keys-only", false, "print only the keys")
+	if code, ok := ctx.parse(flags, arguments); !ok {
+		return code
+	}
+	if flags.NArg() > 1 {
+		return ctx.usageError("scan accepts at most one prefix")
+	}
+	if code := ctx.connect(); code != exitOK {
+		return code
+	}
+
+	scanArgs := utils.ScanArgs{Start: *start, End: *end}
+	if flags.Arg(0) != "" {
+		scanArgs = utils.ScanArgs{Prefix: flags.Arg(0)}
+	}
+	printed := 0
+	for {
+		scanArgs.Limit = scanPageSize
+		if *limit > 0 && *limit-printed < scanPageSize {
+			scanArgs.Limit = *limit - printed
+		}
+		opCtx, cancel := ctx.operation()
+		var result utils.ScanResult
+		var err error
+		if scanArgs.Prefix != "" {
+			result, err = ctx.client.ScanPrefix(opCtx, scanArgs)
+		} else {
+			result, err = ctx.client.Scan(opCtx, scanArgs)
+		}
+		cancel()
+		if err != nil {
+			return reportError(err)
+		}
+		for _, entry := range result.Entries {
+			ctx.printer.entry(entry, *keysOnly)
+			printed++
+		}
+		if result.Cursor == "" || (*limit > 0 && printed >= *limit) {
+			return exitOK
+		}
+		scanArgs.Cursor = result.Cursor
+	}
+}
+
+// runBatch esegue in ordine le operazioni elencate in un file, una per riga:
+//
+//	get KEY
+//	put KEY VALUE
+//	del KEY
+//
+// Il valore di put è il resto della riga, spazi compresi. Le righe vuote e quelle che iniziano con # sono ignorate.
+// Le letture non attendono le chiavi non presenti. L'esecuzione si interrompe al primo errore, salvo con -keep-going,
+// mentre le chiavi non trovate non la interrompono. Il codice di uscita è quello della prima operazione non riuscita.
+func runBatch(ctx *cliContext, arguments []string) int {
+	flags := ctx.flagSet()
+	keepGoing := flags.Bool("keep-going", false, "keep running the operations after an error")
+	if code, ok := ctx.parse(flags, arguments); !ok {
+		return code
+	}
+	if flags.NArg() != 1 {
+		return ctx.usageError("batch requires a file")
+	}
+
+	input := os.Stdin
+	if flags.Arg(0) != "-" {
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "dbctl:", err)
+			return exitError
+		}
+		defer func(file *os.File) {
+			_ = file.Close()
+		}(file)
+		input = file
+	}
+	if code := ctx.connect(); code != exitOK {
+		return code
+	}
+
+	code := exitOK
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		err := ctx.batchLine(line)
+		if err == nil {
+			continue
+		}
+		if code == exitOK {
+			code = exitCode(err)
+		}
+		if errors.Is(err, dbclient.ErrNotFound) {
+			continue
+		}
+		ctx.printer.flush()
+		if errors.Is(err, context.DeadlineExceeded) {
+			fmt.Fprintf(os.Stderr, "dbctl: line %d: operation timed out\n", lineNumber)
+		} else {
+			fmt.Fprintf(os.Stderr, "dbctl: line %d: %v\n", lineNumber, err)
+		}
+		if !*keepGoing {
+			return code
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, "dbctl: error while reading the batch file:", err)
+		return exitError
+	}
+	return code
+}
+
+// batchLine esegue l'operazione descritta da una riga di un file batch
+func (ctx *cliContext) batchLine(line string) error {
+	op, rest, _ := strings.Cut(line, " ")
+	rest = strings.TrimLeft(rest, " \t")
+	switch strings.ToLower(op) {
+	case "get":
+		if rest == "" || strings.ContainsAny(rest, " \t") {
+			return fmt.Errorf("%w: get requires exactly one key", errUsage)
+		}
+		return ctx.lookup(rest)
+	case "put":
+		key, value, found := strings.Cut(rest, " ")
+		if key == "" || !found {
+			return fmt.Errorf("%w: put requires a key and a value", errUsage)
+		}
+		return ctx.put(key, []byte(value), 0)
+	case "del":
+		if rest == "" || strings.ContainsAny(rest, " \t") {
+			return fmt.Errorf("%w: del requires exactly one key", errUsage)
+		}
+		return ctx.delete(rest)
+	default:
+		return fmt.Errorf("%w: unknown operation %q", errUsage, op)
+	}
+}
@@ -0,0 +1,247 @@
+// Comando dbctl: client da riga di comando non interattivo, utilizzabile in script e pipeline.
+//
+// Uso:
+//
+//	dbctl [flag globali] <comando> [flag del comando] [argomenti]
+//
+// Comandi:
+//
+//	get KEY...            stampa il valore delle chiavi
+//	put KEY [VALUE]       scrive una chiave, leggendo il valore da stdin se assente o pari a "-"
+//	del KEY...            rimuove le chiavi
+//	scan [PREFIX]         stampa le entry le cui chiavi hanno il prefisso indicato, oppure tutte le entry
+//	batch FILE            esegue le operazioni elencate nel file, una per riga ("-" per leggerle da stdin)
+//	export [FILE]         scrive le entry presenti su una replica in un file JSONL o CSV, con le relative versioni
+//	import FILE           scrive nel namespace le entry lette da un file JSONL o CSV, a gruppi con MultiPut
+//	backup [FILE]         scrive una snapshot consistente di tutti i namespace di una replica, presa senza interrompere le scritture
+//	restore FILE          ripristina un backup in un cluster vuoto, rieseguendo il WAL fino all'istante indicato con -until
+//	admin status          stampa lo stato interno di ogni replica: clock, code di messaggi, numeri di sequenza e dimensione dello store
+//	admin dump            stampa le entry presenti su ogni replica
+//
+// Il codice di uscita è 0 in caso di successo, 1 per errori restituiti dalle repliche, 2 per un uso non valido,
+// 3 se una chiave non esiste, 4 se nessuna replica è raggiungibile e 5 se la richiesta non termina entro il timeout.
+package main
+
+import (
+	"context"
+	"dbService/dbclient"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/joho/godotenv"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Codici di uscita del comando
+const (
+	exitOK          = 0
+	exitError       = 1
+	exitUsage       = 2
+	exitNotFound    = 3
+	exitUnavailable = 4
+	exitTimeout     = 5
+)
+
+var (
+	NumReplicas int
+	BasePort    int
+)
+
+// errUsage segnala una riga non valida di un file batch
+var errUsage = errors.New("invalid usage")
+
+// command descrive un sottocomando di dbctl
+type command struct {
+	usage string                                        // Sintassi del comando
+	help  string                                        // Descrizione del comando
+	run   func(ctx *cliContext, arguments []string) int // Interpreta i flag e gli argomenti del comando, lo esegue e restituisce il codice di uscita
+}
+
+// commands contiene i sottocomandi di dbctl, indicizzati per nome
+var commands = map[string]*command{
+	"get": {
+		usage: "get [-wait] KEY...",
+		help:  "print the values of the keys",
+		run:   runGet,
+	},
+	"put": {
+		usage: "put [-ttl DURATION] KEY [VALUE]",
+		help:  "write a key, reading the value from stdin if VALUE is missing or \"-\"",
+		run:   runPut,
+	},
+	"del": {
+		usage: "del KEY...",
+		help:  "delete the keys",
+		run:   runDelete,
+	},
+	"scan": {
+		usage: "scan [-limit N] [-start KEY] [-end KEY] [-keys-only] [PREFIX]",
+		help:  "print the entries whose keys have the prefix, or all the entries",
+		run:   runScan,
+	},
+	"batch": {
+		usage: "batch [-keep-going] FILE",
+		help:  "run the operations listed in FILE, one per line (\"-\" for stdin)",
+		run:   runBatch,
+	},
+	"export": {
+		usage: "export [-format jsonl|csv] [FILE]",
+		help:  "write a consistent snapshot of the entries of a replica to FILE (stdout if missing or \"-\")",
+		run:   runExport,
+	},
+	"import": {
+		usage: "import [-format jsonl|csv] [-batch-size N] [-window N] FILE",
+		help:  "write the entries of FILE (\"-\" for stdin) to every replica, in batches",
+		run:   runImport,
+	},
+	"backup": {
+		usage: "backup [FILE]",
+		help:  "write a consistent snapshot of every namespace of a replica, to be restored with restore, to FILE (stdout if missing or \"-\")",
+		run:   runBackup,
+	},
+	"restore": {
+		usage: "restore [-wal DIR] [-until TIME] [-batch-size N] [-window N] FILE",
+		help:  "write a backup to an empty cluster, then replay the WAL in DIR up to TIME",
+		run:   runRestore,
+	},
+	"admin": {
+		usage: "admin status|dump",
+		help:  "print the internal state (status) or the entries (dump) of every replica",
+		run:   runAdmin,
+	},
+}
+
+// commandOrder è l'ordine con cui i comandi sono elencati nell'help
+var commandOrder = []string{"get", "put", "del", "scan", "batch", "export", "import", "backup", "restore", "admin"}
+
+func init() {
+	// Carica le variabili d'ambiente dal file .env, se presente: i flag permettono di indicare le repliche anche senza .env
+	_ = godotenv.Load()
+
+	NumReplicas, _ = strconv.Atoi(os.Getenv("NUM_REPLICAS"))
+	BasePort, _ = strconv.Atoi(os.Getenv("BASE_PORT_TO_CLIENT"))
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run interpreta i flag globali e il sottocomando, lo esegue e restituisce il codice di uscita
+func run(arguments []string) int {
+	global := flag.NewFlagSet("dbctl", flag.ContinueOnError)
+	endpoints := global.String("endpoints", "", "comma-separated replica addresses (host:port), defaults to the local replicas in .env")
+	replica := global.Int("replica", -1, "index of the local replica to contact, -1 to use all of them")
+	namespace := global.String("namespace", "", "namespace of the operations, empty for the default namespace")
+	output := global.String("output", "plain", "output format: plain or json")
+	timeout := global.Duration("timeout", 10*time.Second, "maximum duration of each operation")
+	global.Usage = func() { printUsage(global) }
+
+	if err := global.Parse(arguments); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if global.NArg() == 0 {
+		printUsage(global)
+		return exitUsage
+	}
+	if *output != "plain" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "dbctl: invalid output format %q, it must be plain or json\n", *output)
+		return exitUsage
+	}
+
+	name := global.Arg(0)
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "dbctl: unknown command %q\n", name)
+		printUsage(global)
+		return exitUsage
+	}
+	ctx := &cliContext{
+		name:      name,
+		usage:     cmd.usage,
+		endpoints: *endpoints,
+		replica:   *replica,
+		namespace: *namespace,
+		timeout:   *timeout,
+		printer:   newPrinter(*output),
+	}
+	code := cmd.run(ctx, global.Args()[1:])
+	ctx.printer.flush()
+	if ctx.client != nil {
+		_ = ctx.client.Close()
+	}
+	return code
+}
+
+// resolveEndpoints restituisce gli indirizzi delle repliche da contattare: quelli indicati con -endpoints,
+// oppure le repliche locali configurate nel file .env, eventualmente ridotte alla replica indicata con -replica
+func resolveEndpoints(endpoints string, replica int) ([]string, error) {
+	var addresses []string
+	if endpoints != "" {
+		for _, address := range strings.Split(endpoints, ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				addresses = append(addresses, address)
+			}
+		}
+	} else {
+		if NumReplicas <= 0 || BasePort <= 0 {
+			return nil, fmt.Errorf("no replicas configured, use -endpoints or run from a directory with a .env file")
+		}
+		addresses = dbclient.LocalEndpoints(BasePort, NumReplicas)
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("no endpoints given")
+	}
+	if replica >= 0 {
+		if replica >= len(addresses) {
+			return nil, fmt.Errorf("replica %d does not exist, there are %d replicas", replica, len(addresses))
+		}
+		addresses = addresses[replica : replica+1]
+	}
+	return addresses, nil
+}
+
+// printUsage stampa l'help del comando
+func printUsage(global *flag.FlagSet) {
+	fmt.Fprintln(os.Stderr, "Usage: dbctl [global flags] <command> [command flags] [arguments]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, name := range commandOrder {
+		fmt.Fprintf(os.Stderr, "  %-66s %s\n", commands[name].usage, commands[name].help)
+	}
+	fmt.Fprintln(os.Stderr, "\nGlobal flags:")
+	global.PrintDefaults()
+	fmt.Fprintln(os.Stderr, "\nExit codes: 0 success, 1 error, 2 invalid usage, 3 key not found, 4 no replica reachable, 5 timeout")
+}
+
+// exitCode restituisce il codice di uscita corrispondente all'errore di un'operazione
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case errors.Is(err, dbclient.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, dbclient.ErrUnavailable):
+		return exitUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return exitTimeout
+	default:
+		return exitError
+	}
+}
+
+// reportError stampa l'errore su stderr e restituisce il codice di uscita corrispondente
+func reportError(err error) int {
+	if errors.Is(err, context.DeadlineExceeded) {
+		fmt.Fprintln(os.Stderr, "dbctl: operation timed out")
+	} else {
+		fmt.Fprintln(os.Stderr, "dbctl:", err)
+	}
+	return exitCode(err)
+}
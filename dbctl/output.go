@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"dbService/utils"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// jsonVersion rappresenta nell'output JSON la versione di un valore
+type jsonVersion struct {
+	Clock       int                    `json:"clock,omitempty"`
+	ServerID    int                    `json:"server_id"`
+	VectorClock []int                  `json:"vector_clock,omitempty"`
+	Timestamp   *utils.HybridTimestamp `json:"timestamp,omitempty"` // Assente con consistenza sequenziale
+}
+
+// jsonResult rappresenta nell'output JSON l'esito di un'operazione su una chiave
+type jsonResult struct {
+	Op       string       `json:"op"`
+	Endpoint string       `json:"endpoint,omitempty"` // Replica che ha restituito la entry, presente solo per dbctl admin dump
+	Key      string       `json:"key"`
+	Value    *string      `json:"value,omitempty"`
+	Found    *bool        `json:"found,omitempty"` // Presente solo per le letture
+	Version  *jsonVersion `json:"version,omitempty"`
+	Siblings int          `json:"siblings,omitempty"` // Numero di versioni concorrenti non ancora riconciliate (consistenza causale)
+}
+
+// printer stampa su stdout l'esito delle operazioni nel formato scelto con -output.
+// In formato plain ogni riga contiene un valore, una entry (chiave e valore separati da tab) oppure OK;
+// in formato json ogni riga contiene un oggetto JSON, così che l'output possa essere elaborato una riga alla volta.
+// Gli errori sono sempre scritti su stderr.
+type printer struct {
+	json   bool
+	writer *bufio.Writer
+}
+
+// newPrinter restituisce un printer per il formato indicato
+func newPrinter(format string) *printer {
+	return &printer{json: format == "json", writer: bufio.NewWriter(os.Stdout)}
+}
+
+// value stampa il valore letto per una chiave
+func (printer *printer) value(key string, result utils.Result) {
+	if !printer.json {
+		printer.line(string(result.Value))
+		return
+	}
+	found := true
+	value := string(result.Value)
+	printer.object(jsonResult{Op: "get", Key: key, Value: &value, Found: &found, Version: newJSONVersion(result.Version), Siblings: len(result.Siblings)})
+}
+
+// notFound stampa l'assenza di una chiave letta: in formato plain l'avviso è scritto su stderr, così che stdout contenga solo valori
+func (printer *printer) notFound(key string) {
+	if !printer.json {
+		printer.flush()
+		fmt.Fprintf(os.Stderr, "dbctl: key %s not found\n", key)
+		return
+	}
+	found := false
+	printer.object(jsonResult{Op: "get", Key: key, Found: &found})
+}
+
+// entry stampa una entry restituita da una scansione
+func (printer *printer) entry(result utils.Result, keysOnly bool) {
+	if !printer.json {
+		if keysOnly {
+			printer.line(result.Key)
+		} else {
+			printer.line(result.Key + "\t" + string(result.Value))
+		}
+		return
+	}
+	object := jsonResult{Op: "scan", Key: result.Key, Version: newJSONVersion(result.Version), Siblings: len(result.Siblings)}
+	if !keysOnly {
+		value := string(result.Value)
+		object.Value = &value
+	}
+	printer.object(object)
+}
+
+// done stampa l'esito positivo di una scrittura
+func (printer *printer) done(op string, key string) {
+	if !printer.json {
+		printer.line("OK")
+		return
+	}
+	printer.object(jsonResult{Op: op, Key: key})
+}
+
+// line stampa una riga di testo
+func (printer *printer) line(text string) {
+	_, _ = printer.writer.WriteString(text + "\n")
+}
+
+// object stampa un oggetto JSON su una riga
+func (printer *printer) object(object any) {
+	data, _ := json.Marshal(object)
+	_, _ = printer.writer.Write(append(data, '\n'))
+}
+
+// flush scrive su stdout l'output ancora nel buffer
+func (printer *printer) flush() {
+	_ = printer.writer.Flush()
+}
+
+// newJSONVersion converte una versione nel formato dell'output JSON, nil se la versione non è stata assegnata
+func newJSONVersion(version utils.Version) *jsonVersion {
+	if version.Clock <= 0 && version.VectorClock == nil {
+		return nil
+	}
+	jsonVersion := &jsonVersion{Clock: version.Clock, ServerID: version.ServerID, VectorClock: version.VectorClock}
+	if version.VectorClock != nil {
+		timestamp := version.Timestamp
+		jsonVersion.Timestamp = &timestamp
+	}
+	return jsonVersion
+}
+
+// jsonTransfer rappresenta nell'output JSON il riepilogo di un export, di un import o di un restore
+type jsonTransfer struct {
+	Op       string `json:"op"`
+	Endpoint string `json:"endpoint"`           // Replica da cui sono state lette, o a cui sono state inviate, le entry
+	Entries  int    `json:"entries"`            // Entry esportate, importate o ripristinate
+	Replayed int    `json:"replayed,omitempty"` // Record del WAL rieseguiti (solo restore)
+	Skipped  int    `json:"skipped,omitempty"`
+	Failed   int    `json:"failed,omitempty"`
+	Dropped  int    `json:"dropped,omitempty"` // Valori concorrenti dei campi delle mappe CRDT non ripristinati (solo restore)
+}
+
+// jsonBackup rappresenta nell'output JSON il riepilogo di un backup
+type jsonBackup struct {
+	Op         string      `json:"op"`
+	Endpoint   string      `json:"endpoint"` // Replica da cui è stata presa la snapshot
+	Namespaces int         `json:"namespaces"`
+	Entries    int         `json:"entries"`
+	Cut        jsonVersion `json:"cut"` // Posizione delle scritture incluse nella snapshot
+}
+
+// transfer stampa il riepilogo di un export o di un import: il numero di entry trasferite, di quelle già scadute e di quelle rifiutate dalla replica
+func (printer *printer) transfer(op string, endpoint string, entries int, skipped int, failed int) {
+	if printer.json {
+		printer.object(jsonTransfer{Op: op, Endpoint: endpoint, Entries: entries, Skipped: skipped, Failed: failed})
+		return
+	}
+	if op == "export" {
+		printer.line(fmt.Sprintf("exported %d entries from %s", entries, endpoint))
+		return
+	}
+	printer.line(fmt.Sprintf("imported %d entries through %s, %d expired entries skipped, %d entries failed", entries, endpoint, skipped, failed))
+}
+
+// backup stampa il riepilogo di un backup: il numero di namespace e di entry salvati e la posizione delle scritture incluse
+func (printer *printer) backup(endpoint string, namespaces int, entries int, cut utils.Version) {
+	if printer.json {
+		printer.object(jsonBackup{Op: "backup", Endpoint: endpoint, Namespaces: namespaces, Entries: entries, Cut: jsonVersion{Clock: cut.Clock, ServerID: cut.ServerID, VectorClock: cut.VectorClock}})
+		return
+	}
+	position := fmt.Sprintf("vector clock %v", cut.VectorClock)
+	if !cut.IsVector() {
+		position = fmt.Sprintf("write %s", cut)
+	}
+	printer.line(fmt.Sprintf("backed up %d entries in %d namespaces from %s, up to %s", entries, namespaces, endpoint, position))
+}
+
+// restore stampa il riepilogo di un restore: il numero di entry ripristinate dalla snapshot, di record del WAL rieseguiti, di entry già scadute,
+// di scritture rifiutate e di valori concorrenti delle mappe CRDT non ripristinati
+func (printer *printer) restore(endpoint string, entries int, replayed int, skipped int, failed int, dropped int) {
+	if printer.json {
+		printer.object(jsonTransfer{Op: "restore", Endpoint: endpoint, Entries: entries, Replayed: replayed, Skipped: skipped, Failed: failed, Dropped: dropped})
+		return
+	}
+	printer.line(fmt.Sprintf("restored %d entries through %s and replayed %d WAL records, %d expired entries skipped, %d writes failed, %d concurrent map values dropped", entries, endpoint, replayed, skipped, failed, dropped))
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"dbService/dbclient"
+	"dbService/utils"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// csvHeader è l'intestazione dei file CSV prodotti da dbctl export.
+// La colonna encoding vale text oppure base64, per i valori che non sono testo UTF-8.
+var csvHeader = []string{"key", "value", "encoding", "version", "expires_at"}
+
+// exportRecord rappresenta una entry in un file JSONL prodotto da dbctl export
+type exportRecord struct {
+	Namespace   string       `json:"namespace,omitempty"` // Namespace della entry, presente solo nei file prodotti da dbctl backup
+	Key         string       `json:"key"`
+	Value       *string      `json:"value,omitempty"`        // Valore, se è testo UTF-8
+	ValueBase64 string       `json:"value_base64,omitempty"` // Valore codificato in base64, se non è testo UTF-8
+	Version     *jsonVersion `json:"version,omitempty"`      // Versione della entry sulla replica esportata, ignorata dall'import
+	ExpiresAt   int64        `json:"expires_at,omitempty"`   // Istante di scadenza in millisecondi, assente se la entry non scade
+}
+
+// importEntry rappresenta una entry letta da un file da importare
+type importEntry struct {
+	line      int // Riga del file in cui è descritta la entry
+	namespace string
+	key       string
+	value     []byte
+	expiresAt int64
+}
+
+// pendingBatch rappresenta un gruppo di entry inviato con MultiPut, in attesa di risposta
+type pendingBatch struct {
+	client  *dbclient.Client // Client del namespace in cui sono scritte le entry
+	entries []importEntry
+	future  *dbclient.Future[utils.BatchResult]
+	cancel  context.CancelFunc
+}
+
+// transferFormat restituisce il formato indicato con -format, oppure quello ricavato dall'estensione del file
+func transferFormat(format string, path string) (string, error) {
+	switch format {
+	case "jsonl", "csv":
+		return format, nil
+	case "":
+		if strings.HasSuffix(strings.ToLower(path), ".csv") {
+			return "csv", nil
+		}
+		return "jsonl", nil
+	default:
+		return "", fmt.Errorf("invalid format %q, it must be jsonl or csv", format)
+	}
+}
+
+// runExport scrive su file le entry del namespace presenti su una replica, con le relative versioni.
+// Le entry sono lette con un'unica richiesta, che la replica esegue senza applicare scritture nel frattempo:
+// il file contiene quindi lo stato dello store della replica in un singolo istante.
+func runExport(ctx *cliContext, arguments []string) int {
+	flags := ctx.flagSet()
+	format := flags.String("format", "", "file format: jsonl or csv, defaults to csv for .csv files and jsonl otherwise")
+	if code, ok := ctx.parse(flags, arguments); !ok {
+		return code
+	}
+	if flags.NArg() > 1 {
+		return ctx.usageError("export accepts at most one file")
+	}
+	path := flags.Arg(0)
+	if path == "" {
+		path = "-"
+	}
+	fileFormat, err := transferFormat(*format, path)
+	if err != nil {
+		return ctx.usageError(err.Error())
+	}
+	if code := ctx.connect(); code != exitOK {
+		return code
+	}
+
+	opCtx, cancel := ctx.operation()
+	entries, err := ctx.client.Dump(opCtx)
+	cancel()
+	if err != nil {
+		return reportError(err)
+	}
+
+	writer := ctx.printer.writer
+	var file *os.File
+	if path != "-" {
+		file, err = os.Create(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "dbctl:", err)
+			return exitError
+		}
+		writer = bufio.NewWriter(file)
+	}
+	if fileFormat == "csv" {
+		err = writeCSV(writer, entries)
+	} else {
+		// Il file non indica il namespace: l'import scrive le entry nel namespace indicato con -namespace
+		err = writeJSONL(writer, "", entries)
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if file != nil {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "dbctl: error while writing the export:", err)
+		return exitError
+	}
+
+	// Con l'export su stdout il riepilogo è omesso, così che stdout contenga solo le entry
+	if path != "-" {
+		ctx.printer.transfer("export", ctx.client.Endpoint(), len(entries), 0, 0)
+	}
+	return exitOK
+}
+
+// writeJSONL scrive le entry del namespace una per riga, ognuna come oggetto JSON
+func writeJSONL(writer *bufio.Writer, namespace string, entries []utils.Result) error {
+	encoder := json.NewEncoder(writer)
+	for _, entry := range entries {
+		record := exportRecord{Namespace: namespace, Key: entry.Key, Version: newJSONVersion(entry.Version), ExpiresAt: entry.ExpiresAt}
+		if utf8.Valid(entry.Value) {
+			value := string(entry.Value)
+			record.Value = &value
+		} else {
+			record.ValueBase64 = base64.StdEncoding.EncodeToString(entry.Value)
+		}
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCSV scrive le entry in formato CSV, precedute dall'intestazione
+func writeCSV(writer *bufio.Writer, entries []utils.Result) error {
+	csvWriter := csv.NewWriter(writer)
+	if err := csvWriter.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		value, encoding := string(entry.Value), "text"
+		if !utf8.Valid(entry.Value) {
+			value, encoding = base64.StdEncoding.EncodeToString(entry.Value), "base64"
+		}
+		expiresAt := ""
+		if entry.ExpiresAt > 0 {
+			expiresAt = strconv.FormatInt(entry.ExpiresAt, 10)
+		}
+		if err := csvWriter.Write([]string{entry.Key, value, encoding, entry.Version.String(), expiresAt}); err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// runImport scrive nel namespace le entry lette da un file prodotto da dbctl export, o scritto a mano nello stesso formato.
+// Le entry sono scritte con MultiPut a gruppi di -batch-size, così che ogni replica le riceva tramite il protocollo di replicazione,
+// e fino a -window gruppi sono inviati senza attenderne la risposta. Le entry già scadute sono ignorate,
+// mentre le altre mantengono l'istante di scadenza. Le versioni indicate nel file sono ignorate: ogni entry è una nuova scrittura.
+func runImport(ctx *cliContext, arguments []string) int {
+	flags := ctx.flagSet()
+	format := flags.String("format", "", "file format: jsonl or csv, defaults to csv for .csv files and jsonl otherwise")
+	batchSize := flags.Int("batch-size", 100, "number of entries written by each MultiPut")
+	window := flags.Int("window", 4, "maximum number of MultiPut waiting for a response")
+	if code, ok := ctx.parse(flags, arguments); !ok {
+		return code
+	}
+	if flags.NArg() != 1 {
+		return ctx.usageError("import requires a file")
+	}
+	if *batchSize <= 0 || *window <= 0 {
+		return ctx.usageError("-batch-size and -window must be positive")
+	}
+	fileFormat, err := transferFormat(*format, flags.Arg(0))
+	if err != nil {
+		return ctx.usageError(err.Error())
+	}
+	input := os.Stdin
+	if flags.Arg(0) != "-" {
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "dbctl:", err)
+			return exitError
+		}
+		defer func(file *os.File) {
+			_ = file.Close()
+		}(file)
+		input = file
+	}
+	if code := ctx.connect(); code != exitOK {
+		return code
+	}
+
+	importer := &importer{ctx: ctx, client: ctx.client, window: *window, code: exitOK}
+	var batch []importEntry
+	read := func(entry importEntry) error {
+		if entry.expiresAt > 0 && entry.expiresAt <= time.Now().UnixMilli() {
+			importer.skipped++
+			return nil
+		}
+		batch = append(batch, entry)
+		if len(batch) < *batchSize {
+			return nil
+		}
+		err := importer.send(batch)
+		batch = nil
+		return err
+	}
+	if fileFormat == "csv" {
+		err = readCSV(input, read)
+	} else {
+		err = readJSONL(input, 1, read)
+	}
+	if err == nil && len(batch) > 0 {
+		err = importer.send(batch)
+	}
+	// Anche in caso di errore attende i gruppi già inviati, così da riportare le entry rifiutate
+	if drainErr := importer.drain(); err == nil {
+		err = drainErr
+	}
+	if err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, "dbctl:", err)
+			return exitUsage
+		}
+		return reportError(err)
+	}
+
+	ctx.printer.transfer("import", ctx.client.Endpoint(), importer.imported, importer.skipped, importer.failed)
+	return importer.code
+}
+
+// importer invia le entry da importare, mantenendo al più window gruppi in attesa di risposta
+type importer struct {
+	ctx      *cliContext
+	client   *dbclient.Client // Client del namespace in cui sono scritti i gruppi inviati
+	window   int
+	pending  []pendingBatch
+	imported int
+	skipped  int // Entry già scadute
+	failed   int // Entry rifiutate dalla replica
+	code     int // Codice di uscita della prima entry rifiutata
+}
+
+// send invia un gruppo di entry con MultiPut, dopo aver atteso la risposta del gruppo più vecchio se ne sono già in attesa window
+func (importer *importer) send(entries []importEntry) error {
+	if len(importer.pending) == importer.window {
+		if err := importer.wait(); err != nil {
+			return err
+		}
+	}
+	args := make([]utils.Args, len(entries))
+	for i, entry := range entries {
+		args[i] = utils.Args{Key: entry.key, Value: entry.value}
+		if entry.expiresAt > 0 {
+			args[i].TTL = time.Until(time.UnixMilli(entry.expiresAt))
+		}
+	}
+	opCtx, cancel := importer.ctx.operation()
+	importer.pending = append(importer.pending, pendingBatch{client: importer.client, entries: entries, future: importer.client.MultiPutAsync(opCtx, args), cancel: cancel})
+	return nil
+}
+
+// wait attende la risposta del gruppo inviato per primo, e ne conta le entry scritte e quelle rifiutate.
+// Le entry con un valore troppo grande per essere trasportato in un MultiPut sono scritte singolarmente con Put, che lo trasferisce a frammenti.
+func (importer *importer) wait() error {
+	batch := importer.pending[0]
+	importer.pending = importer.pending[1:]
+	result, err := batch.future.Wait()
+	batch.cancel()
+	if err != nil {
+		return err
+	}
+	for i, entry := range batch.entries {
+		entryErr := dbclient.EntryError("MultiPut", result, i)
+		if errors.Is(entryErr, dbclient.ErrValueTooLarge) {
+			entryErr = importer.put(batch.client, entry)
+		}
+		if entryErr != nil {
+			importer.failed++
+			if importer.code == exitOK {
+				importer.code = exitCode(entryErr)
+			}
+			importer.ctx.printer.flush()
+			fmt.Fprintf(os.Stderr, "dbctl: line %d: key %s: %v\n", entry.line, entry.key, entryErr)
+			continue
+		}
+		importer.imported++
+	}
+	return nil
+}
+
+// put scrive singolarmente una entry con il client indicato
+func (importer *importer) put(client *dbclient.Client, entry importEntry) error {
+	opCtx, cancel := importer.ctx.operation()
+	defer cancel()
+	var options []dbclient.WriteOption
+	if entry.expiresAt > 0 {
+		options = append(options, dbclient.WithTTL(time.Until(time.UnixMilli(entry.expiresAt))))
+	}
+	return client.Put(opCtx, entry.key, entry.value, options...)
+}
+
+// drain attende la risposta di tutti i gruppi inviati
+func (importer *importer) drain() error {
+	for len(importer.pending) > 0 {
+		if err := importer.wait(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readJSONL legge le entry di un file JSONL, una per riga, a partire dalla riga firstLine del file. Le righe vuote sono ignorate.
+func readJSONL(input io.Reader, firstLine int, read func(importEntry) error) error {
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for lineNumber := firstLine; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var record exportRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return fmt.Errorf("%w: line %d: %v", errUsage, lineNumber, err)
+		}
+		entry := importEntry{line: lineNumber, namespace: record.Namespace, key: record.Key, expiresAt: record.ExpiresAt}
+		switch {
+		case record.Value != nil:
+			entry.value = []byte(*record.Value)
+		case record.ValueBase64 != "":
+			value, err := base64.StdEncoding.DecodeString(record.ValueBase64)
+			if err != nil {
+				return fmt.Errorf("%w: line %d: invalid value_base64: %v", errUsage, lineNumber, err)
+			}
+			entry.value = value
+		}
+		if entry.key == "" {
+			return fmt.Errorf("%w: line %d: missing key", errUsage, lineNumber)
+		}
+		if err := read(entry); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error while reading the file: %w", err)
+	}
+	return nil
+}
+
+// readCSV legge le entry di un file CSV. La prima riga è l'intestazione, che deve contenere le colonne key e value,
+// mentre le colonne encoding ed expires_at sono facoltative e le altre sono ignorate.
+func readCSV(input io.Reader, read func(importEntry) error) error {
+	csvReader := csv.NewReader(input)
+	csvReader.FieldsPerRecord = -1
+	header, err := csvReader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, ok := columns["key"]; !ok {
+		return fmt.Errorf("%w: the CSV header has no key column", errUsage)
+	}
+	if _, ok := columns["value"]; !ok {
+		return fmt.Errorf("%w: the CSV header has no value column", errUsage)
+	}
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return record[i]
+	}
+
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", errUsage, err)
+		}
+		lineNumber, _ := csvReader.FieldPos(0)
+		entry := importEntry{line: lineNumber, key: field(record, "key"), value: []byte(field(record, "value"))}
+		if entry.key == "" {
+			return fmt.Errorf("%w: line %d: missing key", errUsage, lineNumber)
+		}
+		switch field(record, "encoding") {
+		case "", "text":
+		case "base64":
+			value, err := base64.StdEncoding.DecodeString(field(record, "value"))
+			if err != nil {
+				return fmt.Errorf("%w: line %d: invalid base64 value: %v", errUsage, lineNumber, err)
+			}
+			entry.value = value
+		default:
+			return fmt.Errorf("%w: line %d: invalid encoding %q, it must be text or base64", errUsage, lineNumber, field(record, "encoding"))
+		}
+		if expiresAt := field(record, "expires_at"); expiresAt != "" {
+			entry.expiresAt, err = strconv.ParseInt(expiresAt, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%w: line %d: invalid expires_at %q", errUsage, lineNumber, expiresAt)
+			}
+		}
+		if err := read(entry); err != nil {
+			return err
+		}
+	}
+}
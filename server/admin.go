@@ -0,0 +1,141 @@
+package main
+
+import (
+	"dbService/utils"
+	"log"
+	"sync"
+	"time"
+)
+
+// PeerActivity tiene traccia dell'istante in cui la replica ha ricevuto l'ultimo messaggio da ogni altra replica
+type PeerActivity struct {
+	lastReceived map[int]time.Time
+	mutex        sync.Mutex
+}
+
+// received registra la ricezione di un messaggio dalla replica indicata
+func (activity *PeerActivity) received(serverID int) {
+	activity.mutex.Lock()
+	defer activity.mutex.Unlock()
+	if activity.lastReceived == nil {
+		activity.lastReceived = make(map[int]time.Time)
+	}
+	activity.lastReceived[serverID] = time.Now()
+}
+
+// last restituisce l'istante di ricezione dell'ultimo messaggio dalla replica indicata, nil se non ne sono stati ricevuti
+func (activity *PeerActivity) last(serverID int) *time.Time {
+	activity.mutex.Lock()
+	defer activity.mutex.Unlock()
+	lastReceived, exist := activity.lastReceived[serverID]
+	if !exist {
+		return nil
+	}
+	return &lastReceived
+}
+
+// current restituisce il numero di sequenza corrente, senza incrementarlo
+func (seqNum *NextSeqNum) current() int {
+	seqNum.mutex.Lock()
+	defer seqNum.mutex.Unlock()
+	return seqNum.SeqNum
+}
+
+// Status restituisce lo stato interno della replica: clock di Lamport, code di messaggi, numeri di sequenza attesi da ogni altra replica e dimensione dei namespace
+func (db *DbSequential) Status(args utils.StatusArgs, result *utils.ReplicaStatus) error {
+	db.Clock.mutex.Lock()
+	clock := db.Clock.value
+	db.Clock.mutex.Unlock()
+
+	*result = utils.ReplicaStatus{
+		ServerID:     db.ID,
+		Consistency:  "SEQUENTIAL",
+		Clock:        clock,
+		NextSeqNum:   db.NextSeqNum.current(),
+		MessageQueue: db.MessageQueue.Status(),
+		Namespaces:   namespaceUsage(db.Namespaces),
+	}
+	for i := 0; i < NumReplicas; i++ {
+		if i == db.ID {
+			continue
+		}
+		result.Peers = append(result.Peers, utils.PeerStatus{
+			ServerID:       i,
+			Address:        GetServerAddress(i).GetFullAddress(),
+			ExpectedSeqNum: db.ExpectedNextSeqNum[i].current(),
+			FIFOQueue:      db.FIFOQueues[i].Status(),
+			LastReceived:   db.Activity.last(i),
+		})
+	}
+	return nil
+}
+
+// Status restituisce lo stato interno della replica: clock vettoriale, code di messaggi, numeri di sequenza attesi da ogni altra replica e dimensione dei namespace
+func (db *DbCausal) Status(args utils.StatusArgs, result *utils.ReplicaStatus) error {
+	db.Clock.mutex.Lock()
+	clock := append([]int(nil), db.Clock.value...)
+	db.Clock.mutex.Unlock()
+
+	*result = utils.ReplicaStatus{
+		ServerID:     db.ID,
+		Consistency:  "CAUSAL",
+		VectorClock:  clock,
+		NextSeqNum:   db.NextSeqNum.current(),
+		MessageQueue: db.MessageQueue.Status(),
+		Namespaces:   namespaceUsage(db.Namespaces),
+	}
+	for i := 0; i < NumReplicas; i++ {
+		if i == db.ID {
+			continue
+		}
+		result.Peers = append(result.Peers, utils.PeerStatus{
+			ServerID:       i,
+			Address:        GetServerAddress(i).GetFullAddress(),
+			ExpectedSeqNum: db.ExpectedNextSeqNum[i].current(),
+			FIFOQueue:      db.FIFOQueues[i].Status(),
+			LastReceived:   db.Activity.last(i),
+		})
+	}
+	return nil
+}
+
+// Dump restituisce tutte le entry di un namespace della replica, così come sono presenti nel suo store
+func (db *DbSequential) Dump(args utils.DumpArgs, result *utils.DumpResult) error {
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	result.Entries = namespace.Store.dump()
+	return nil
+}
+
+// Dump restituisce tutte le entry di un namespace della replica, comprese le versioni concorrenti non ancora riconciliate
+func (db *DbCausal) Dump(args utils.DumpArgs, result *utils.DumpResult) error {
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	result.Entries = namespace.Store.dump()
+	return nil
+}
+
+// namespaceUsage restituisce il numero di chiavi e la dimensione di ogni namespace presente sulla replica
+func namespaceUsage(namespaces *Namespaces) []utils.NamespaceUsage {
+	var usage []utils.NamespaceUsage
+	for _, info := range namespaces.list() {
+		usage = append(usage, utils.NamespaceUsage{Name: info.Config.Name, Keys: info.Keys, Size: info.Size})
+	}
+	return usage
+}
+
+// logNamespaceUsage riporta nel log la dimensione di ogni namespace della replica.
+// Il contenuto dello store può essere consultato con dbctl admin dump finché la replica è attiva.
+func logNamespaceUsage(namespaces *Namespaces) {
+	for _, usage := range namespaceUsage(namespaces) {
+		name := usage.Name
+		if name == utils.DefaultNamespace {
+			name = "default"
+		}
+		log.Printf("Namespace %s: %d keys, %d bytes", name, usage.Keys, usage.Size)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"dbService/utils"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SnapshotGate coordina l'applicazione delle scritture con le snapshot di backup della replica.
+// Le scritture sono applicate mantenendo il lock in lettura, mentre una snapshot è presa con il lock in scrittura:
+// la snapshot non osserva quindi scritture applicate solo in parte, e la posizione registrata dal gate corrisponde esattamente al suo contenuto.
+type SnapshotGate struct {
+	mutex   sync.RWMutex
+	applied utils.Version // Versione dell'ultima scrittura applicata nell'ordine totale (sequenziale), oppure unione dei clock delle scritture applicate (causale)
+	pending int           // Scritture locali a cui è già stato assegnato un clock vettoriale, ma non ancora applicate (consistenza causale)
+	state   sync.Mutex    // Protegge applied e pending, aggiornati da scritture applicate in parallelo
+}
+
+// enter segnala l'inizio dell'applicazione di una scrittura, che una snapshot non può interrompere
+func (gate *SnapshotGate) enter() {
+	gate.mutex.RLock()
+}
+
+// exit segnala la fine dell'applicazione di una scrittura
+func (gate *SnapshotGate) exit() {
+	gate.mutex.RUnlock()
+}
+
+// advance registra l'applicazione della scrittura con la versione indicata
+func (gate *SnapshotGate) advance(version utils.Version) {
+	gate.state.Lock()
+	defer gate.state.Unlock()
+	if version.IsVector() {
+		gate.applied = utils.Version{VectorClock: utils.MergeClocks(make([]int, NumReplicas), gate.applied.VectorClock, version.VectorClock)}
+	} else if !version.LessOrEqual(gate.applied) {
+		gate.applied = utils.Version{Clock: version.Clock, ServerID: version.ServerID}
+	}
+}
+
+// assign registra l'assegnazione di un clock vettoriale a una scrittura locale, che sarà applicata in seguito
+func (gate *SnapshotGate) assign() {
+	gate.state.Lock()
+	defer gate.state.Unlock()
+	gate.pending++
+}
+
+// deliver registra l'applicazione di una scrittura locale a cui era stato assegnato un clock vettoriale
+func (gate *SnapshotGate) deliver() {
+	gate.state.Lock()
+	defer gate.state.Unlock()
+	gate.pending--
+}
+
+// snapshot invoca take con la posizione delle scritture applicate, impedendo l'applicazione di altre scritture fino al suo termine.
+// Con consistenza causale due scritture locali possono essere applicate in ordine diverso da quello dei loro clock:
+// la snapshot attende quindi che non ci siano scritture locali con un clock già assegnato e non ancora applicate, così che il clock registrato non ne includa nessuna.
+func (gate *SnapshotGate) snapshot(take func(cut utils.Version)) {
+	for {
+		gate.mutex.Lock()
+		gate.state.Lock()
+		pending, cut := gate.pending, gate.applied
+		gate.state.Unlock()
+		if pending == 0 {
+			take(cut)
+			gate.mutex.Unlock()
+			return
+		}
+		gate.mutex.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// snapshot restituisce la configurazione, gli indici secondari e tutte le entry di ogni namespace presente sulla replica
+func (namespaces *Namespaces) snapshot() []utils.BackupNamespace {
+	namespaces.mutex.Lock()
+	var all []*Namespace
+	for _, namespace := range namespaces.byName {
+		all = append(all, namespace)
+	}
+	namespaces.mutex.Unlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Config.Name < all[j].Config.Name
+	})
+	var snapshot []utils.BackupNamespace
+	for _, namespace := range all {
+		var indexes []utils.IndexArgs
+		for _, index := range namespace.Store.listIndexes() {
+			indexes = append(indexes, index.Config)
+		}
+		snapshot = append(snapshot, utils.BackupNamespace{Config: namespace.Config, Indexes: indexes, Entries: namespace.Store.dump()})
+	}
+	return snapshot
+}
+
+// Backup restituisce una snapshot di tutti i namespace della replica, con la versione dell'ultima scrittura applicata.
+// Tutte le repliche applicano le scritture nello stesso ordine totale, quindi la snapshot di una replica corrisponde a uno stato attraversato da ogni replica.
+func (db *DbSequential) Backup(args utils.BackupArgs, result *utils.BackupResult) error {
+	db.Snapshots.snapshot(func(cut utils.Version) {
+		*result = utils.BackupResult{
+			ServerID:    db.ID,
+			Consistency: "SEQUENTIAL",
+			Cut:         cut,
+			TakenAt:     time.Now().UnixMilli(),
+			Namespaces:  db.Namespaces.snapshot(),
+		}
+	})
+	return nil
+}
+
+// Backup restituisce una snapshot di tutti i namespace e dei valori dei CRDT della replica, con il clock vettoriale delle scritture applicate.
+// La consegna causalmente ordinata garantisce che la snapshot includa anche tutte le scritture che precedono causalmente quelle incluse.
+func (db *DbCausal) Backup(args utils.BackupArgs, result *utils.BackupResult) error {
+	db.Snapshots.snapshot(func(cut utils.Version) {
+		if cut.VectorClock == nil {
+			cut.VectorClock = make([]int, NumReplicas)
+		}
+		*result = utils.BackupResult{
+			ServerID:    db.ID,
+			Consistency: "CAUSAL",
+			Cut:         cut,
+			TakenAt:     time.Now().UnixMilli(),
+			Namespaces:  db.Namespaces.snapshot(),
+			CRDTs:       db.CRDTs.snapshot(),
+		}
+	})
+	return nil
+}
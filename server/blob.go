@@ -0,0 +1,95 @@
+package main
+
+import (
+	"dbService/utils"
+	"fmt"
+	"sync"
+)
+
+// BlobStore mantiene i valori di grandi dimensioni trasferiti a frammenti, finché il messaggio che li scrive non viene applicato.
+// Le code di ordinamento contengono solo l'identificatore del valore, non il valore stesso.
+type BlobStore struct {
+	blobs  map[string]*blob
+	nextID int
+	mutex  sync.Mutex
+}
+
+// blob è un valore in corso di ricezione
+type blob struct {
+	data     []byte
+	received int // Byte ricevuti finora
+}
+
+// NewBlobStore crea uno store vuoto per i valori trasferiti a frammenti
+func NewBlobStore() *BlobStore {
+	return &BlobStore{blobs: make(map[string]*blob)}
+}
+
+// newID restituisce un identificatore univoco per un valore trasferito dalla replica indicata
+func (store *BlobStore) newID(serverID int) string {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.nextID++
+	return fmt.Sprintf("%d.%d", serverID, store.nextID)
+}
+
+// put registra un valore completo, ad esempio quello scritto dalla replica che riceve la richiesta del client
+func (store *BlobStore) put(id string, value []byte) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.blobs[id] = &blob{data: value, received: len(value)}
+}
+
+// addChunk copia un frammento ricevuto nella sua posizione all'interno del valore
+func (store *BlobStore) addChunk(chunk utils.Chunk) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	value, exist := store.blobs[chunk.BlobID]
+	if !exist {
+		value = &blob{data: make([]byte, chunk.Size)}
+		store.blobs[chunk.BlobID] = value
+	}
+	if chunk.Offset < 0 || chunk.Offset+len(chunk.Data) > len(value.data) {
+		fmt.Printf("CHUNK of value %s at offset %d discarded, out of bounds\n", chunk.BlobID, chunk.Offset)
+		return
+	}
+	copy(value.data[chunk.Offset:], chunk.Data)
+	value.received += len(chunk.Data)
+}
+
+// take restituisce il valore completo indicato e lo rimuove dallo store.
+// Poiché i frammenti sono inviati prima del messaggio che scrive il valore, e i messaggi sono ricevuti in ordine FIFO,
+// al momento dell'applicazione della scrittura il valore è sempre completo.
+func (store *BlobStore) take(id string) ([]byte, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	value, exist := store.blobs[id]
+	if !exist {
+		return nil, fmt.Errorf("value %s not received", id)
+	}
+	delete(store.blobs, id)
+	if value.received < len(value.data) {
+		return nil, fmt.Errorf("value %s incomplete, %d of %d bytes received", id, value.received, len(value.data))
+	}
+	return value.data, nil
+}
+
+// checkValueSize verifica che il valore da scrivere non superi la dimensione massima configurata
+func checkValueSize(key string, value []byte) error {
+	if MaxValueSize > 0 && len(value) > MaxValueSize {
+		return fmt.Errorf("value of key %s is %d bytes, the maximum value size is %d bytes", key, len(value), MaxValueSize)
+	}
+	return nil
+}
+
+// checkInlineValue verifica che il valore possa essere trasportato all'interno del messaggio che lo scrive.
+// Solo la Put trasferisce a frammenti i valori più grandi di CHUNK_SIZE, le operazioni su più chiavi li trasportano nel messaggio stesso.
+func checkInlineValue(key string, value []byte) error {
+	if err := checkValueSize(key, value); err != nil {
+		return err
+	}
+	if ChunkSize > 0 && len(value) > ChunkSize {
+		return fmt.Errorf("value of key %s is %d bytes, values larger than %d bytes must be written with Put", key, len(value), ChunkSize)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"dbService/utils"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const defaultCDCMaxFileSize = 10 * 1024 * 1024 // Dimensione massima di un segmento del flusso CDC, se non configurata
+
+// CDCLog scrive il flusso CDC (change data capture) di una replica: un record JSON per ogni modifica applicata allo store.
+// Il flusso è diviso in segmenti, ognuno chiamato con l'offset del suo primo record: al superamento della dimensione massima
+// viene aperto un nuovo segmento, e i segmenti più vecchi oltre il numero massimo configurato vengono rimossi.
+type CDCLog struct {
+	dir         string
+	maxFileSize int64
+	maxFiles    int // Numero massimo di segmenti mantenuti, 0 per mantenerli tutti
+	file        *os.File
+	size        int64 // Dimensione del segmento corrente
+	nextOffset  int64 // Offset da assegnare al prossimo record
+	mutex       sync.Mutex
+}
+
+// NewCDCLog apre il flusso CDC nella cartella indicata.
+// Se la cartella contiene già dei segmenti, la numerazione dei record riprende dall'ultimo record scritto.
+func NewCDCLog(dir string, maxFileSize int64, maxFiles int) (*CDCLog, error) {
+	if maxFileSize <= 0 {
+		maxFileSize = defaultCDCMaxFileSize
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	cdc := &CDCLog{dir: dir, maxFileSize: maxFileSize, maxFiles: maxFiles}
+
+	// Recupera l'offset successivo all'ultimo record scritto in precedenza
+	segments, err := utils.CDCSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) > 0 {
+		last := segments[len(segments)-1]
+		cdc.nextOffset, err = utils.CDCSegmentOffset(last)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CDC segment %s: %w", last, err)
+		}
+		records, err := utils.ReadCDC(dir, cdc.nextOffset, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(records) > 0 {
+			cdc.nextOffset = records[len(records)-1].Offset + 1
+		}
+	}
+
+	if err := cdc.rotate(); err != nil {
+		return nil, err
+	}
+	return cdc, nil
+}
+
+// record aggiunge al flusso i record indicati, assegnando a ognuno il proprio offset.
+// Se il flusso CDC non è configurato (cdc pari a nil) non fa nulla.
+func (cdc *CDCLog) record(records ...utils.CDCRecord) {
+	if cdc == nil || len(records) == 0 {
+		return
+	}
+	cdc.mutex.Lock()
+	defer cdc.mutex.Unlock()
+
+	appliedAt := time.Now().UnixMilli()
+	for _, record := range records {
+		record.Offset = cdc.nextOffset
+		record.AppliedAt = appliedAt
+		line, err := json.Marshal(record)
+		if err != nil {
+			log.Println("Error while encoding CDC record: ", err)
+			continue
+		}
+		line = append(line, '\n')
+
+		if cdc.size+int64(len(line)) > cdc.maxFileSize && cdc.size > 0 {
+			if err := cdc.rotate(); err != nil {
+				log.Println("Error while rotating CDC segment: ", err)
+				return
+			}
+		}
+		n, err := cdc.file.Write(line)
+		cdc.size += int64(n)
+		if err != nil {
+			log.Println("Error while writing CDC record: ", err)
+			return
+		}
+		cdc.nextOffset++
+	}
+}
+
+// rotate chiude il segmento corrente, apre un nuovo segmento a partire dal prossimo offset e rimuove i segmenti in eccesso.
+// Deve essere invocata mantenendo il lock sul flusso.
+func (cdc *CDCLog) rotate() error {
+	if cdc.file != nil {
+		cdc.file.Close()
+	}
+	file, err := os.OpenFile(filepath.Join(cdc.dir, utils.CDCSegmentName(cdc.nextOffset)), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	cdc.file = file
+	cdc.size = info.Size()
+
+	if cdc.maxFiles <= 0 {
+		return nil
+	}
+	segments, err := utils.CDCSegments(cdc.dir)
+	if err != nil {
+		return err
+	}
+	for len(segments) > cdc.maxFiles {
+		if err := os.Remove(filepath.Join(cdc.dir, segments[0])); err != nil {
+			return err
+		}
+		segments = segments[1:]
+	}
+	return nil
+}
+
+// sequentialChanges restituisce i record CDC delle modifiche applicate da un messaggio con consistenza sequenziale.
+// Di una transazione sono registrate le scritture del ramo eseguito, indicato da succeeded.
+func sequentialChanges(msg utils.Message, succeeded bool) []utils.CDCRecord {
+	change := utils.CDCRecord{Op: msg.Op, Namespace: msg.Namespace, Key: msg.Key, Path: msg.Path, Value: msg.Value, Clock: msg.Clock, ServerID: msg.ServerID, ExpiresAt: msg.ExpiresAt, NamespaceConfig: msg.NamespaceConfig, Index: msg.Index}
+	switch msg.Op {
+	case utils.PUT, utils.DELETE, utils.EXPIRE, utils.SET_TTL, utils.SET_PATH, utils.DELETE_PATH, utils.CREATE_NAMESPACE, utils.DROP_NAMESPACE, utils.CREATE_INDEX, utils.DROP_INDEX:
+		return []utils.CDCRecord{change}
+	case utils.MULTI_PUT, utils.MULTI_DELETE:
+		op := utils.PUT
+		if msg.Op == utils.MULTI_DELETE {
+			op = utils.DELETE
+		}
+		var changes []utils.CDCRecord
+		for _, entry := range msg.Batch {
+			changes = append(changes, utils.CDCRecord{Op: op, Namespace: msg.Namespace, Key: entry.Key, Value: entry.Value, Clock: msg.Clock, ServerID: msg.ServerID, ExpiresAt: entry.ExpiresAt})
+		}
+		return changes
+	case utils.TXN:
+		ops := msg.Txn.Success
+		if !succeeded {
+			ops = msg.Txn.Failure
+		}
+		var changes []utils.CDCRecord
+		for _, op := range ops {
+			if op.Op == utils.PUT || op.Op == utils.DELETE {
+				changes = append(changes, utils.CDCRecord{Op: op.Op, Namespace: msg.Namespace, Key: op.Key, Value: op.Value, Clock: msg.Clock, ServerID: msg.ServerID, ExpiresAt: op.ExpiresAt})
+			}
+		}
+		return changes
+	}
+	return nil
+}
+
+// causalChanges restituisce i record CDC delle modifiche applicate da un messaggio con consistenza causale
+func causalChanges(msg utils.VectorMessage) []utils.CDCRecord {
+	change := utils.CDCRecord{Op: msg.Op, Namespace: msg.Namespace, Key: msg.Key, Path: msg.Path, Value: msg.Value, VectorClock: msg.Clock, ServerID: msg.ServerID, ExpiresAt: msg.ExpiresAt, CRDT: msg.CRDT, NamespaceConfig: msg.NamespaceConfig, Index: msg.Index}
+	switch msg.Op {
+	case utils.CREATE_NAMESPACE, utils.DROP_NAMESPACE, utils.CREATE_INDEX, utils.DROP_INDEX, utils.PUT, utils.DELETE, utils.EXPIRE, utils.SET_TTL, utils.SET_PATH, utils.DELETE_PATH, utils.INCREMENT, utils.SET_ADD, utils.SET_REMOVE, utils.REGISTER_SET, utils.MAP_PUT, utils.MAP_REMOVE:
+		return []utils.CDCRecord{change}
+	case utils.MULTI_PUT, utils.MULTI_DELETE:
+		op := utils.PUT
+		if msg.Op == utils.MULTI_DELETE {
+			op = utils.DELETE
+		}
+		var changes []utils.CDCRecord
+		for _, entry := range msg.Batch {
+			changes = append(changes, utils.CDCRecord{Op: op, Namespace: msg.Namespace, Key: entry.Key, Value: entry.Value, VectorClock: msg.Clock, ServerID: msg.ServerID, ExpiresAt: entry.ExpiresAt})
+		}
+		return changes
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"dbService/utils"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConflictResolver sceglie quale tra due versioni concorrenti di una chiave deve essere mantenuta.
+// La scelta deve dipendere solo dalle due versioni, e non dall'ordine con cui vengono ricevute, così che tutte le repliche convergano allo stesso valore.
+// Una delle due versioni può rappresentare una DELETE (Deleted pari a true).
+type ConflictResolver interface {
+	Resolve(stored utils.VersionedValue, incoming utils.VersionedValue) utils.VersionedValue
+}
+
+// Politiche di risoluzione dei conflitti configurabili
+const (
+	SIBLINGS    = "SIBLINGS"    // Le versioni concorrenti sono mantenute finché il client non le riconcilia
+	LWW         = "LWW"         // Vince la scrittura con timestamp ibrido maggiore
+	LOWEST_ID   = "LOWEST_ID"   // Vince la scrittura originata dalla replica con ID minore
+	HIGHEST_ID  = "HIGHEST_ID"  // Vince la scrittura originata dalla replica con ID maggiore
+	DELETE_WINS = "DELETE_WINS" // Una DELETE prevale sempre su una PUT concorrente
+	PUT_WINS    = "PUT_WINS"    // Una PUT prevale sempre su una DELETE concorrente
+)
+
+// LastWriterWins risolve i conflitti mantenendo la scrittura con timestamp ibrido maggiore (a parità di timestamp, quella della replica con ID maggiore)
+type LastWriterWins struct{}
+
+func (LastWriterWins) Resolve(stored utils.VersionedValue, incoming utils.VersionedValue) utils.VersionedValue {
+	if stored.Version.Timestamp == incoming.Version.Timestamp {
+		return HighestReplicaWins{}.Resolve(stored, incoming)
+	}
+	if stored.Version.Timestamp.Before(incoming.Version.Timestamp) {
+		return incoming
+	}
+	return stored
+}
+
+// LowestReplicaWins risolve i conflitti mantenendo la scrittura originata dalla replica con ID minore
+type LowestReplicaWins struct{}
+
+func (LowestReplicaWins) Resolve(stored utils.VersionedValue, incoming utils.VersionedValue) utils.VersionedValue {
+	if incoming.Version.ServerID < stored.Version.ServerID {
+		return incoming
+	}
+	return stored
+}
+
+// HighestReplicaWins risolve i conflitti mantenendo la scrittura originata dalla replica con ID maggiore
+type HighestReplicaWins struct{}
+
+func (HighestReplicaWins) Resolve(stored utils.VersionedValue, incoming utils.VersionedValue) utils.VersionedValue {
+	if incoming.Version.ServerID > stored.Version.ServerID {
+		return incoming
+	}
+	return stored
+}
+
+// DeleteWins risolve i conflitti tra una PUT e una DELETE a favore della DELETE.
+// Tra due scritture dello stesso tipo vince l'ultima scrittura.
+type DeleteWins struct{}
+
+func (DeleteWins) Resolve(stored utils.VersionedValue, incoming utils.VersionedValue) utils.VersionedValue {
+	if stored.Deleted != incoming.Deleted {
+		if incoming.Deleted {
+			return incoming
+		}
+		return stored
+	}
+	return LastWriterWins{}.Resolve(stored, incoming)
+}
+
+// PutWins risolve i conflitti tra una PUT e una DELETE a favore della PUT.
+// Tra due scritture dello stesso tipo vince l'ultima scrittura.
+type PutWins struct{}
+
+func (PutWins) Resolve(stored utils.VersionedValue, incoming utils.VersionedValue) utils.VersionedValue {
+	if stored.Deleted != incoming.Deleted {
+		if incoming.Deleted {
+			return stored
+		}
+		return incoming
+	}
+	return LastWriterWins{}.Resolve(stored, incoming)
+}
+
+// NewConflictResolver restituisce il resolver corrispondente al nome della politica.
+// Per la politica SIBLINGS restituisce nil, in quanto le versioni concorrenti non vengono risolte automaticamente.
+func NewConflictResolver(policy string) (ConflictResolver, error) {
+	switch strings.ToUpper(strings.TrimSpace(policy)) {
+	case SIBLINGS, "":
+		return nil, nil
+	case LWW:
+		return LastWriterWins{}, nil
+	case LOWEST_ID:
+		return LowestReplicaWins{}, nil
+	case HIGHEST_ID:
+		return HighestReplicaWins{}, nil
+	case DELETE_WINS:
+		return DeleteWins{}, nil
+	case PUT_WINS:
+		return PutWins{}, nil
+	}
+	return nil, fmt.Errorf("unknown conflict policy %q", policy)
+}
+
+// prefixResolver associa un resolver alle chiavi che iniziano con un dato prefisso
+type prefixResolver struct {
+	prefix   string
+	resolver ConflictResolver
+}
+
+// ConflictPolicies mantiene i resolver configurati per prefisso di chiave
+type ConflictPolicies struct {
+	defaultResolver ConflictResolver
+	byPrefix        []prefixResolver // Ordinati per lunghezza del prefisso decrescente
+}
+
+// NewConflictPolicies costruisce le politiche di risoluzione dei conflitti a partire dalla configurazione.
+// defaultPolicy è la politica applicata alle chiavi che non corrispondono ad alcun prefisso,
+// mentre prefixPolicies ha il formato "prefisso1=POLITICA1,prefisso2=POLITICA2".
+func NewConflictPolicies(defaultPolicy string, prefixPolicies string) (*ConflictPolicies, error) {
+	defaultResolver, err := NewConflictResolver(defaultPolicy)
+	if err != nil {
+		return nil, err
+	}
+	policies := &ConflictPolicies{defaultResolver: defaultResolver}
+
+	for _, entry := range strings.Split(prefixPolicies, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		separator := strings.LastIndex(entry, "=")
+		if separator < 0 {
+			return nil, fmt.Errorf("invalid conflict policy %q, expected prefix=POLICY", entry)
+		}
+		resolver, err := NewConflictResolver(entry[separator+1:])
+		if err != nil {
+			return nil, err
+		}
+		policies.byPrefix = append(policies.byPrefix, prefixResolver{prefix: strings.TrimSpace(entry[:separator]), resolver: resolver})
+	}
+
+	// Il prefisso più lungo che corrisponde alla chiave determina la politica
+	sort.SliceStable(policies.byPrefix, func(i, j int) bool {
+		return len(policies.byPrefix[i].prefix) > len(policies.byPrefix[j].prefix)
+	})
+	return policies, nil
+}
+
+// ResolverFor restituisce il resolver da applicare alla chiave, oppure nil se le versioni concorrenti devono essere mantenute come siblings
+func (policies *ConflictPolicies) ResolverFor(key string) ConflictResolver {
+	if policies == nil {
+		return nil
+	}
+	for _, entry := range policies.byPrefix {
+		if strings.HasPrefix(key, entry.prefix) {
+			return entry.resolver
+		}
+	}
+	return policies.defaultResolver
+}
+
+// HybridClock implementa un hybrid logical clock, utilizzato per assegnare alle scritture timestamp confrontabili con l'ultima scrittura
+type HybridClock struct {
+	last  utils.HybridTimestamp
+	mutex sync.Mutex
+}
+
+// Now restituisce il timestamp da assegnare a una nuova scrittura locale
+func (hlc *HybridClock) Now() utils.HybridTimestamp {
+	hlc.mutex.Lock()
+	defer hlc.mutex.Unlock()
+	wallTime := time.Now().UnixMilli()
+	if wallTime > hlc.last.WallTime {
+		hlc.last = utils.HybridTimestamp{WallTime: wallTime}
+	} else {
+		hlc.last.Logical++
+	}
+	return hlc.last
+}
+
+// Update aggiorna il clock alla ricezione di una scrittura, così che i timestamp successivi seguano quello ricevuto
+func (hlc *HybridClock) Update(remote utils.HybridTimestamp) {
+	hlc.mutex.Lock()
+	defer hlc.mutex.Unlock()
+	if hlc.last.Before(remote) {
+		hlc.last = remote
+	}
+}
+
+// supersedes indica se la creazione con versione incoming prevale su quella, concorrente, con versione stored (consistenza causale).
+// Come per la politica LWW prevale il timestamp ibrido maggiore e, a parità, la replica con ID maggiore, così che tutte le repliche scelgano la stessa creazione.
+func supersedes(stored utils.Version, incoming utils.Version) bool {
+	winner := LastWriterWins{}.Resolve(utils.VersionedValue{Version: stored}, utils.VersionedValue{Version: incoming})
+	return winner.Version.ServerID == incoming.ServerID && winner.Version.Timestamp == incoming.Timestamp
+}
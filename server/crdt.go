@@ -0,0 +1,263 @@
+package main
+
+import (
+	"dbService/utils"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// PNCounter è un contatore che supporta incrementi e decrementi.
+// Per ogni replica mantiene separatamente la somma degli incrementi (P) e dei decrementi (N) originati da essa.
+type PNCounter struct {
+	P map[int]int
+	N map[int]int
+}
+
+// Value restituisce il valore del contatore, ossia la differenza tra la somma degli incrementi e quella dei decrementi
+func (counter *PNCounter) Value() int {
+	value := 0
+	for _, p := range counter.P {
+		value += p
+	}
+	for _, n := range counter.N {
+		value -= n
+	}
+	return value
+}
+
+// ORSet è un observed-remove set: ogni aggiunta di un elemento è identificata da un tag univoco,
+// e una rimozione elimina solo i tag osservati dalla replica che l'ha richiesta.
+// In questo modo un'aggiunta concorrente a una rimozione prevale.
+type ORSet struct {
+	Elements map[string]map[string]bool // Per ogni elemento, l'insieme dei tag delle aggiunte non ancora rimosse
+}
+
+// Members restituisce gli elementi presenti nell'insieme, in ordine lessicografico
+func (set *ORSet) Members() []string {
+	members := []string{}
+	for element, tags := range set.Elements {
+		if len(tags) > 0 {
+			members = append(members, element)
+		}
+	}
+	sort.Strings(members)
+	return members
+}
+
+// MVMap è una mappa i cui campi sono registri multi-valore: le scritture concorrenti su uno stesso campo sono mantenute tutte
+type MVMap struct {
+	Fields map[string][]utils.VersionedValue
+}
+
+// Values restituisce i valori correnti di ogni campo della mappa, in ordine lessicografico
+func (mvMap *MVMap) Values() map[string][]string {
+	fields := make(map[string][]string)
+	for field, values := range mvMap.Fields {
+		for _, value := range values {
+			fields[field] = append(fields[field], string(value.Value))
+		}
+		sort.Strings(fields[field])
+	}
+	return fields
+}
+
+// CRDTStore mantiene i valori dei tipi di dato CRDT della replica.
+// Ogni tipo di dato ha un proprio spazio delle chiavi, separato dallo store key-value.
+type CRDTStore struct {
+	Counters  map[string]*PNCounter
+	Sets      map[string]*ORSet
+	Registers map[string]utils.VersionedValue // Registri last-writer-wins: tra due scritture concorrenti prevale quella con timestamp ibrido maggiore
+	Maps      map[string]*MVMap
+	mutex     sync.Mutex
+}
+
+// NewCRDTStore crea uno store vuoto per i tipi di dato CRDT
+func NewCRDTStore() CRDTStore {
+	return CRDTStore{
+		Counters:  make(map[string]*PNCounter),
+		Sets:      make(map[string]*ORSet),
+		Registers: make(map[string]utils.VersionedValue),
+		Maps:      make(map[string]*MVMap),
+	}
+}
+
+// apply applica allo store l'operazione CRDT trasportata dal messaggio.
+// Le operazioni sono commutative tra loro quando concorrenti, e il multicast causalmente ordinato consegna le operazioni
+// in relazione causa-effetto nello stesso ordine su tutte le repliche: quindi tutte le repliche convergono allo stesso stato.
+func (store *CRDTStore) apply(msg utils.VectorMessage) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	op := msg.CRDT
+	switch msg.Op {
+	case utils.INCREMENT:
+		counter := store.counter(msg.Key)
+		if op.Delta >= 0 {
+			counter.P[msg.ServerID] += op.Delta
+		} else {
+			counter.N[msg.ServerID] -= op.Delta
+		}
+		fmt.Printf("INCREMENT counter %s by %d, value %d\n", msg.Key, op.Delta, counter.Value())
+
+	case utils.SET_ADD:
+		set := store.set(msg.Key)
+		if set.Elements[op.Element] == nil {
+			set.Elements[op.Element] = make(map[string]bool)
+		}
+		set.Elements[op.Element][op.Tag] = true
+		fmt.Printf("SET ADD set %s element %s tag %s\n", msg.Key, op.Element, op.Tag)
+
+	case utils.SET_REMOVE:
+		set := store.set(msg.Key)
+		for _, tag := range op.Observed {
+			delete(set.Elements[op.Element], tag)
+		}
+		if len(set.Elements[op.Element]) == 0 {
+			delete(set.Elements, op.Element)
+		}
+		fmt.Printf("SET REMOVE set %s element %s, %d tags removed\n", msg.Key, op.Element, len(op.Observed))
+
+	case utils.REGISTER_SET:
+		// Tra due scritture concorrenti prevale quella con timestamp ibrido maggiore.
+		// Una scrittura che segue causalmente la precedente ha sempre timestamp maggiore, quindi la sostituisce.
+		register := utils.VersionedValue{Value: msg.Value, Version: msg.Version()}
+		if stored, exist := store.Registers[msg.Key]; exist {
+			register = LastWriterWins{}.Resolve(stored, register)
+		}
+		store.Registers[msg.Key] = register
+		fmt.Printf("REGISTER SET register %s value %s, current value %s\n", msg.Key, msg.Value, register.Value)
+
+	case utils.MAP_PUT, utils.MAP_REMOVE:
+		mvMap := store.mvMap(msg.Key)
+		version := msg.Version()
+		// Le scritture che precedono causalmente l'operazione sono sostituite, quelle concorrenti sono mantenute
+		var values []utils.VersionedValue
+		for _, value := range mvMap.Fields[op.Field] {
+			if !value.Version.LessOrEqual(version) {
+				values = append(values, value)
+			}
+		}
+		if msg.Op == utils.MAP_PUT {
+			values = append(values, utils.VersionedValue{Value: msg.Value, Version: version})
+		}
+		if len(values) == 0 {
+			delete(mvMap.Fields, op.Field)
+		} else {
+			mvMap.Fields[op.Field] = values
+		}
+		fmt.Printf("%s map %s field %s value %s, %d concurrent values\n", msg.Op, msg.Key, op.Field, msg.Value, len(values))
+	}
+}
+
+// snapshot restituisce i valori correnti di tutti i tipi di dato CRDT della replica, per una snapshot di backup
+func (store *CRDTStore) snapshot() *utils.BackupCRDTs {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	snapshot := &utils.BackupCRDTs{
+		Counters:  make(map[string]int),
+		Sets:      make(map[string][]string),
+		Registers: make(map[string][]byte),
+		Maps:      make(map[string]map[string][]string),
+	}
+	for key, counter := range store.Counters {
+		snapshot.Counters[key] = counter.Value()
+	}
+	for key, set := range store.Sets {
+		snapshot.Sets[key] = set.Members()
+	}
+	for key, register := range store.Registers {
+		snapshot.Registers[key] = register.Value
+	}
+	for key, mvMap := range store.Maps {
+		snapshot.Maps[key] = mvMap.Values()
+	}
+	return snapshot
+}
+
+// counterValue restituisce il valore corrente del contatore indicato (0 se non esiste)
+func (store *CRDTStore) counterValue(key string) int {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if counter, exist := store.Counters[key]; exist {
+		return counter.Value()
+	}
+	return 0
+}
+
+// setMembers restituisce gli elementi correnti dell'insieme indicato
+func (store *CRDTStore) setMembers(key string) []string {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if set, exist := store.Sets[key]; exist {
+		return set.Members()
+	}
+	return []string{}
+}
+
+// observedTags restituisce i tag di un elemento dell'insieme osservati dalla replica, che una rimozione deve eliminare
+func (store *CRDTStore) observedTags(key string, element string) []string {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	var tags []string
+	if set, exist := store.Sets[key]; exist {
+		for tag := range set.Elements[element] {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// registerValue restituisce il valore corrente del registro indicato, con la sua versione
+func (store *CRDTStore) registerValue(key string) utils.Result {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if register, exist := store.Registers[key]; exist {
+		return utils.Result{Key: key, Value: register.Value, Version: register.Version}
+	}
+	return utils.Result{Key: key, Value: []byte("NOT FOUND")}
+}
+
+// mapValues restituisce i campi correnti della mappa indicata
+func (store *CRDTStore) mapValues(key string) map[string][]string {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if mvMap, exist := store.Maps[key]; exist {
+		return mvMap.Values()
+	}
+	return map[string][]string{}
+}
+
+// counter restituisce il contatore associato alla chiave, creandolo se non esiste.
+// Deve essere invocata mantenendo il lock sullo store.
+func (store *CRDTStore) counter(key string) *PNCounter {
+	counter, exist := store.Counters[key]
+	if !exist {
+		counter = &PNCounter{P: make(map[int]int), N: make(map[int]int)}
+		store.Counters[key] = counter
+	}
+	return counter
+}
+
+// set restituisce l'insieme associato alla chiave, creandolo se non esiste.
+// Deve essere invocata mantenendo il lock sullo store.
+func (store *CRDTStore) set(key string) *ORSet {
+	set, exist := store.Sets[key]
+	if !exist {
+		set = &ORSet{Elements: make(map[string]map[string]bool)}
+		store.Sets[key] = set
+	}
+	return set
+}
+
+// mvMap restituisce la mappa associata alla chiave, creandola se non esiste.
+// Deve essere invocata mantenendo il lock sullo store.
+func (store *CRDTStore) mvMap(key string) *MVMap {
+	mvMap, exist := store.Maps[key]
+	if !exist {
+		mvMap = &MVMap{Fields: make(map[string][]utils.VersionedValue)}
+		store.Maps[key] = mvMap
+	}
+	return mvMap
+}
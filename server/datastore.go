@@ -1,9 +1,19 @@
 package main
 
 import (
+	"bytes"
 	"dbService/utils"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
 	"sync"
+	"time"
+)
+
+const (
+	expirerInterval  = time.Second // Intervallo con cui l'expirer controlla le entry scadute
+	defaultScanLimit = 100         // Numero massimo di entry restituite da una scansione, se non indicato dal client
 )
 
 // DataStore definisce il servizio messo a disposizione del client.
@@ -17,93 +27,728 @@ type DataStore interface {
 
 	// Delete rimuove la entry corrispondente a una data chiave
 	Delete(args utils.Args, result *utils.Result) error
+
+	// SetTTL aggiorna il TTL della versione corrente di una chiave, senza riscriverne il valore
+	SetTTL(args utils.Args, result *utils.CondResult) error
+
+	// Txn esegue atomicamente una transazione composta da condizioni, letture e scritture su più chiavi
+	Txn(args utils.TxnArgs, result *utils.TxnResult) error
+
+	// CompareAndSwap aggiorna il valore di una chiave solo se il valore corrente coincide con quello atteso
+	CompareAndSwap(args utils.CASArgs, result *utils.CondResult) error
+
+	// PutIfAbsent inserisce una coppia key-value solo se la chiave non è già presente
+	PutIfAbsent(args utils.Args, result *utils.CondResult) error
+
+	// DeleteIfValue rimuove la entry corrispondente a una chiave solo se il valore corrente coincide con quello indicato
+	DeleteIfValue(args utils.Args, result *utils.CondResult) error
+
+	// GetAt recupera il valore di una chiave in corrispondenza di una data versione o timestamp
+	GetAt(args utils.GetAtArgs, result *utils.Result) error
+
+	// Scan restituisce, una pagina alla volta, le entry le cui chiavi sono comprese in un intervallo
+	Scan(args utils.ScanArgs, result *utils.ScanResult) error
+
+	// ScanPrefix restituisce, una pagina alla volta, le entry le cui chiavi hanno un dato prefisso
+	ScanPrefix(args utils.ScanArgs, result *utils.ScanResult) error
+
+	// MultiGet recupera con un'unica richiesta i valori corrispondenti a più chiavi
+	MultiGet(args utils.BatchArgs, result *utils.BatchResult) error
+
+	// MultiPut inserisce o aggiorna con un'unica richiesta più coppie key-value
+	MultiPut(args utils.BatchArgs, result *utils.BatchResult) error
+
+	// MultiDelete rimuove con un'unica richiesta le entry corrispondenti a più chiavi
+	MultiDelete(args utils.BatchArgs, result *utils.BatchResult) error
+
+	// Increment incrementa (o decrementa) un PN-counter
+	Increment(args utils.CounterArgs, result *utils.CounterResult) error
+
+	// GetCounter restituisce il valore di un PN-counter
+	GetCounter(args utils.Args, result *utils.CounterResult) error
+
+	// SetAdd aggiunge un elemento a un OR-set
+	SetAdd(args utils.SetArgs, result *utils.SetResult) error
+
+	// SetRemove rimuove un elemento da un OR-set
+	SetRemove(args utils.SetArgs, result *utils.SetResult) error
+
+	// SetMembers restituisce gli elementi di un OR-set
+	SetMembers(args utils.Args, result *utils.SetResult) error
+
+	// RegisterSet scrive il valore di un registro last-writer-wins
+	RegisterSet(args utils.Args, result *utils.Result) error
+
+	// RegisterGet restituisce il valore di un registro last-writer-wins
+	RegisterGet(args utils.Args, result *utils.Result) error
+
+	// MapPut scrive un campo di una mappa multi-valore
+	MapPut(args utils.MapArgs, result *utils.MapResult) error
+
+	// MapRemove rimuove un campo di una mappa multi-valore
+	MapRemove(args utils.MapArgs, result *utils.MapResult) error
+
+	// MapGet restituisce i campi di una mappa multi-valore
+	MapGet(args utils.Args, result *utils.MapResult) error
+
+	// GetPath recupera il valore di un percorso all'interno di un documento JSON
+	GetPath(args utils.PathArgs, result *utils.Result) error
+
+	// SetPath scrive il valore di un percorso all'interno di un documento JSON
+	SetPath(args utils.PathArgs, result *utils.Result) error
+
+	// DeletePath rimuove un percorso all'interno di un documento JSON
+	DeletePath(args utils.PathArgs, result *utils.Result) error
+
+	// CreateIndex crea un indice secondario su un campo dei documenti JSON di un namespace
+	CreateIndex(args utils.IndexArgs, result *utils.IndexInfo) error
+
+	// ListIndexes restituisce gli indici secondari di un namespace
+	ListIndexes(args utils.IndexArgs, result *utils.IndexList) error
+
+	// DropIndex rimuove un indice secondario
+	DropIndex(args utils.IndexArgs, result *utils.IndexInfo) error
+
+	// QueryIndex restituisce le chiavi il cui campo indicizzato ha un dato valore
+	QueryIndex(args utils.QueryArgs, result *utils.QueryResult) error
+
+	// Watch attende le modifiche applicate dalla replica a una chiave, o alle chiavi con un dato prefisso
+	Watch(args utils.WatchArgs, result *utils.WatchResult) error
+
+	// CreateNamespace crea un namespace, con un proprio spazio delle chiavi e una propria configurazione
+	CreateNamespace(args utils.NamespaceArgs, result *utils.NamespaceInfo) error
+
+	// ListNamespaces restituisce i namespace presenti sulla replica
+	ListNamespaces(args utils.NamespaceArgs, result *utils.NamespaceList) error
+
+	// DropNamespace rimuove un namespace con tutte le sue chiavi
+	DropNamespace(args utils.NamespaceArgs, result *utils.NamespaceInfo) error
+
+	// Status restituisce lo stato interno della replica, per l'amministrazione del cluster
+	Status(args utils.StatusArgs, result *utils.ReplicaStatus) error
+
+	// Dump restituisce tutte le entry di un namespace della replica, per l'amministrazione del cluster
+	Dump(args utils.DumpArgs, result *utils.DumpResult) error
+
+	// Backup restituisce una snapshot consistente di tutti i namespace della replica, presa senza interrompere le scritture
+	Backup(args utils.BackupArgs, result *utils.BackupResult) error
 }
 
 type DbStore struct {
-	Store map[string]string // Store di coppie chiave-valore
-	mutex sync.Mutex
+	Store    map[string]utils.VersionedValue   // Store di coppie chiave-valore, con la versione corrente di ogni chiave
+	History  map[string][]utils.VersionedValue // Ultime MaxVersions versioni di ogni chiave (inclusa la corrente), dalla più vecchia alla più recente
+	Siblings map[string][]utils.VersionedValue // Versioni concorrenti delle chiavi scritte in concorrenza e non ancora riconciliate (solo consistenza causale)
+	// Versione dell'ultima DELETE delle chiavi cancellate, o la versione scaduta delle chiavi rimosse per scadenza,
+	// per le chiavi i cui conflitti sono risolti automaticamente (solo consistenza causale).
+	// Permette di confrontare la rimozione con una PUT concorrente ricevuta successivamente.
+	Tombstones map[string]utils.VersionedValue
+	Paths      map[string]map[string]utils.Version // Versione dell'ultima operazione su ogni percorso dei documenti (solo consistenza causale)
+	TTLs       map[string]utils.Version            // Versione dell'ultimo aggiornamento del TTL di ogni chiave (solo consistenza causale)
+	Indexes    map[string]*SecondaryIndex          // Indici secondari sui campi dei documenti, aggiornati a ogni modifica del valore corrente di una chiave
+	keys       []string                            // Indice ordinato delle chiavi presenti nello store, utilizzato dalle scansioni
+	size       int64                               // Dimensione in byte di chiavi e valori correnti, utilizzata dalle quote dei namespace
+	Watches    WatchHub                            // Ultime modifiche applicate allo store, osservabili dai client tramite Watch
+	mutex      sync.Mutex
+}
+
+// newDbStore crea uno store vuoto
+func newDbStore() *DbStore {
+	return &DbStore{
+		Store:      make(map[string]utils.VersionedValue),
+		History:    make(map[string][]utils.VersionedValue),
+		Siblings:   make(map[string][]utils.VersionedValue),
+		Tombstones: make(map[string]utils.VersionedValue),
+		Paths:      make(map[string]map[string]utils.Version),
+		TTLs:       make(map[string]utils.Version),
+		Indexes:    make(map[string]*SecondaryIndex),
+	}
 }
 
-// putEntry ritorna il valore associato alla chiave indicata, oppure una stringa che indica l'assenza della chiave nello store
-func (db *DbStore) getEntry(key string) string {
+// getEntry ritorna il valore associato alla chiave indicata e la sua versione, oppure una stringa che indica l'assenza della chiave nello store
+func (db *DbStore) getEntry(key string) utils.Result {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
-	value, exist := db.Store[key]
+	entry, exist := db.Store[key]
 	if !exist {
 		if ConsistencyType == "SEQUENTIAL" {
 			fmt.Printf("GET key %s value NOT FOUND\n", key)
 		}
-		return "NOT FOUND"
+		return utils.Result{Key: key, Value: []byte("NOT FOUND")}
 
 	} else {
-		fmt.Printf("GET key %s value %s\n", key, value)
-		return value
+		fmt.Printf("GET key %s value %s version %s\n", key, entry.Value, entry.Version)
+		for _, sibling := range db.Siblings[key] {
+			fmt.Printf("  sibling value %s version %s\n", sibling.Value, sibling.Version)
+		}
+		return db.resultFor(key, entry)
+	}
+}
+
+// getEntries ritorna i valori associati alle chiavi indicate, osservando lo store in un unico istante
+func (db *DbStore) getEntries(keys []utils.BatchEntry) []utils.Result {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	results := make([]utils.Result, len(keys))
+	for i, key := range keys {
+		entry, exist := db.Store[key.Key]
+		if !exist {
+			fmt.Printf("MULTIGET key %s value NOT FOUND\n", key.Key)
+			results[i] = utils.Result{Key: key.Key, Value: []byte("NOT FOUND")}
+		} else {
+			fmt.Printf("MULTIGET key %s value %s version %s\n", key.Key, entry.Value, entry.Version)
+			results[i] = db.resultFor(key.Key, entry)
+		}
+	}
+	return results
+}
+
+// resultFor costruisce il risultato di una lettura della entry indicata.
+// Con consistenza causale restituisce anche le versioni concorrenti e il contesto che le include tutte, con cui il client può riconciliarle.
+// Deve essere invocata mantenendo il lock sullo store.
+func (db *DbStore) resultFor(key string, entry utils.VersionedValue) utils.Result {
+	result := utils.Result{Key: key, Value: entry.Value, Version: entry.Version, ExpiresAt: entry.ExpiresAt}
+	if entry.Version.IsVector() {
+		siblings := db.Siblings[key]
+		clocks := [][]int{entry.Version.VectorClock}
+		for _, sibling := range siblings {
+			clocks = append(clocks, sibling.Version.VectorClock)
+		}
+		result.Siblings = siblings
+		result.Context = utils.MergeClocks(clocks...)
+	}
+	return result
+}
+
+// scanEntries restituisce una pagina delle entry le cui chiavi rientrano nell'intervallo o nel prefisso richiesto, in ordine lessicografico.
+// La scansione riprende dalla prima chiave successiva al cursore indicato.
+func (db *DbStore) scanEntries(args utils.ScanArgs) utils.ScanResult {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultScanLimit
+	}
+
+	// Individua la prima chiave da cui partire tramite ricerca binaria sull'indice ordinato
+	start := args.Start
+	if args.Prefix != "" {
+		start = args.Prefix
+	}
+	i := sort.SearchStrings(db.keys, start)
+	if args.Cursor != "" {
+		i = sort.Search(len(db.keys), func(j int) bool { return db.keys[j] > args.Cursor })
+	}
+
+	result := utils.ScanResult{}
+	for ; i < len(db.keys); i++ {
+		key := db.keys[i]
+		if args.Prefix != "" && !strings.HasPrefix(key, args.Prefix) {
+			break
+		}
+		if args.Prefix == "" && args.End != "" && key >= args.End {
+			break
+		}
+		if len(result.Entries) == limit {
+			// Esistono ulteriori chiavi nell'intervallo, la scansione riprenderà dall'ultima chiave restituita
+			result.Cursor = result.Entries[limit-1].Key
+			break
+		}
+		result.Entries = append(result.Entries, db.resultFor(key, db.Store[key]))
+	}
+	fmt.Printf("SCAN start %q end %q prefix %q cursor %q, %d entries\n", args.Start, args.End, args.Prefix, args.Cursor, len(result.Entries))
+	return result
+}
+
+// getEntryAt ritorna il valore della chiave indicata nella versione più recente che precede o coincide con quella richiesta.
+// Se la chiave non esisteva in quella versione, oppure la versione non è più presente nella storia mantenuta, ritorna NOT FOUND.
+func (db *DbStore) getEntryAt(args utils.GetAtArgs) utils.Result {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	// Se è indicato un timestamp, considera tutte le versioni con timestamp di Lamport non superiore
+	at := args.Version
+	if args.Timestamp > 0 {
+		at = utils.Version{Clock: args.Timestamp, ServerID: math.MaxInt}
+	}
+
+	history := db.History[args.Key]
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Version.LessOrEqual(at) {
+			if history[i].Deleted {
+				break
+			}
+			fmt.Printf("GET key %s at %s value %s version %s\n", args.Key, at, history[i].Value, history[i].Version)
+			return utils.Result{Key: args.Key, Value: history[i].Value, Version: history[i].Version}
+		}
 	}
+	fmt.Printf("GET key %s at %s value NOT FOUND\n", args.Key, at)
+	return utils.Result{Key: args.Key, Value: []byte("NOT FOUND")}
 }
 
-// putEntry inserisce una nuova entry nello store key-value.
+// putEntry inserisce una nuova entry nello store key-value, con la versione della scrittura che l'ha prodotta.
 // Se esiste già una entry nello store associata alla chiave data, il valore corrispondente viene aggiornato.
-func (db *DbStore) putEntry(key string, value string) {
+// expiresAt è l'istante di scadenza della entry in millisecondi (0 se la entry non scade).
+func (db *DbStore) putEntry(key string, value []byte, version utils.Version, expiresAt int64) {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
-	db.Store[key] = value
-	fmt.Printf("PUT key %s value %s\n", key, value)
+	db.setValue(key, utils.VersionedValue{Value: value, Version: version, ExpiresAt: expiresAt})
+	fmt.Printf("PUT key %s value %s version %s\n", key, value, version)
 }
 
 // deleteEntry rimuove la entry associata a una data chiave nello store key-value.
 // Se la chiave non esiste la delete non esegue alcuna operazione
-func (db *DbStore) deleteEntry(key string) {
+func (db *DbStore) deleteEntry(key string, version utils.Version) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.removeValue(key, version)
+	fmt.Printf("DELETE key %s version %s\n", key, version)
+}
+
+// applyBatch applica con consistenza sequenziale una MultiPut o una MultiDelete.
+// Tutte le scritture sono applicate atomicamente e assumono la versione del messaggio che le trasporta.
+func (db *DbStore) applyBatch(op utils.Operation, entries []utils.BatchEntry, version utils.Version) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	for _, entry := range entries {
+		if op == utils.MULTI_PUT {
+			db.setValue(entry.Key, utils.VersionedValue{Value: entry.Value, Version: version, ExpiresAt: entry.ExpiresAt})
+			fmt.Printf("MULTIPUT key %s value %s version %s\n", entry.Key, entry.Value, version)
+		} else {
+			db.removeValue(entry.Key, version)
+			fmt.Printf("MULTIDELETE key %s version %s\n", entry.Key, version)
+		}
+	}
+}
+
+// expireEntry rimuove la entry associata a una chiave solo se la sua versione corrente coincide con quella scaduta.
+// Se nel frattempo la chiave è stata riscritta, la nuova versione non viene rimossa.
+// version è la versione del messaggio di EXPIRE, con cui è registrata la rimozione. Restituisce true se la entry è stata rimossa.
+func (db *DbStore) expireEntry(key string, expired utils.Version, version utils.Version) bool {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
+	entry, exist := db.Store[key]
+	if !exist || entry.Version.Clock != expired.Clock || entry.Version.ServerID != expired.ServerID {
+		fmt.Printf("EXPIRE key %s version %s skipped, the key was updated\n", key, expired)
+		return false
+	}
+	db.removeValue(key, version)
+	fmt.Printf("EXPIRE key %s version %s\n", key, expired)
+	return true
+}
+
+// expireVersion rimuove con consistenza causale la versione scaduta di una chiave, mantenendo le versioni successive o concorrenti.
+// Se la chiave è risolta automaticamente (resolved) la versione scaduta rimane come tombstone:
+// una scrittura concorrente ricevuta successivamente è così risolta contro di essa, come sulle repliche che l'hanno ricevuta prima della scadenza.
+// version è la versione del messaggio di EXPIRE. Se la versione è rimossa viene invocata onApply, mantenendo il lock sullo store.
+func (db *DbStore) expireVersion(key string, expired utils.Version, version utils.Version, resolved bool, onApply func()) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	current := db.Siblings[key]
+	if stored, exist := db.Store[key]; exist && current == nil {
+		current = []utils.VersionedValue{stored}
+	}
+
+	var siblings []utils.VersionedValue
+	var removed utils.VersionedValue
+	found := false
+	for _, sibling := range current {
+		if sibling.Version.Equal(expired) {
+			removed = sibling
+			found = true
+		} else {
+			siblings = append(siblings, sibling)
+		}
+	}
+	if !found {
+		fmt.Printf("EXPIRE key %s version %s skipped, the key was updated\n", key, expired)
+		return
+	}
+	onApply()
+
+	// Le versioni rimaste sono già ordinate per ID della replica, e il valore corrente resta quella con ID maggiore
+	switch len(siblings) {
+	case 0:
+		db.dropEntry(key, version)
+		db.appendHistory(key, utils.VersionedValue{Version: version, Deleted: true})
+		delete(db.Siblings, key)
+		if resolved {
+			db.Tombstones[key] = removed
+		}
+	case 1:
+		db.storeEntry(key, siblings[0], version)
+		delete(db.Siblings, key)
+	default:
+		db.storeEntry(key, siblings[len(siblings)-1], version)
+		db.Siblings[key] = siblings
+	}
+	fmt.Printf("EXPIRE key %s version %s, %d concurrent versions\n", key, expired, len(siblings))
+}
+
+// setExpiry imposta l'istante di scadenza della versione corrente di una chiave, senza modificarne il valore e la versione (0 se la entry non deve più scadere).
+// Restituisce false se la chiave non esiste.
+func (db *DbStore) setExpiry(key string, expiresAt int64) bool {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	entry, exist := db.Store[key]
+	if !exist {
+		fmt.Printf("SETTTL key %s skipped, the key does not exist\n", key)
+		return false
+	}
+	entry.ExpiresAt = expiresAt
+	db.Store[key] = entry
+	fmt.Printf("SETTTL key %s version %s expires at %d\n", key, entry.Version, expiresAt)
+	return true
+}
+
+// mergeExpiry imposta con consistenza causale l'istante di scadenza delle versioni di una chiave che precedono causalmente l'aggiornamento del TTL,
+// senza modificarne il valore e la versione. Le versioni concorrenti mantengono la propria scadenza.
+// Come per le operazioni sullo stesso percorso di un documento, tra due aggiornamenti concorrenti del TTL prevale quello con timestamp ibrido maggiore.
+// Se il TTL di almeno una versione è aggiornato viene invocata onApply, mantenendo il lock sullo store.
+func (db *DbStore) mergeExpiry(key string, expiresAt int64, version utils.Version, onApply func()) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if last, exist := db.TTLs[key]; exist && !last.LessOrEqual(version) && !supersedes(last, version) {
+		fmt.Printf("SETTTL key %s skipped, superseded by version %s\n", key, last)
+		return
+	}
+
+	current := db.Siblings[key]
+	if stored, exist := db.Store[key]; exist && current == nil {
+		current = []utils.VersionedValue{stored}
+	}
+	changed := false
+	for i, sibling := range current {
+		if sibling.Deleted || !sibling.Version.LessOrEqual(version) {
+			continue
+		}
+		current[i].ExpiresAt = expiresAt
+		changed = true
+	}
+	if !changed {
+		fmt.Printf("SETTTL key %s skipped, no version of the key precedes %s\n", key, version)
+		return
+	}
+
+	onApply()
+	db.TTLs[key] = version
+	// Il valore corrente è la versione originata dalla replica con ID maggiore, e valore e indici non cambiano
+	db.Store[key] = current[len(current)-1]
+	fmt.Printf("SETTTL key %s version %s expires at %d\n", key, version, expiresAt)
+}
+
+// applySibling applica una PUT o una DELETE con consistenza causale.
+// context è il contesto causale della scrittura: le versioni correnti che esso include sono sostituite dalla nuova,
+// mentre quelle concorrenti sono mantenute come versioni sorelle (siblings) finché una scrittura successiva non le riconcilia.
+// Una DELETE rimuove solo le versioni incluse nel contesto, quelle concorrenti restano nello store.
+// Se la scrittura è applicata viene invocata onApply, mantenendo il lock sullo store.
+func (db *DbStore) applySibling(key string, entry utils.VersionedValue, context []int, onApply func()) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	siblings, applied := db.mergeSibling(key, entry, context)
+	if !applied {
+		fmt.Printf("Write of key %s version %s skipped, superseded by a stored version\n", key, entry.Version)
+		return
+	}
+	onApply()
+	if entry.Deleted {
+		fmt.Printf("DELETE key %s version %s, %d concurrent versions\n", key, entry.Version, siblings)
+	} else {
+		fmt.Printf("PUT key %s value %s version %s, %d concurrent versions\n", key, entry.Value, entry.Version, siblings)
+	}
+}
+
+// mergeSibling confronta la nuova versione con le versioni correnti della chiave e ritorna il numero di versioni che restano nello store,
+// e se la nuova versione è stata applicata. Deve essere invocata mantenendo il lock sullo store.
+func (db *DbStore) mergeSibling(key string, entry utils.VersionedValue, context []int) (int, bool) {
+	current := db.Siblings[key]
+	if stored, exist := db.Store[key]; exist && current == nil {
+		current = []utils.VersionedValue{stored}
+	}
+
+	contextVersion := utils.Version{VectorClock: context}
+	var siblings []utils.VersionedValue
+	for _, sibling := range current {
+		// La nuova scrittura è già superata da una versione presente (ad esempio due scritture locali applicate in ordine inverso)
+		if entry.Version.LessOrEqual(sibling.Version) {
+			return len(current), false
+		}
+		// Le versioni incluse nel contesto della scrittura sono sostituite, quelle concorrenti sono mantenute
+		if !sibling.Version.LessOrEqual(contextVersion) {
+			siblings = append(siblings, sibling)
+		}
+	}
+	if !entry.Deleted {
+		siblings = append(siblings, entry)
+	}
+	db.appendHistory(key, entry)
+
+	switch len(siblings) {
+	case 0:
+		db.dropEntry(key, entry.Version)
+		delete(db.Siblings, key)
+	case 1:
+		db.storeEntry(key, siblings[0], entry.Version)
+		delete(db.Siblings, key)
+	default:
+		// Le versioni concorrenti sono ordinate per ID della replica che le ha originate, così che tutte le repliche le restituiscano nello stesso ordine.
+		// Come valore corrente viene scelta la versione originata dalla replica con ID maggiore.
+		sort.Slice(siblings, func(i, j int) bool {
+			return siblings[i].Version.ServerID < siblings[j].Version.ServerID
+		})
+		db.storeEntry(key, siblings[len(siblings)-1], entry.Version)
+		db.Siblings[key] = siblings
+	}
+	return len(siblings), true
+}
+
+// resolveEntry applica una scrittura con consistenza causale a una chiave i cui conflitti sono risolti automaticamente.
+// Se la scrittura è concorrente con la versione corrente (o con l'ultima DELETE), il resolver sceglie quale delle due mantenere.
+// Se la scrittura è applicata viene invocata onApply, mantenendo il lock sullo store.
+func (db *DbStore) resolveEntry(key string, entry utils.VersionedValue, context []int, resolver ConflictResolver, onApply func()) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	stored, exist := db.Store[key]
+	if !exist {
+		stored, exist = db.Tombstones[key]
+	}
+	if exist {
+		// La nuova scrittura è già superata dalla versione presente
+		if entry.Version.LessOrEqual(stored.Version) {
+			return
+		}
+		// La scrittura è concorrente con la versione presente, il resolver sceglie la versione da mantenere
+		if !stored.Version.LessOrEqual(utils.Version{VectorClock: context}) {
+			winner := resolver.Resolve(stored, entry)
+			fmt.Printf("CONFLICT key %s between version %s and version %s, version %s wins\n", key, stored.Version, entry.Version, winner.Version)
+			// Due versioni concorrenti sono originate necessariamente da repliche diverse
+			if winner.Version.ServerID == stored.Version.ServerID {
+				return
+			}
+		}
+	}
+
+	onApply()
+	delete(db.Siblings, key)
+	db.appendHistory(key, entry)
+	if entry.Deleted {
+		db.dropEntry(key, entry.Version)
+		db.Tombstones[key] = entry
+		fmt.Printf("DELETE key %s version %s\n", key, entry.Version)
+	} else {
+		db.storeEntry(key, entry, entry.Version)
+		delete(db.Tombstones, key)
+		fmt.Printf("PUT key %s value %s version %s\n", key, entry.Value, entry.Version)
+	}
+}
+
+// setValue aggiorna il valore corrente di una chiave e ne registra la versione nella storia.
+// Deve essere invocata mantenendo il lock sullo store.
+func (db *DbStore) setValue(key string, entry utils.VersionedValue) {
+	db.storeEntry(key, entry, entry.Version)
+	db.appendHistory(key, entry)
+}
+
+// removeValue rimuove una chiave dallo store, registrando nella storia la sua cancellazione.
+// Deve essere invocata mantenendo il lock sullo store.
+func (db *DbStore) removeValue(key string, version utils.Version) {
+	if _, exist := db.Store[key]; !exist {
+		return
+	}
+	db.dropEntry(key, version)
+	db.appendHistory(key, utils.VersionedValue{Version: version, Deleted: true})
+}
+
+// storeEntry imposta la versione corrente di una chiave, aggiornando l'indice ordinato delle chiavi e gli indici secondari, e notificando la modifica alle Watch.
+// cause è la versione della scrittura che ha prodotto la modifica, che può differire da quella del valore corrente in presenza di versioni concorrenti.
+// Deve essere invocata mantenendo il lock sullo store.
+func (db *DbStore) storeEntry(key string, entry utils.VersionedValue, cause utils.Version) {
+	oldValue := []byte("NOT FOUND")
+	if stored, exist := db.Store[key]; exist {
+		oldValue = stored.Value
+		db.size -= int64(len(key) + len(stored.Value))
+	} else {
+		i := sort.SearchStrings(db.keys, key)
+		db.keys = append(db.keys, "")
+		copy(db.keys[i+1:], db.keys[i:])
+		db.keys[i] = key
+	}
+	db.Store[key] = entry
+	db.size += int64(len(key) + len(entry.Value))
+	for _, index := range db.Indexes {
+		index.update(key, entry.Value)
+	}
+	db.Watches.publish(utils.WatchEvent{Op: utils.PUT, Key: key, OldValue: oldValue, NewValue: entry.Value, Version: cause})
+}
+
+// dropEntry rimuove una chiave dallo store, dall'indice ordinato delle chiavi e dagli indici secondari, notificando la modifica alle Watch.
+// Deve essere invocata mantenendo il lock sullo store.
+func (db *DbStore) dropEntry(key string, version utils.Version) {
+	stored, exist := db.Store[key]
+	if !exist {
+		return
+	}
 	delete(db.Store, key)
-	fmt.Printf("DELETE key %s\n", key)
+	db.size -= int64(len(key) + len(stored.Value))
+	i := sort.SearchStrings(db.keys, key)
+	db.keys = append(db.keys[:i], db.keys[i+1:]...)
+	for _, index := range db.Indexes {
+		index.remove(key)
+	}
+	db.Watches.publish(utils.WatchEvent{Op: utils.DELETE, Key: key, OldValue: stored.Value, NewValue: []byte("NOT FOUND"), Version: version})
 }
 
-// printDbStore stampa il contenuto del DbStore
-func (db *DbStore) printDbStore() {
+// usage restituisce il numero di chiavi presenti nello store e la dimensione in byte di chiavi e valori
+func (db *DbStore) usage() (int, int64) {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
+	return len(db.Store), db.size
+}
 
-	// Verifica se lo store è vuoto
-	if len(db.Store) == 0 {
-		fmt.Println("The store is empty.")
+// sizeDelta restituisce la variazione della dimensione dello store prodotta dalla scrittura del valore indicato sulla chiave
+func (db *DbStore) sizeDelta(key string, value []byte) int64 {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	delta := int64(len(key) + len(value))
+	if stored, exist := db.Store[key]; exist {
+		delta -= int64(len(key) + len(stored.Value))
+	}
+	return delta
+}
+
+// appendHistory aggiunge una versione alla storia della chiave, mantenendo al più MaxVersions versioni
+func (db *DbStore) appendHistory(key string, entry utils.VersionedValue) {
+	if MaxVersions <= 0 {
 		return
 	}
+	history := append(db.History[key], entry)
+	if len(history) > MaxVersions {
+		history = history[len(history)-MaxVersions:]
+	}
+	db.History[key] = history
+}
 
-	// Definisce la larghezza delle colonne
-	keyHeader := "Key"
-	valueHeader := "Value"
-	maxKeyLength := len(keyHeader)
-	maxValueLength := len(valueHeader)
+// runExpirer controlla periodicamente le entry scadute dello store.
+// La rimozione di una entry scaduta non avviene localmente, ma è richiesta tramite expire così da essere propagata
+// secondo il protocollo di consistenza attivo: in questo modo le repliche non sono mai in disaccordo sulla presenza di una chiave.
+// Solo la replica che ha originato la scrittura ne richiede la scadenza, così che ogni versione sia fatta scadere una sola volta.
+// Il controllo termina alla chiusura di stop (nil per non terminare mai).
+func (db *DbStore) runExpirer(serverID int, stop <-chan struct{}, expire func(key string, version utils.Version)) {
+	// Versioni di cui è già stata richiesta la scadenza, ancora in attesa di essere applicata
+	requested := make(map[string]bool)
 
-	// Trova la lunghezza massima di key e value
-	for key, value := range db.Store {
-		if len(key) > maxKeyLength {
-			maxKeyLength = len(key)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(expirerInterval):
 		}
-		if len(value) > maxValueLength {
-			maxValueLength = len(value)
+		now := time.Now().UnixMilli()
+
+		db.mutex.Lock()
+		expired := make(map[string][]utils.Version)
+		stillRequested := make(map[string]bool)
+		for key, entry := range db.Store {
+			versions := db.Siblings[key]
+			if versions == nil {
+				versions = []utils.VersionedValue{entry}
+			}
+			for _, sibling := range versions {
+				if sibling.ExpiresAt == 0 || sibling.ExpiresAt > now || sibling.Version.ServerID != serverID {
+					continue
+				}
+				id := key + "@" + sibling.Version.String()
+				if requested[id] {
+					stillRequested[id] = true
+				} else {
+					expired[key] = append(expired[key], sibling.Version)
+				}
+			}
+		}
+		db.mutex.Unlock()
+		requested = stillRequested
+
+		for key, versions := range expired {
+			for _, version := range versions {
+				requested[key+"@"+version.String()] = true
+				expire(key, version)
+			}
 		}
 	}
+}
 
-	// Stampa l'intestazione
-	fmt.Printf("+-%s-+-%s-+\n", generateLine(maxKeyLength), generateLine(maxValueLength))
-	fmt.Printf("| %-*s | %-*s |\n", maxKeyLength, keyHeader, maxValueLength, valueHeader)
-	fmt.Printf("+-%s-+-%s-+\n", generateLine(maxKeyLength), generateLine(maxValueLength))
+// applyTxn applica atomicamente una transazione multi-chiave allo store.
+// Le condizioni sono valutate sullo stato corrente dello store, quindi vengono eseguite le operazioni del ramo corrispondente.
+// Le GET all'interno della transazione osservano le scritture che le precedono nella stessa transazione.
+// Tutte le scritture della transazione assumono la versione del messaggio che la trasporta.
+func (db *DbStore) applyTxn(txn utils.TxnArgs, version utils.Version) utils.TxnResult {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
 
-	// Stampa ogni entry dello store
-	for key, value := range db.Store {
-		fmt.Printf("| %-*s | %-*s |\n", maxKeyLength, key, maxValueLength, value)
-		fmt.Printf("+-%s-+-%s-+\n", generateLine(maxKeyLength), generateLine(maxValueLength))
+	// Valuta tutte le condizioni della transazione
+	succeeded := true
+	for _, cmp := range txn.Compare {
+		if !db.evaluateCompare(cmp) {
+			succeeded = false
+			break
+		}
+	}
+
+	ops := txn.Success
+	if !succeeded {
+		ops = txn.Failure
+	}
+	fmt.Printf("TXN compare %t, executing %d operations, version %s\n", succeeded, len(ops), version)
+
+	result := utils.TxnResult{Succeeded: succeeded}
+	for _, op := range ops {
+		switch op.Op {
+		case utils.GET:
+			entry, exist := db.Store[op.Key]
+			if !exist {
+				fmt.Printf("  TXN GET key %s value NOT FOUND\n", op.Key)
+				result.Responses = append(result.Responses, utils.Result{Key: op.Key, Value: []byte("NOT FOUND")})
+			} else {
+				fmt.Printf("  TXN GET key %s value %s\n", op.Key, entry.Value)
+				result.Responses = append(result.Responses, utils.Result{Key: op.Key, Value: entry.Value, Version: entry.Version})
+			}
+		case utils.PUT:
+			db.setValue(op.Key, utils.VersionedValue{Value: op.Value, Version: version, ExpiresAt: op.ExpiresAt})
+			fmt.Printf("  TXN PUT key %s value %s\n", op.Key, op.Value)
+		case utils.DELETE:
+			db.removeValue(op.Key, version)
+			fmt.Printf("  TXN DELETE key %s\n", op.Key)
+		}
 	}
+	return result
 }
 
-// generateLine genera una riga di trattini della lunghezza desiderata
-func generateLine(length int) string {
-	// Crea uno slice di byte di lunghezza specificata, ogni elemento è '-'
-	line := make([]byte, length)
-	for i := range line {
-		line[i] = '-'
+// evaluateCompare verifica una condizione della transazione sullo stato corrente dello store.
+// Deve essere invocata mantenendo il lock sullo store.
+func (db *DbStore) evaluateCompare(cmp utils.Compare) bool {
+	entry, exist := db.Store[cmp.Key]
+	switch cmp.Op {
+	case utils.EQUAL:
+		return exist && bytes.Equal(entry.Value, cmp.Value)
+	case utils.NOT_EQUAL:
+		return !exist || !bytes.Equal(entry.Value, cmp.Value)
+	case utils.EXISTS:
+		return exist
+	case utils.ABSENT:
+		return !exist
+	}
+	return false
+}
+
+// dump restituisce tutte le entry dello store, in ordine lessicografico
+func (db *DbStore) dump() []utils.Result {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	entries := make([]utils.Result, 0, len(db.keys))
+	for _, key := range db.keys {
+		entries = append(entries, db.resultFor(key, db.Store[key]))
 	}
-	return string(line)
+	return entries
 }
@@ -2,13 +2,18 @@ package main
 
 import (
 	"dbService/utils"
-	"encoding/json"
+	"encoding/gob"
+	"errors"
+	"fmt"
 	"log"
 	"net"
 	"sync"
 	"time"
 )
 
+// causalWaitTimeout è il tempo massimo di attesa della delivery delle scritture incluse nel contesto causale o nel clock di sessione indicati dal client
+const causalWaitTimeout = 10 * time.Second
+
 type VectorClock struct {
 	value []int
 	mutex sync.Mutex
@@ -28,44 +33,668 @@ type DbCausal struct {
 	FIFOQueues         map[int]*utils.VectorMessageQueue // Mantiene per ogni replica una coda per gestire la ricezione FIFO order dei messaggi
 	ExpectedNextSeqNum map[int]*NextSeqNum               // Per ogni replica tiene traccia del numero di sequenza del messaggio successivo che deve ricevere da quella replica (comunicazione FIFO order)
 	NextSeqNum         NextSeqNum                        // Numero di sequenza da assegnare al prossimo messaggio (REQUEST o ACK) inviato dal server
+	HLC                HybridClock                       // Clock ibrido, assegna i timestamp usati dalle politiche di risoluzione dei conflitti
+	Policies           *ConflictPolicies                 // Politiche di risoluzione dei conflitti configurate per prefisso di chiave
+	CRDTs              CRDTStore                         // Valori dei tipi di dato CRDT, replicati tramite multicast causalmente ordinato
+	CDC                *CDCLog                           // Flusso delle modifiche applicate dalla replica, nil se non configurato
+	Namespaces         *Namespaces                       // Namespace presenti sulla replica, il namespace di default usa DbStore
+	Blobs              *BlobStore                        // Valori di grandi dimensioni ricevuti a frammenti, in attesa di essere scritti
+	Activity           PeerActivity                      // Istante di ricezione dell'ultimo messaggio da ogni altra replica
+	Sessions           SessionOrders                     // Ordine di esecuzione delle richieste di ogni sessione dei client
+	Snapshots          SnapshotGate                      // Coordina l'applicazione delle scritture con le snapshot di backup
 }
 
 // Get recupera il valore corrispondente a una chiave
 func (db *DbCausal) Get(args utils.Args, result *utils.Result) error {
-	value := db.DbStore.getEntry(args.Key)
-	for value == "NOT FOUND" {
+	// Le richieste di una sessione sono eseguite nell'ordine con cui il client le ha inviate
+	release := db.Sessions.enter(args.Order)
+	defer release()
+
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	if err := db.waitForSession(args.After); err != nil {
+		return err
+	}
+	entry := namespace.Store.getEntry(args.Key)
+	// In attesa della chiave la richiesta è considerata completata, così che le richieste successive della sessione inviate senza attenderne la risposta non restino bloccate
+	release()
+	for string(entry.Value) == "NOT FOUND" {
 		time.Sleep(500 * time.Millisecond)
-		value = db.DbStore.getEntry(args.Key)
+		// Il namespace può essere rimosso durante l'attesa
+		if namespace, err = db.Namespaces.get(args.Namespace); err != nil {
+			return err
+		}
+		entry = namespace.Store.getEntry(args.Key)
 	}
-	result.Value = value
+	*result = entry
 	return nil
 }
 
-// Put inserisce una nuova coppia key-value, o aggiorna il valore corrente se la chiave già esiste
+// Put inserisce una nuova coppia key-value, o aggiorna il valore corrente se la chiave già esiste.
+// Se il client indica il contesto causale restituito da una Get, la scrittura riconcilia le versioni concorrenti incluse nel contesto.
+// In assenza di un TTL si applica quello di default del namespace.
 func (db *DbCausal) Put(args utils.Args, result *utils.Result) error {
+	defer db.Sessions.enter(args.Order)()
+
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	if err := checkValueSize(args.Key, args.Value); err != nil {
+		return err
+	}
+	if err := namespace.admit(utils.BatchEntry{Key: args.Key, Value: args.Value}); err != nil {
+		return err
+	}
+	if err := db.waitForSession(args.After); err != nil {
+		return err
+	}
+	if err := db.waitForContext(args.Context); err != nil {
+		return err
+	}
+
+	// Costruisce il messaggio di update, incrementando il clock del server di 1
+	update := db.newUpdate(utils.PUT, args.Key, args.Value, args.Context)
+	update.ExpiresAt = namespace.withDefaultTTL(args).ExpiresAt()
+	update.Namespace = args.Namespace
+
 	// La consegna all'applicativo di un messaggio proveniente dal processo stesso può essere realizzata immediatamente.
 	// Questo perché eventi successivi in uno stesso processo sono causalmente ordinati tra loro, nell'ordine con cui tali richieste giungono alla replica.
-	db.DbStore.putEntry(args.Key, args.Value)
+	db.DeliverMessage(update)
 
-	// propaga la PUT verso le altre repliche del db
-	db.sendUpdate(utils.PUT, args.Key, args.Value)
+	// propaga la PUT verso le altre repliche del db.
+	// Un valore di grandi dimensioni è trasferito a frammenti, e il messaggio ne trasporta solo l'identificatore.
+	db.sendVectorMessage(db.sendChunks(update))
+	result.Version = update.Version()
 	return nil
 }
 
+// sendChunks trasferisce a frammenti verso le altre repliche il valore del messaggio, se supera CHUNK_SIZE,
+// e restituisce il messaggio in cui il valore è sostituito dal suo identificatore.
+// I frammenti sono inviati prima del messaggio, quindi la comunicazione FIFO order garantisce che ogni replica li riceva prima di esso.
+func (db *DbCausal) sendChunks(update utils.VectorMessage) utils.VectorMessage {
+	if ChunkSize <= 0 || len(update.Value) <= ChunkSize {
+		return update
+	}
+	blobID := db.Blobs.newID(db.ID)
+	for _, chunk := range utils.SplitChunks(blobID, update.Value, ChunkSize) {
+		db.sendVectorMessage(utils.VectorMessage{Op: utils.CHUNK, Chunk: &chunk, ServerID: db.ID})
+	}
+	update.Value = nil
+	update.BlobID = blobID
+	return update
+}
+
 // Delete rimuove la entry corrispondente a una data chiave
 func (db *DbCausal) Delete(args utils.Args, result *utils.Result) error {
+	defer db.Sessions.enter(args.Order)()
+
 	//Sono valide le stesse considerazioni realizzate per la PUT.
-	db.DbStore.deleteEntry(args.Key)
+	if _, err := db.Namespaces.get(args.Namespace); err != nil {
+		return err
+	}
+	if err := db.waitForSession(args.After); err != nil {
+		return err
+	}
+	if err := db.waitForContext(args.Context); err != nil {
+		return err
+	}
+	update := db.newUpdate(utils.DELETE, args.Key, args.Value, args.Context)
+	update.Namespace = args.Namespace
+	db.DeliverMessage(update)
 
 	//propaga la DELETE verso le altre repliche del db
-	db.sendUpdate(utils.DELETE, args.Key, args.Value)
+	db.sendVectorMessage(update)
+	result.Version = update.Version()
+	return nil
+}
+
+// SetTTL aggiorna il TTL delle versioni correnti di una chiave, senza riscriverne il valore e senza produrre una nuova versione.
+// L'aggiornamento si applica alle versioni consegnate dalla replica prima della richiesta, su tutte le repliche, mentre le scritture concorrenti mantengono la propria scadenza.
+// Con un TTL nullo la entry non scade più. Succeeded indica se la chiave esisteva sulla replica al momento della richiesta.
+func (db *DbCausal) SetTTL(args utils.Args, result *utils.CondResult) error {
+	defer db.Sessions.enter(args.Order)()
+
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	if err := db.waitForSession(args.After); err != nil {
+		return err
+	}
+	result.Key = args.Key
+	// Una chiave assente non ha versioni di cui aggiornare il TTL
+	if entry := namespace.Store.getEntry(args.Key); !entry.Version.IsVector() {
+		return nil
+	}
+
+	update := db.newUpdate(utils.SET_TTL, args.Key, nil, nil)
+	update.ExpiresAt = args.ExpiresAt()
+	update.Namespace = args.Namespace
+	db.DeliverMessage(update)
+
+	//propaga l'aggiornamento verso le altre repliche del db
+	db.sendVectorMessage(update)
+	result.Succeeded = true
 	return nil
 }
 
-// updateVectorClockOnSend incrementa di 1 il clock del server nel clock vettoriale
-func (db *DbCausal) updateVectorClockOnSend() []int {
+// waitForContext attende che la replica abbia consegnato all'applicativo tutte le scritture incluse nel contesto causale indicato dal client.
+// In questo modo il contesto è sempre incluso nel clock della scrittura, e ogni replica che la riceve ha già applicato le versioni che essa riconcilia.
+// Se le scritture del contesto non sono consegnate entro causalWaitTimeout, ad esempio perché la replica che le ha originate non è raggiungibile, viene restituito un errore.
+func (db *DbCausal) waitForContext(context []int) error {
+	if context == nil {
+		return nil
+	}
+	if len(context) != NumReplicas {
+		return errors.New("invalid causal context: its length must match the number of replicas")
+	}
+	deadline := time.Now().Add(causalWaitTimeout)
+	for !db.clockIncludes(context) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("causal context %v not delivered by replica %d within %s", context, db.ID, causalWaitTimeout)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return nil
+}
+
+// waitForSession attende che la replica abbia consegnato all'applicativo tutte le scritture incluse nel clock della sessione del client.
+// Il client indica il clock delle operazioni già eseguite dalla sessione, anche su altre repliche,
+// così che le garanzie di sessione (read-your-writes, monotonic reads) siano mantenute quando il client cambia replica.
+// Se la replica non riceve le scritture della sessione entro causalWaitTimeout le garanzie non possono essere rispettate, e viene restituito un errore.
+func (db *DbCausal) waitForSession(clock []int) error {
+	if clock == nil {
+		return nil
+	}
+	if len(clock) != NumReplicas {
+		return errors.New("invalid session clock: its length must match the number of replicas")
+	}
+	deadline := time.Now().Add(causalWaitTimeout)
+	for !db.clockIncludes(clock) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("session guarantees can not be met: session clock %v not delivered by replica %d within %s", clock, db.ID, causalWaitTimeout)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return nil
+}
+
+// clockIncludes verifica se il clock vettoriale della replica include il clock indicato, ossia se per ogni k clock[k] <= V[k]
+func (db *DbCausal) clockIncludes(clock []int) bool {
+	db.Clock.mutex.Lock()
+	defer db.Clock.mutex.Unlock()
+	for k := range clock {
+		if clock[k] > db.Clock.value[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// writeContext restituisce il contesto causale con cui applicare una scrittura.
+// In assenza di un contesto esplicito la scrittura riconcilia tutte le versioni che la precedono causalmente, ossia quelle incluse nel suo clock.
+func writeContext(context []int, clock []int) []int {
+	if context == nil {
+		return clock
+	}
+	return context
+}
+
+// GetAt recupera il valore di una chiave in corrispondenza di un dato clock vettoriale.
+// Viene restituito il valore della versione più recente, tra quelle applicate dalla replica, che precede causalmente il clock indicato.
+func (db *DbCausal) GetAt(args utils.GetAtArgs, result *utils.Result) error {
+	if !args.Version.IsVector() {
+		return errors.New("GetAt with CAUSAL consistency requires a vector clock version")
+	}
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	*result = namespace.Store.getEntryAt(args)
+	return nil
+}
+
+// Watch attende le modifiche applicate dalla replica alla chiave (o alle chiavi con il prefisso) richiesta.
+// Le modifiche sono osservate nell'ordine di delivery della replica, che rispetta le relazioni causa-effetto.
+func (db *DbCausal) Watch(args utils.WatchArgs, result *utils.WatchResult) error {
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	watch, err := namespace.Store.Watches.wait(args)
+	if err != nil {
+		return err
+	}
+	*result = watch
+	return nil
+}
+
+// Scan restituisce una pagina delle entry le cui chiavi sono comprese nell'intervallo richiesto.
+// Come la GET, la scansione legge lo stato locale della replica, che include tutte le scritture causalmente precedenti già consegnate.
+func (db *DbCausal) Scan(args utils.ScanArgs, result *utils.ScanResult) error {
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	*result = namespace.Store.scanEntries(args)
+	return nil
+}
+
+// ScanPrefix restituisce una pagina delle entry le cui chiavi hanno il prefisso richiesto
+func (db *DbCausal) ScanPrefix(args utils.ScanArgs, result *utils.ScanResult) error {
+	if args.Prefix == "" {
+		return errors.New("ScanPrefix requires a prefix")
+	}
+	return db.Scan(args, result)
+}
+
+// MultiGet recupera i valori corrispondenti a più chiavi dallo stato locale della replica.
+// A differenza della Get, una chiave non presente non sospende la richiesta ma restituisce NOT FOUND.
+func (db *DbCausal) MultiGet(args utils.BatchArgs, result *utils.BatchResult) error {
+	defer db.Sessions.enter(args.Order)()
+
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	if err := db.waitForSession(args.After); err != nil {
+		return err
+	}
+	errs, _ := args.Validate()
+	result.Errors = errs
+	result.Results = make([]utils.Result, len(args.Entries))
+	results := namespace.Store.getEntries(args.BatchEntries(errs))
+	for i := range args.Entries {
+		if errs[i] == "" {
+			result.Results[i] = results[0]
+			results = results[1:]
+		}
+	}
+	return nil
+}
+
+// MultiPut inserisce o aggiorna più coppie key-value, propagate come un unico messaggio causalmente ordinato
+func (db *DbCausal) MultiPut(args utils.BatchArgs, result *utils.BatchResult) error {
+	return db.sendBatch(utils.MULTI_PUT, args, result)
+}
+
+// MultiDelete rimuove le entry corrispondenti a più chiavi, propagate come un unico messaggio causalmente ordinato
+func (db *DbCausal) MultiDelete(args utils.BatchArgs, result *utils.BatchResult) error {
+	return db.sendBatch(utils.MULTI_DELETE, args, result)
+}
+
+// sendBatch applica localmente le scritture valide della richiesta e le propaga verso le altre repliche come un unico messaggio
+func (db *DbCausal) sendBatch(op utils.Operation, args utils.BatchArgs, result *utils.BatchResult) error {
+	defer db.Sessions.enter(args.Order)()
+
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	errs, _ := args.ValidateWrites()
+	for i, entry := range args.Entries {
+		if errs[i] == "" && entry.Context != nil && len(entry.Context) != NumReplicas {
+			errs[i] = "invalid causal context: its length must match the number of replicas"
+		}
+		if errs[i] == "" && op == utils.MULTI_PUT {
+			if err := checkInlineValue(entry.Key, entry.Value); err != nil {
+				errs[i] = err.Error()
+			}
+		}
+	}
+	result.Errors = errs
+	result.Results = make([]utils.Result, len(args.Entries))
+	for i, entry := range args.Entries {
+		result.Results[i] = utils.Result{Key: entry.Key, Value: entry.Value}
+		args.Entries[i] = namespace.withDefaultTTL(entry)
+	}
+	entries := args.BatchEntries(errs)
+	if len(entries) == 0 {
+		return nil
+	}
+	if op == utils.MULTI_PUT {
+		if err := namespace.admit(entries...); err != nil {
+			return err
+		}
+	}
+
+	if err := db.waitForSession(args.After); err != nil {
+		return err
+	}
+
+	// Attende che la replica abbia consegnato tutte le scritture incluse nei contesti causali indicati
+	var contexts [][]int
+	for _, entry := range entries {
+		if entry.Context != nil {
+			contexts = append(contexts, entry.Context)
+		}
+	}
+	if err := db.waitForContext(utils.MergeClocks(contexts...)); err != nil {
+		return err
+	}
+
+	update := db.newUpdate(op, "", nil, nil)
+	update.Batch = entries
+	update.Namespace = args.Namespace
+	db.DeliverMessage(update)
+	db.sendVectorMessage(update)
+
+	// Tutte le scritture della richiesta hanno la versione del messaggio che le trasporta
+	for i := range args.Entries {
+		if errs[i] == "" {
+			result.Results[i].Version = update.Version()
+		}
+	}
+	return nil
+}
+
+// Increment incrementa (o decrementa, se Delta è negativo) il PN-counter indicato e ne restituisce il valore corrente sulla replica
+func (db *DbCausal) Increment(args utils.CounterArgs, result *utils.CounterResult) error {
+	db.sendCRDTUpdate(utils.INCREMENT, args.Key, nil, utils.CRDTOp{Delta: args.Delta})
+	result.Key = args.Key
+	result.Value = db.CRDTs.counterValue(args.Key)
+	return nil
+}
+
+// GetCounter restituisce il valore corrente del PN-counter indicato
+func (db *DbCausal) GetCounter(args utils.Args, result *utils.CounterResult) error {
+	result.Key = args.Key
+	result.Value = db.CRDTs.counterValue(args.Key)
+	return nil
+}
+
+// SetAdd aggiunge un elemento all'OR-set indicato.
+// L'aggiunta è identificata da un tag univoco, composto dall'ID della replica e dal suo clock al momento della richiesta.
+func (db *DbCausal) SetAdd(args utils.SetArgs, result *utils.SetResult) error {
+	update := db.newUpdate(utils.SET_ADD, args.Key, nil, nil)
+	update.CRDT = &utils.CRDTOp{Element: args.Element, Tag: fmt.Sprintf("%d.%d", db.ID, update.Clock[db.ID])}
+	db.DeliverMessage(update)
+	db.sendVectorMessage(update)
+
+	result.Key = args.Key
+	result.Elements = db.CRDTs.setMembers(args.Key)
+	return nil
+}
+
+// SetRemove rimuove un elemento dall'OR-set indicato.
+// Vengono rimosse solo le aggiunte dell'elemento osservate dalla replica, quindi un'aggiunta concorrente prevale sulla rimozione.
+func (db *DbCausal) SetRemove(args utils.SetArgs, result *utils.SetResult) error {
+	observed := db.CRDTs.observedTags(args.Key, args.Element)
+	db.sendCRDTUpdate(utils.SET_REMOVE, args.Key, nil, utils.CRDTOp{Element: args.Element, Observed: observed})
+
+	result.Key = args.Key
+	result.Elements = db.CRDTs.setMembers(args.Key)
+	return nil
+}
+
+// SetMembers restituisce gli elementi correnti dell'OR-set indicato
+func (db *DbCausal) SetMembers(args utils.Args, result *utils.SetResult) error {
+	result.Key = args.Key
+	result.Elements = db.CRDTs.setMembers(args.Key)
+	return nil
+}
+
+// RegisterSet scrive il valore del registro last-writer-wins indicato
+func (db *DbCausal) RegisterSet(args utils.Args, result *utils.Result) error {
+	if err := checkInlineValue(args.Key, args.Value); err != nil {
+		return err
+	}
+	db.sendCRDTUpdate(utils.REGISTER_SET, args.Key, args.Value, utils.CRDTOp{})
+	*result = db.CRDTs.registerValue(args.Key)
+	return nil
+}
+
+// RegisterGet restituisce il valore corrente del registro last-writer-wins indicato
+func (db *DbCausal) RegisterGet(args utils.Args, result *utils.Result) error {
+	*result = db.CRDTs.registerValue(args.Key)
+	return nil
+}
+
+// MapPut scrive un campo della mappa multi-valore indicata.
+// La scrittura sostituisce i valori del campo che la precedono causalmente, mentre quelli concorrenti sono mantenuti.
+func (db *DbCausal) MapPut(args utils.MapArgs, result *utils.MapResult) error {
+	db.sendCRDTUpdate(utils.MAP_PUT, args.Key, []byte(args.Value), utils.CRDTOp{Field: args.Field})
+	result.Key = args.Key
+	result.Fields = db.CRDTs.mapValues(args.Key)
+	return nil
+}
+
+// MapRemove rimuove un campo della mappa multi-valore indicata.
+// Vengono rimossi solo i valori del campo che precedono causalmente la rimozione.
+func (db *DbCausal) MapRemove(args utils.MapArgs, result *utils.MapResult) error {
+	db.sendCRDTUpdate(utils.MAP_REMOVE, args.Key, nil, utils.CRDTOp{Field: args.Field})
+	result.Key = args.Key
+	result.Fields = db.CRDTs.mapValues(args.Key)
+	return nil
+}
+
+// MapGet restituisce i campi correnti della mappa multi-valore indicata
+func (db *DbCausal) MapGet(args utils.Args, result *utils.MapResult) error {
+	result.Key = args.Key
+	result.Fields = db.CRDTs.mapValues(args.Key)
+	return nil
+}
+
+// sendCRDTUpdate costruisce il messaggio associato a un'operazione CRDT, lo consegna immediatamente alla replica locale e lo propaga alle altre repliche
+func (db *DbCausal) sendCRDTUpdate(op utils.Operation, key string, value []byte, crdtOp utils.CRDTOp) {
+	update := db.newUpdate(op, key, value, nil)
+	update.CRDT = &crdtOp
+	db.DeliverMessage(update)
+	db.sendVectorMessage(update)
+}
+
+// GetPath recupera il valore di un percorso all'interno di un documento JSON.
+// Come la Get, attende che la chiave sia presente sulla replica.
+func (db *DbCausal) GetPath(args utils.PathArgs, result *utils.Result) error {
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	if err := checkPathArgs(namespace, args, utils.GET); err != nil {
+		return err
+	}
+	var entry utils.Result
+	if err := db.Get(utils.Args{Namespace: args.Namespace, Key: args.Key}, &entry); err != nil {
+		return err
+	}
+	*result, err = documentPath(entry, args.Path)
+	return err
+}
+
+// SetPath scrive il valore di un percorso all'interno di un documento JSON
+func (db *DbCausal) SetPath(args utils.PathArgs, result *utils.Result) error {
+	return db.sendPathUpdate(utils.SET_PATH, args)
+}
+
+// DeletePath rimuove un percorso all'interno di un documento JSON
+func (db *DbCausal) DeletePath(args utils.PathArgs, result *utils.Result) error {
+	return db.sendPathUpdate(utils.DELETE_PATH, args)
+}
+
+// sendPathUpdate propaga verso le altre repliche la scrittura o la rimozione di un percorso di un documento.
+// L'operazione è propagata come operazione sul percorso, e non come documento intero, così che le scritture concorrenti
+// su campi diversi di uno stesso documento siano applicate entrambe da tutte le repliche.
+// La replica che riceve la richiesta verifica l'operazione e la quota del namespace sullo stato corrente del documento.
+func (db *DbCausal) sendPathUpdate(op utils.Operation, args utils.PathArgs) error {
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	if err := checkPathArgs(namespace, args, op); err != nil {
+		return err
+	}
+	document, err := namespace.Store.previewPath(args.Key, op, args.Path, args.Value)
+	if err != nil {
+		return err
+	}
+	if err := checkValueSize(args.Key, document); err != nil {
+		return err
+	}
+	if err := namespace.admit(utils.BatchEntry{Key: args.Key, Value: document}); err != nil {
+		return err
+	}
+
+	update := db.newUpdate(op, args.Key, args.Value, nil)
+	update.Path = args.Path
+	update.Namespace = args.Namespace
+	db.DeliverMessage(update)
+
+	// propaga l'operazione verso le altre repliche del db
+	db.sendVectorMessage(update)
+	return nil
+}
+
+// CreateNamespace crea un namespace.
+// La creazione è applicata immediatamente dalla replica locale e propagata alle altre repliche tramite multicast causalmente ordinato:
+// le scritture sul namespace che la seguono causalmente sono quindi consegnate da ogni replica dopo la creazione.
+func (db *DbCausal) CreateNamespace(args utils.NamespaceArgs, result *utils.NamespaceInfo) error {
+	if err := args.Validate(); err != nil {
+		return err
+	}
+	if _, err := NewConflictResolver(args.ConflictPolicy); err != nil {
+		return err
+	}
+	if db.Namespaces.lookup(args.Name) != nil {
+		return fmt.Errorf("namespace %q already exists", args.Name)
+	}
+
+	update := db.newUpdate(utils.CREATE_NAMESPACE, args.Name, nil, nil)
+	update.NamespaceConfig = &args
+	db.DeliverMessage(update)
+	db.sendVectorMessage(update)
+
+	*result = utils.NamespaceInfo{Config: args}
+	return nil
+}
+
+// ListNamespaces restituisce i namespace presenti sulla replica
+func (db *DbCausal) ListNamespaces(args utils.NamespaceArgs, result *utils.NamespaceList) error {
+	result.Namespaces = db.Namespaces.list()
+	return nil
+}
+
+// DropNamespace rimuove un namespace con tutte le sue chiavi.
+// Le scritture sul namespace consegnate da una replica dopo la rimozione vengono scartate.
+func (db *DbCausal) DropNamespace(args utils.NamespaceArgs, result *utils.NamespaceInfo) error {
+	if err := args.Validate(); err != nil {
+		return err
+	}
+	namespace, err := db.Namespaces.get(args.Name)
+	if err != nil {
+		return err
+	}
+
+	update := db.newUpdate(utils.DROP_NAMESPACE, args.Name, nil, nil)
+	db.DeliverMessage(update)
+	db.sendVectorMessage(update)
+
+	*result = utils.NamespaceInfo{Config: namespace.Config}
+	return nil
+}
+
+// CreateIndex crea un indice secondario su un campo dei documenti JSON di un namespace.
+// L'indice è costruito da ogni replica a partire dal proprio store al momento della consegna della creazione, e aggiornato a ogni scrittura successiva:
+// è quindi sempre consistente con i dati della replica.
+func (db *DbCausal) CreateIndex(args utils.IndexArgs, result *utils.IndexInfo) error {
+	if err := args.Validate(); err != nil {
+		return err
+	}
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	if _, err := namespace.Store.lookupIndex(args.Name); err == nil {
+		return fmt.Errorf("index %q already exists", args.Name)
+	}
+
+	update := db.newUpdate(utils.CREATE_INDEX, args.Name, nil, nil)
+	update.Namespace = args.Namespace
+	update.Index = &args
+	db.DeliverMessage(update)
+	db.sendVectorMessage(update)
+
+	*result, err = namespace.Store.lookupIndex(args.Name)
+	return err
+}
+
+// ListIndexes restituisce gli indici secondari di un namespace presenti sulla replica
+func (db *DbCausal) ListIndexes(args utils.IndexArgs, result *utils.IndexList) error {
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	result.Indexes = namespace.Store.listIndexes()
+	return nil
+}
+
+// DropIndex rimuove un indice secondario
+func (db *DbCausal) DropIndex(args utils.IndexArgs, result *utils.IndexInfo) error {
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	info, err := namespace.Store.lookupIndex(args.Name)
+	if err != nil {
+		return err
+	}
+
+	update := db.newUpdate(utils.DROP_INDEX, args.Name, nil, nil)
+	update.Namespace = args.Namespace
+	db.DeliverMessage(update)
+	db.sendVectorMessage(update)
+
+	*result = info
+	return nil
+}
+
+// QueryIndex restituisce le chiavi il cui campo indicizzato ha il valore cercato, secondo lo stato corrente della replica
+func (db *DbCausal) QueryIndex(args utils.QueryArgs, result *utils.QueryResult) error {
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	if _, err := namespace.Store.lookupIndex(args.Index); err != nil {
+		return err
+	}
+	*result = namespace.Store.queryIndex(args)
+	return nil
+}
+
+// errSequentialOnly è restituito dalle operazioni condizionali, che richiedono un ordine totale tra le operazioni.
+// Il multicast causalmente ordinato non stabilisce un ordine comune tra operazioni concorrenti, quindi le repliche non possono concordare sull'esito delle condizioni.
+var errSequentialOnly = errors.New("operation supported only with SEQUENTIAL consistency")
+
+// Txn non è supportata con consistenza causale
+func (db *DbCausal) Txn(args utils.TxnArgs, result *utils.TxnResult) error {
+	return errSequentialOnly
+}
+
+// CompareAndSwap non è supportata con consistenza causale
+func (db *DbCausal) CompareAndSwap(args utils.CASArgs, result *utils.CondResult) error {
+	return errSequentialOnly
+}
+
+// PutIfAbsent non è supportata con consistenza causale
+func (db *DbCausal) PutIfAbsent(args utils.Args, result *utils.CondResult) error {
+	return errSequentialOnly
+}
+
+// DeleteIfValue non è supportata con consistenza causale
+func (db *DbCausal) DeleteIfValue(args utils.Args, result *utils.CondResult) error {
+	return errSequentialOnly
+}
+
+// updateVectorClockOnSend incrementa di 1 il clock del server nel clock vettoriale e assegna il timestamp ibrido della scrittura.
+// Clock e timestamp sono assegnati nella stessa sezione critica, quindi l'ordine dei timestamp delle scritture locali è quello dei loro clock.
+func (db *DbCausal) updateVectorClockOnSend() ([]int, utils.HybridTimestamp) {
 	db.Clock.mutex.Lock()
 	db.Clock.value[db.ID]++
+	timestamp := db.HLC.Now()
+	// La scrittura a cui è assegnato il clock sarà applicata in seguito: fino ad allora una snapshot di backup non può essere presa
+	db.Snapshots.assign()
 
 	// Crea una copia del clock vettoriale per evitare modifiche
 	clockCopy := make([]int, len(db.Clock.value))
@@ -73,7 +702,7 @@ func (db *DbCausal) updateVectorClockOnSend() []int {
 
 	db.Clock.mutex.Unlock()
 
-	return clockCopy
+	return clockCopy, timestamp
 }
 
 // updateVectorClockOnDelivery aggiorna il clock, per ogni k: V[k] = max{V[k], ts(msg)[k]}
@@ -85,23 +714,24 @@ func (db *DbCausal) updateVectorClockOnDelivery(msgClock []int) {
 	}
 }
 
-// sendUpdate propaga la richiesta di update (PUT o DELETE) verso gli altri processi
-func (db *DbCausal) sendUpdate(op utils.Operation, key string, value string) {
+// newUpdate costruisce il messaggio associato a una richiesta di update (PUT o DELETE) da propagare verso gli altri processi.
+// Il clock del server è incrementato di 1, e il clock risultante rappresenta la versione della scrittura.
+// context è il contesto causale indicato dal client, nil se assente.
+func (db *DbCausal) newUpdate(op utils.Operation, key string, value []byte, context []int) utils.VectorMessage {
 
-	// Incrementa il clock del server di 1
-	currentClock := db.updateVectorClockOnSend()
+	// Incrementa il clock del server di 1 e assegna il timestamp ibrido
+	currentClock, timestamp := db.updateVectorClockOnSend()
 
 	// costruisce un messaggio associato alla richiesta di update, associando il clock vettoriale
-	update := utils.VectorMessage{
-		Key:      key,
-		Value:    value,
-		Op:       op,
-		Clock:    currentClock,
-		ServerID: db.ID,
+	return utils.VectorMessage{
+		Key:       key,
+		Value:     value,
+		Op:        op,
+		Clock:     currentClock,
+		Timestamp: timestamp,
+		Context:   context,
+		ServerID:  db.ID,
 	}
-
-	// Invia il messaggio alle altre repliche, simulando un ritardo di comunicazione
-	db.sendVectorMessage(update)
 }
 
 // sendVectorMessage invia un messaggio alle altre repliche, simulando un ritardo di comunicazione
@@ -122,8 +752,8 @@ func (db *DbCausal) sendVectorMessage(msg utils.VectorMessage) {
 				log.Fatal("Error in dialing: ", err)
 			}
 
-			// Codifica il messaggio in json e lo invia al server
-			encoder := json.NewEncoder(conn)
+			// Codifica il messaggio con gob, che trasmette i valori binari senza espanderli, e lo invia al server
+			encoder := gob.NewEncoder(conn)
 			err = encoder.Encode(msg)
 			if err != nil {
 				log.Fatal("Error while coding message : ", err)
@@ -147,8 +777,8 @@ func (db *DbCausal) handleConnection(conn net.Conn) {
 		}
 	}(conn)
 
-	// Decodifica il messaggio JSON ricevuto
-	decoder := json.NewDecoder(conn)
+	// Decodifica il messaggio gob ricevuto
+	decoder := gob.NewDecoder(conn)
 	var msg utils.VectorMessage
 	err := decoder.Decode(&msg)
 	if err != nil {
@@ -158,6 +788,7 @@ func (db *DbCausal) handleConnection(conn net.Conn) {
 
 	seqNum := msg.SeqNum
 	idSender := msg.ServerID
+	db.Activity.received(idSender)
 
 	// Controlla se il messaggio ricevuto dal server idSender ha il numero di sequenza atteso
 	// se ha il numero di sequenza atteso il messaggio può essere ricevuto
@@ -186,6 +817,11 @@ func (db *DbCausal) handleConnection(conn net.Conn) {
 
 // receive gestisce la ricezione di un messaggio da parte della replica
 func (db *DbCausal) receive(msg utils.VectorMessage) {
+	// Un frammento di un valore non entra nella coda di attesa, ma viene copiato direttamente nel valore a cui appartiene
+	if msg.Op == utils.CHUNK {
+		db.Blobs.addChunk(*msg.Chunk)
+		return
+	}
 
 	db.Clock.mutex.Lock()
 
@@ -218,13 +854,142 @@ func (db *DbCausal) receive(msg utils.VectorMessage) {
 
 // DeliverMessage consegna il messaggio all'applicativo, ossia realizza l'operazione associata
 func (db *DbCausal) DeliverMessage(msg utils.VectorMessage) {
+	// Una snapshot di backup non può essere presa durante la consegna, e al suo termine il clock del messaggio è incluso nella posizione delle scritture applicate
+	db.Snapshots.enter()
+	defer func() {
+		db.Snapshots.advance(msg.Version())
+		if msg.ServerID == db.ID {
+			db.Snapshots.deliver()
+		}
+		db.Snapshots.exit()
+	}()
+
+	// Recupera il valore ricevuto a frammenti
+	if msg.BlobID != "" {
+		value, err := db.Blobs.take(msg.BlobID)
+		if err != nil {
+			log.Printf("%s key %s skipped: %v", msg.Op, msg.Key, err)
+			return
+		}
+		msg.Value = value
+	}
+
+	// aggiorna il clock ibrido, così che le scritture locali successive abbiano timestamp maggiore
+	db.HLC.Update(msg.Timestamp)
+
 	// processa il messaggio
 	switch msg.Op {
 	case utils.GET:
 		db.DbStore.getEntry(msg.Key)
-	case utils.PUT:
-		db.DbStore.putEntry(msg.Key, msg.Value)
-	case utils.DELETE:
-		db.DbStore.deleteEntry(msg.Key)
+	case utils.PUT, utils.DELETE:
+		namespace := db.Namespaces.lookup(msg.Namespace)
+		if namespace == nil {
+			fmt.Printf("%s key %s skipped, namespace %s does not exist\n", msg.Op, msg.Key, msg.Namespace)
+			return
+		}
+		db.applyUpdate(namespace, msg)
+		return
+	case utils.EXPIRE:
+		namespace := db.Namespaces.lookup(msg.Namespace)
+		if namespace == nil {
+			fmt.Printf("%s key %s skipped, namespace %s does not exist\n", msg.Op, msg.Key, msg.Namespace)
+			return
+		}
+		namespace.Store.expireVersion(msg.Key, *msg.Expired, msg.Version(), namespace.ResolverFor(msg.Key, db.Policies) != nil, func() {
+			db.CDC.record(causalChanges(msg)...)
+		})
+		return
+	case utils.MULTI_PUT, utils.MULTI_DELETE:
+		namespace := db.Namespaces.lookup(msg.Namespace)
+		if namespace == nil {
+			fmt.Printf("%s skipped, namespace %s does not exist\n", msg.Op, msg.Namespace)
+			return
+		}
+		// Ogni scrittura del messaggio assume la versione del messaggio, ed è registrata nel flusso CDC solo se applicata
+		changes := causalChanges(msg)
+		for i, entry := range msg.Batch {
+			db.applyEntry(namespace, entry.Key, utils.VersionedValue{
+				Value:     entry.Value,
+				Version:   msg.Version(),
+				Deleted:   msg.Op == utils.MULTI_DELETE,
+				ExpiresAt: entry.ExpiresAt,
+			}, writeContext(entry.Context, msg.Clock), changes[i])
+		}
+		return
+	case utils.SET_TTL:
+		namespace := db.Namespaces.lookup(msg.Namespace)
+		if namespace == nil {
+			fmt.Printf("%s key %s skipped, namespace %s does not exist\n", msg.Op, msg.Key, msg.Namespace)
+			return
+		}
+		namespace.Store.mergeExpiry(msg.Key, msg.ExpiresAt, msg.Version(), func() {
+			db.CDC.record(causalChanges(msg)...)
+		})
+		return
+	case utils.SET_PATH, utils.DELETE_PATH:
+		namespace := db.Namespaces.lookup(msg.Namespace)
+		if namespace == nil {
+			fmt.Printf("%s key %s skipped, namespace %s does not exist\n", msg.Op, msg.Key, msg.Namespace)
+			return
+		}
+		namespace.Store.mergePath(msg.Op, msg.Key, msg.Path, msg.Value, msg.Version(), func() {
+			db.CDC.record(causalChanges(msg)...)
+		})
+		return
+	case utils.INCREMENT, utils.SET_ADD, utils.SET_REMOVE, utils.REGISTER_SET, utils.MAP_PUT, utils.MAP_REMOVE:
+		db.CRDTs.apply(msg)
+	case utils.CREATE_NAMESPACE, utils.DROP_NAMESPACE:
+		var err error
+		if msg.Op == utils.CREATE_NAMESPACE {
+			err = db.Namespaces.create(*msg.NamespaceConfig, msg.Version())
+		} else {
+			err = db.Namespaces.drop(msg.Key)
+		}
+		if err != nil {
+			fmt.Printf("%s %s skipped: %v\n", msg.Op, msg.Key, err)
+			return
+		}
+	case utils.CREATE_INDEX, utils.DROP_INDEX:
+		if err := db.Namespaces.indexOp(msg.Namespace, msg.Op, msg.Key, msg.Index, msg.Version()); err != nil {
+			fmt.Printf("%s %s skipped: %v\n", msg.Op, msg.Key, err)
+			return
+		}
 	}
+
+	// registra nel flusso CDC le modifiche applicate dal messaggio.
+	// Le scritture sulle chiavi sono registrate dallo store, solo se applicate e nella stessa sezione critica in cui sono applicate,
+	// così che il flusso rispetti l'ordine con cui la replica applica scritture locali e remote concorrenti.
+	db.CDC.record(causalChanges(msg)...)
+}
+
+// applyUpdate applica allo store una PUT o una DELETE.
+// Se per la chiave è configurata una politica di risoluzione dei conflitti, una scrittura concorrente con la versione corrente è risolta dal resolver,
+// altrimenti le versioni concorrenti sono mantenute come siblings.
+func (db *DbCausal) applyUpdate(namespace *Namespace, msg utils.VectorMessage) {
+	entry := utils.VersionedValue{Value: msg.Value, Version: msg.Version(), Deleted: msg.Op == utils.DELETE, ExpiresAt: msg.ExpiresAt}
+	db.applyEntry(namespace, msg.Key, entry, writeContext(msg.Context, msg.Clock), causalChanges(msg)[0])
+}
+
+// applyEntry applica allo store del namespace la versione di una chiave prodotta da una scrittura, con il contesto causale indicato.
+// change è il record CDC della scrittura, registrato solo se la scrittura non è superata o scartata dalla risoluzione dei conflitti.
+func (db *DbCausal) applyEntry(namespace *Namespace, key string, entry utils.VersionedValue, context []int, change utils.CDCRecord) {
+	record := func() {
+		db.CDC.record(change)
+	}
+	if resolver := namespace.ResolverFor(key, db.Policies); resolver != nil {
+		namespace.Store.resolveEntry(key, entry, context, resolver, record)
+	} else {
+		namespace.Store.applySibling(key, entry, context, record)
+	}
+}
+
+// expire richiede la rimozione di una versione scaduta di una chiave, tramite un messaggio di EXPIRE causalmente ordinato.
+// Ogni replica rimuove solo la versione scaduta: a differenza di una DELETE, l'EXPIRE non partecipa alla risoluzione dei conflitti,
+// quindi le scritture successive o concorrenti non vengono rimosse qualunque sia la politica della chiave.
+func (db *DbCausal) expire(namespace string, key string, version utils.Version) {
+	update := db.newUpdate(utils.EXPIRE, key, nil, nil)
+	update.Expired = &version
+	update.Namespace = namespace
+	db.DeliverMessage(update)
+	db.sendVectorMessage(update)
 }
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"dbService/utils"
+	"testing"
+	"time"
+)
+
+func TestCausalGetMissingKeyReleasesSession(t *testing.T) {
+	store := newDbStore()
+	db := &DbCausal{Namespaces: NewNamespaces(store, 0, func(string, string, utils.Version) {})}
+
+	got := make(chan utils.Result, 1)
+	go func() {
+		var result utils.Result
+		if err := db.Get(utils.Args{Key: "k", Order: utils.SessionOrder{Session: "s", Seq: 1}}, &result); err != nil {
+			t.Error(err)
+		}
+		got <- result
+	}()
+	time.Sleep(blockedFor)
+
+	// La richiesta successiva della sessione non attende che la chiave letta dalla precedente sia scritta
+	entered := make(chan func(), 1)
+	go func() {
+		entered <- db.Sessions.enter(utils.SessionOrder{Session: "s", Seq: 2})
+	}()
+	select {
+	case release := <-entered:
+		release()
+	case <-time.After(time.Second):
+		t.Fatal("request 2 is waiting for the Get of a missing key")
+	}
+
+	store.mutex.Lock()
+	store.Store["k"] = utils.VersionedValue{Value: []byte("v")}
+	store.mutex.Unlock()
+	select {
+	case result := <-got:
+		if string(result.Value) != "v" {
+			t.Fatalf("Get returned %q, want %q", result.Value, "v")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Get is still waiting for a key that exists")
+	}
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"dbService/utils"
-	"encoding/json"
+	"encoding/gob"
+	"errors"
+	"fmt"
 	"log"
 	"math/rand"
 	"net"
@@ -39,38 +41,645 @@ type DbSequential struct {
 	FIFOQueues         map[int]*utils.MessageQueue // Mantiene per ogni replica una coda per gestire la ricezione FIFO order dei messaggi
 	ExpectedNextSeqNum map[int]*NextSeqNum         // Per ogni replica tiene traccia del numero di sequenza del messaggio successivo che deve ricevere da quella replica (comunicazione FIFO order)
 	NextSeqNum         NextSeqNum                  // Numero di sequenza da assegnare al prossimo messaggio (REQUEST o ACK) inviato dal server
-
+	CDC                *CDCLog                     // Flusso delle modifiche applicate dalla replica, nil se non configurato
+	Namespaces         *Namespaces                 // Namespace presenti sulla replica, il namespace di default usa DbStore
+	Blobs              *BlobStore                  // Valori di grandi dimensioni trasferiti a frammenti, in attesa di essere scritti
+	Activity           PeerActivity                // Istante di ricezione dell'ultimo messaggio da ogni altra replica
+	Sessions           SessionOrders               // Ordine di esecuzione delle richieste di ogni sessione dei client
+	Snapshots          SnapshotGate                // Coordina l'applicazione delle scritture con le snapshot di backup
 }
 
 // Get recupera il valore corrispondente a una chiave
 func (db *DbSequential) Get(args utils.Args, result *utils.Result) error {
+	// Le richieste di una sessione sono eseguite nell'ordine con cui il client le ha inviate
+	defer db.Sessions.enter(args.Order)()
+
+	if _, err := db.Namespaces.get(args.Namespace); err != nil {
+		return err
+	}
+
 	// Crea un canale per ricevere il risultato
-	responseChan := make(chan string)
+	responseChan := make(chan utils.Result)
 
 	// gestisce la richiesta di Get
-	go db.handleGetRequest(args.Key, responseChan)
+	go db.handleReadRequest(utils.Message{
+		Key:          args.Key,
+		Op:           utils.GET,
+		Namespace:    args.Namespace,
+		ResponseChan: responseChan,
+	})
 
 	// Aspetta la risposta tramite il canale
-	value := <-responseChan
-	result.Value = value
+	*result = <-responseChan
 
 	return nil
 }
 
-// Put inserisce una nuova coppia key-value, o aggiorna il valore corrente se la chiave già esiste
+// Put inserisce una nuova coppia key-value, o aggiorna il valore corrente se la chiave già esiste.
+// Se è indicato un TTL, l'istante di scadenza è calcolato dalla replica che riceve la richiesta e propagato con il messaggio.
+// In assenza di un TTL si applica quello di default del namespace.
 func (db *DbSequential) Put(args utils.Args, result *utils.Result) error {
+	release := db.Sessions.enter(args.Order)
+	defer release()
+
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	if err := checkValueSize(args.Key, args.Value); err != nil {
+		return err
+	}
+	if err := namespace.admit(utils.BatchEntry{Key: args.Key, Value: args.Value}); err != nil {
+		return err
+	}
+
+	update := utils.Message{
+		Key:       args.Key,
+		Value:     args.Value,
+		Op:        utils.PUT,
+		ExpiresAt: namespace.withDefaultTTL(args).ExpiresAt(),
+		Namespace: args.Namespace,
+	}
+	// Un valore di grandi dimensioni è trasferito a frammenti, e il messaggio ne trasporta solo l'identificatore
+	db.sendChunks(&update)
+
+	// La posizione della PUT nell'ordine totale è fissata dal clock della REQUEST:
+	// la richiesta successiva della sessione può essere eseguita senza attenderne la propagazione
+	update = db.enqueueRequest(update)
+	release()
+
 	// propaga la PUT verso le altre repliche del db
-	db.sendUpdate(utils.PUT, args.Key, args.Value)
+	db.propagateRequest(update)
 	return nil
 }
 
+// sendChunks trasferisce a frammenti verso le altre repliche il valore del messaggio, se supera CHUNK_SIZE.
+// Il valore viene sostituito nel messaggio dal suo identificatore, così che la coda di messaggi non contenga il valore.
+// I frammenti sono inviati prima del messaggio, quindi la comunicazione FIFO order garantisce che ogni replica li riceva prima di esso.
+func (db *DbSequential) sendChunks(update *utils.Message) {
+	if ChunkSize <= 0 || len(update.Value) <= ChunkSize {
+		return
+	}
+	blobID := db.Blobs.newID(db.ID)
+	db.Blobs.put(blobID, update.Value)
+
+	var wg sync.WaitGroup
+	for _, chunk := range utils.SplitChunks(blobID, update.Value, ChunkSize) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			db.sendMessage(utils.Message{Op: utils.CHUNK, Chunk: &chunk, ServerID: db.ID})
+		}()
+	}
+	wg.Wait()
+
+	update.Value = nil
+	update.BlobID = blobID
+}
+
 // Delete rimuove la entry corrispondente a una data chiave
 func (db *DbSequential) Delete(args utils.Args, result *utils.Result) error {
+	release := db.Sessions.enter(args.Order)
+	defer release()
+
+	if _, err := db.Namespaces.get(args.Namespace); err != nil {
+		return err
+	}
+	update := db.enqueueRequest(utils.Message{
+		Key:       args.Key,
+		Value:     args.Value,
+		Op:        utils.DELETE,
+		Namespace: args.Namespace,
+	})
+	release()
+
 	//propaga la DELETE verso le altre repliche del db
-	db.sendUpdate(utils.DELETE, args.Key, args.Value)
+	db.propagateRequest(update)
 	return nil
 }
 
+// SetTTL aggiorna il TTL della versione corrente di una chiave, senza riscriverne il valore e senza produrre una nuova versione.
+// Come per la PUT l'istante di scadenza è calcolato dalla replica che riceve la richiesta, e con un TTL nullo la entry non scade più.
+// L'aggiornamento è ordinato nell'ordine totale, e Succeeded indica se la chiave esisteva quando è stato applicato.
+func (db *DbSequential) SetTTL(args utils.Args, result *utils.CondResult) error {
+	release := db.Sessions.enter(args.Order)
+	defer release()
+
+	if _, err := db.Namespaces.get(args.Namespace); err != nil {
+		return err
+	}
+
+	// Crea un canale per ricevere l'esito dell'aggiornamento, una volta applicato dalla replica locale
+	txnChan := make(chan utils.TxnOutcome, 1)
+	update := db.enqueueRequest(utils.Message{
+		Key:       args.Key,
+		Op:        utils.SET_TTL,
+		ExpiresAt: args.ExpiresAt(),
+		Namespace: args.Namespace,
+		TxnChan:   txnChan,
+	})
+	release()
+
+	// propaga l'aggiornamento verso le altre repliche del db
+	db.propagateRequest(update)
+
+	outcome := <-txnChan
+	if outcome.Err != nil {
+		return outcome.Err
+	}
+	result.Succeeded = outcome.Result.Succeeded
+	result.Key = args.Key
+	return nil
+}
+
+// Txn esegue una transazione multi-chiave.
+// La transazione è propagata come un unico messaggio, e viene applicata atomicamente da ogni replica nella sua posizione nell'ordine totale.
+func (db *DbSequential) Txn(args utils.TxnArgs, result *utils.TxnResult) error {
+	if err := args.Validate(); err != nil {
+		return err
+	}
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	if err := checkTxnWrites(namespace, args); err != nil {
+		return err
+	}
+
+	// Crea un canale per ricevere l'esito della transazione, una volta applicata dalla replica locale
+	txnChan := make(chan utils.TxnOutcome, 1)
+
+	// propaga la transazione verso le altre repliche del db
+	db.sendRequest(utils.Message{
+		Op:        utils.TXN,
+		Txn:       &args,
+		Namespace: args.Namespace,
+		TxnChan:   txnChan,
+	})
+
+	// Aspetta l'esito della transazione tramite il canale.
+	// Se il namespace è stato rimosso prima della delivery la transazione non è applicata, e il client riceve l'errore.
+	outcome := <-txnChan
+	if outcome.Err != nil {
+		return outcome.Err
+	}
+	*result = outcome.Result
+	return nil
+}
+
+// checkTxnWrites verifica che le scritture di entrambi i rami della transazione siano ammesse, poiché l'esito del confronto è noto solo alla delivery.
+// I rami sono verificati separatamente rispetto alla quota del namespace, dato che ne viene applicato uno solo.
+func checkTxnWrites(namespace *Namespace, args utils.TxnArgs) error {
+	for _, branch := range [][]utils.TxnOp{args.Success, args.Failure} {
+		var puts []utils.BatchEntry
+		for _, op := range branch {
+			if op.Op == utils.PUT {
+				if err := checkInlineValue(op.Key, op.Value); err != nil {
+					return err
+				}
+				puts = append(puts, utils.BatchEntry{Key: op.Key, Value: op.Value})
+			}
+		}
+		if err := namespace.admit(puts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CompareAndSwap aggiorna il valore di una chiave solo se il valore corrente coincide con quello atteso.
+// La condizione è valutata da ogni replica al momento della delivery, quindi tutte le repliche concordano sull'esito.
+func (db *DbSequential) CompareAndSwap(args utils.CASArgs, result *utils.CondResult) error {
+	return db.conditionalWrite(args.Namespace,
+		utils.Compare{Key: args.Key, Op: utils.EQUAL, Value: args.Expected},
+		utils.TxnOp{Op: utils.PUT, Key: args.Key, Value: args.Value},
+		result)
+}
+
+// PutIfAbsent inserisce una coppia key-value solo se la chiave non è già presente.
+// Se è indicato un TTL, l'istante di scadenza è calcolato dalla replica che riceve la richiesta, come per la PUT.
+func (db *DbSequential) PutIfAbsent(args utils.Args, result *utils.CondResult) error {
+	return db.conditionalWrite(args.Namespace,
+		utils.Compare{Key: args.Key, Op: utils.ABSENT},
+		utils.TxnOp{Op: utils.PUT, Key: args.Key, Value: args.Value, ExpiresAt: args.ExpiresAt()},
+		result)
+}
+
+// DeleteIfValue rimuove la entry corrispondente a una chiave solo se il valore corrente coincide con quello indicato
+func (db *DbSequential) DeleteIfValue(args utils.Args, result *utils.CondResult) error {
+	return db.conditionalWrite(args.Namespace,
+		utils.Compare{Key: args.Key, Op: utils.EQUAL, Value: args.Value},
+		utils.TxnOp{Op: utils.DELETE, Key: args.Key},
+		result)
+}
+
+// conditionalWrite esegue una scrittura condizionale come transazione su una singola chiave.
+// In entrambi i rami la transazione legge il valore corrente della chiave, restituito al client insieme all'esito.
+func (db *DbSequential) conditionalWrite(namespace string, cmp utils.Compare, write utils.TxnOp, result *utils.CondResult) error {
+	read := utils.TxnOp{Op: utils.GET, Key: cmp.Key}
+	txn := utils.TxnArgs{
+		Namespace: namespace,
+		Compare:   []utils.Compare{cmp},
+		Success:   []utils.TxnOp{write, read},
+		Failure:   []utils.TxnOp{read},
+	}
+
+	var txnResult utils.TxnResult
+	if err := db.Txn(txn, &txnResult); err != nil {
+		return err
+	}
+
+	// Entrambi i rami contengono la lettura della chiave, quindi una transazione applicata restituisce sempre una risposta
+	if len(txnResult.Responses) == 0 {
+		return fmt.Errorf("conditional write on key %s returned no response", cmp.Key)
+	}
+	result.Succeeded = txnResult.Succeeded
+	result.Key = cmp.Key
+	result.Value = txnResult.Responses[0].Value
+	return nil
+}
+
+// Scan restituisce una pagina delle entry le cui chiavi sono comprese nell'intervallo richiesto.
+// Come la GET, la scansione è ordinata tramite la coda di messaggi, così da osservare lo store nello stesso punto dell'ordine totale.
+func (db *DbSequential) Scan(args utils.ScanArgs, result *utils.ScanResult) error {
+	if _, err := db.Namespaces.get(args.Namespace); err != nil {
+		return err
+	}
+
+	// Crea un canale per ricevere il risultato
+	scanChan := make(chan utils.ScanResult)
+
+	// gestisce la richiesta di Scan
+	go db.handleReadRequest(utils.Message{
+		Op:        utils.SCAN,
+		Scan:      &args,
+		Namespace: args.Namespace,
+		ScanChan:  scanChan,
+	})
+
+	// Aspetta la risposta tramite il canale
+	*result = <-scanChan
+	return nil
+}
+
+// ScanPrefix restituisce una pagina delle entry le cui chiavi hanno il prefisso richiesto
+func (db *DbSequential) ScanPrefix(args utils.ScanArgs, result *utils.ScanResult) error {
+	if args.Prefix == "" {
+		return errors.New("ScanPrefix requires a prefix")
+	}
+	return db.Scan(args, result)
+}
+
+// MultiGet recupera i valori corrispondenti a più chiavi.
+// Le letture sono inserite nella coda come un unico messaggio, quindi osservano lo store nello stesso punto dell'ordine totale.
+func (db *DbSequential) MultiGet(args utils.BatchArgs, result *utils.BatchResult) error {
+	defer db.Sessions.enter(args.Order)()
+
+	if _, err := db.Namespaces.get(args.Namespace); err != nil {
+		return err
+	}
+	errs, valid := args.Validate()
+	result.Errors = errs
+	result.Results = make([]utils.Result, len(args.Entries))
+	if !valid {
+		return nil
+	}
+
+	// Crea un canale per ricevere il risultato
+	batchChan := make(chan []utils.Result)
+
+	// gestisce la richiesta di MultiGet
+	keys := args.BatchEntries(errs)
+	go db.handleReadRequest(utils.Message{
+		Op:        utils.MULTI_GET,
+		Batch:     keys,
+		Namespace: args.Namespace,
+		BatchChan: batchChan,
+	})
+
+	// Aspetta la risposta tramite il canale e la associa agli elementi validi della richiesta
+	results := <-batchChan
+	for i := range args.Entries {
+		if errs[i] == "" {
+			result.Results[i] = results[0]
+			results = results[1:]
+		}
+	}
+	return nil
+}
+
+// MultiPut inserisce o aggiorna più coppie key-value, propagate come un unico messaggio e applicate atomicamente
+func (db *DbSequential) MultiPut(args utils.BatchArgs, result *utils.BatchResult) error {
+	return db.sendBatch(utils.MULTI_PUT, args, result)
+}
+
+// MultiDelete rimuove le entry corrispondenti a più chiavi, propagate come un unico messaggio e applicate atomicamente
+func (db *DbSequential) MultiDelete(args utils.BatchArgs, result *utils.BatchResult) error {
+	return db.sendBatch(utils.MULTI_DELETE, args, result)
+}
+
+// sendBatch propaga le scritture valide della richiesta verso le altre repliche come un unico messaggio
+func (db *DbSequential) sendBatch(op utils.Operation, args utils.BatchArgs, result *utils.BatchResult) error {
+	release := db.Sessions.enter(args.Order)
+	defer release()
+
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	errs, _ := args.ValidateWrites()
+	result.Errors = errs
+	result.Results = make([]utils.Result, len(args.Entries))
+	for i, entry := range args.Entries {
+		result.Results[i] = utils.Result{Key: entry.Key, Value: entry.Value}
+		args.Entries[i] = namespace.withDefaultTTL(entry)
+		if errs[i] == "" && op == utils.MULTI_PUT {
+			if err := checkInlineValue(entry.Key, entry.Value); err != nil {
+				errs[i] = err.Error()
+			}
+		}
+	}
+
+	entries := args.BatchEntries(errs)
+	if len(entries) == 0 {
+		return nil
+	}
+	if op == utils.MULTI_PUT {
+		if err := namespace.admit(entries...); err != nil {
+			return err
+		}
+	}
+	update := db.enqueueRequest(utils.Message{
+		Op:        op,
+		Batch:     entries,
+		Namespace: args.Namespace,
+	})
+	release()
+	db.propagateRequest(update)
+	return nil
+}
+
+// GetAt recupera il valore di una chiave in corrispondenza di una data versione o timestamp di Lamport.
+// La lettura avviene sulla storia delle versioni già applicate dalla replica, che è identica su tutte le repliche grazie all'ordine totale.
+func (db *DbSequential) GetAt(args utils.GetAtArgs, result *utils.Result) error {
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	*result = namespace.Store.getEntryAt(args)
+	return nil
+}
+
+// Watch attende le modifiche applicate dalla replica alla chiave (o alle chiavi con il prefisso) richiesta.
+// Le modifiche sono osservate nell'ordine totale con cui la replica le applica.
+func (db *DbSequential) Watch(args utils.WatchArgs, result *utils.WatchResult) error {
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	watch, err := namespace.Store.Watches.wait(args)
+	if err != nil {
+		return err
+	}
+	*result = watch
+	return nil
+}
+
+// GetPath recupera il valore di un percorso all'interno di un documento JSON.
+// Il documento è letto come con una Get, nella posizione della lettura nell'ordine totale.
+func (db *DbSequential) GetPath(args utils.PathArgs, result *utils.Result) error {
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	if err := checkPathArgs(namespace, args, utils.GET); err != nil {
+		return err
+	}
+	var entry utils.Result
+	if err := db.Get(utils.Args{Namespace: args.Namespace, Key: args.Key}, &entry); err != nil {
+		return err
+	}
+	*result, err = documentPath(entry, args.Path)
+	return err
+}
+
+// SetPath scrive il valore di un percorso all'interno di un documento JSON
+func (db *DbSequential) SetPath(args utils.PathArgs, result *utils.Result) error {
+	return db.sendPathUpdate(utils.SET_PATH, args)
+}
+
+// DeletePath rimuove un percorso all'interno di un documento JSON
+func (db *DbSequential) DeletePath(args utils.PathArgs, result *utils.Result) error {
+	return db.sendPathUpdate(utils.DELETE_PATH, args)
+}
+
+// sendPathUpdate propaga verso le altre repliche la scrittura o la rimozione di un percorso di un documento.
+// L'operazione è propagata come operazione sul percorso, e non come documento intero: ogni replica la applica al documento
+// nella posizione del messaggio nell'ordine totale, quindi le scritture su campi diversi di uno stesso documento non si sovrascrivono.
+// La replica che riceve la richiesta verifica l'operazione e la quota del namespace sullo stato corrente del documento.
+func (db *DbSequential) sendPathUpdate(op utils.Operation, args utils.PathArgs) error {
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	if err := checkPathArgs(namespace, args, op); err != nil {
+		return err
+	}
+	document, err := namespace.Store.previewPath(args.Key, op, args.Path, args.Value)
+	if err != nil {
+		return err
+	}
+	if err := checkValueSize(args.Key, document); err != nil {
+		return err
+	}
+	if err := namespace.admit(utils.BatchEntry{Key: args.Key, Value: document}); err != nil {
+		return err
+	}
+
+	db.sendRequest(utils.Message{
+		Key:       args.Key,
+		Value:     args.Value,
+		Op:        op,
+		Path:      args.Path,
+		Namespace: args.Namespace,
+	})
+	return nil
+}
+
+// CreateNamespace crea un namespace.
+// La creazione è propagata come messaggio ordinato nell'ordine totale, e la richiesta termina quando è stata applicata dalla replica locale:
+// le richieste successive del client alla stessa replica osservano quindi il namespace.
+func (db *DbSequential) CreateNamespace(args utils.NamespaceArgs, result *utils.NamespaceInfo) error {
+	if err := args.Validate(); err != nil {
+		return err
+	}
+	if _, err := NewConflictResolver(args.ConflictPolicy); err != nil {
+		return err
+	}
+	if db.Namespaces.lookup(args.Name) != nil {
+		return fmt.Errorf("namespace %q already exists", args.Name)
+	}
+	if err := db.sendNamespaceRequest(utils.Message{Key: args.Name, Op: utils.CREATE_NAMESPACE, NamespaceConfig: &args}); err != nil {
+		return err
+	}
+	*result = utils.NamespaceInfo{Config: args}
+	return nil
+}
+
+// ListNamespaces restituisce i namespace presenti sulla replica
+func (db *DbSequential) ListNamespaces(args utils.NamespaceArgs, result *utils.NamespaceList) error {
+	result.Namespaces = db.Namespaces.list()
+	return nil
+}
+
+// DropNamespace rimuove un namespace con tutte le sue chiavi.
+// Come la creazione, la rimozione è ordinata nell'ordine totale: le scritture che la seguono nell'ordine vengono scartate da tutte le repliche.
+func (db *DbSequential) DropNamespace(args utils.NamespaceArgs, result *utils.NamespaceInfo) error {
+	if err := args.Validate(); err != nil {
+		return err
+	}
+	namespace, err := db.Namespaces.get(args.Name)
+	if err != nil {
+		return err
+	}
+	if err := db.sendNamespaceRequest(utils.Message{Key: args.Name, Op: utils.DROP_NAMESPACE}); err != nil {
+		return err
+	}
+	*result = utils.NamespaceInfo{Config: namespace.Config}
+	return nil
+}
+
+// CreateIndex crea un indice secondario su un campo dei documenti JSON di un namespace.
+// Come la creazione di un namespace, è ordinata nell'ordine totale: tutte le repliche costruiscono l'indice a partire dallo stesso stato dello store,
+// e lo aggiornano con le stesse scritture che seguono la creazione.
+func (db *DbSequential) CreateIndex(args utils.IndexArgs, result *utils.IndexInfo) error {
+	if err := args.Validate(); err != nil {
+		return err
+	}
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	if _, err := namespace.Store.lookupIndex(args.Name); err == nil {
+		return fmt.Errorf("index %q already exists", args.Name)
+	}
+	if err := db.sendNamespaceRequest(utils.Message{Key: args.Name, Op: utils.CREATE_INDEX, Namespace: args.Namespace, Index: &args}); err != nil {
+		return err
+	}
+	*result, err = namespace.Store.lookupIndex(args.Name)
+	return err
+}
+
+// ListIndexes restituisce gli indici secondari di un namespace presenti sulla replica
+func (db *DbSequential) ListIndexes(args utils.IndexArgs, result *utils.IndexList) error {
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	result.Indexes = namespace.Store.listIndexes()
+	return nil
+}
+
+// DropIndex rimuove un indice secondario, con un messaggio ordinato nell'ordine totale
+func (db *DbSequential) DropIndex(args utils.IndexArgs, result *utils.IndexInfo) error {
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	info, err := namespace.Store.lookupIndex(args.Name)
+	if err != nil {
+		return err
+	}
+	if err := db.sendNamespaceRequest(utils.Message{Key: args.Name, Op: utils.DROP_INDEX, Namespace: args.Namespace}); err != nil {
+		return err
+	}
+	*result = info
+	return nil
+}
+
+// QueryIndex restituisce le chiavi il cui campo indicizzato ha il valore cercato.
+// Come la GET, la ricerca è ordinata tramite la coda di messaggi, così da osservare l'indice nello stesso punto dell'ordine totale.
+func (db *DbSequential) QueryIndex(args utils.QueryArgs, result *utils.QueryResult) error {
+	namespace, err := db.Namespaces.get(args.Namespace)
+	if err != nil {
+		return err
+	}
+	if _, err := namespace.Store.lookupIndex(args.Index); err != nil {
+		return err
+	}
+
+	queryChan := make(chan utils.QueryResult)
+	go db.handleReadRequest(utils.Message{
+		Op:        utils.QUERY_INDEX,
+		Namespace: args.Namespace,
+		Query:     &args,
+		QueryChan: queryChan,
+	})
+	*result = <-queryChan
+	return nil
+}
+
+// sendNamespaceRequest propaga un'operazione di amministrazione dei namespace o dei loro indici e ne attende l'applicazione da parte della replica locale
+func (db *DbSequential) sendNamespaceRequest(msg utils.Message) error {
+	namespaceChan := make(chan error, 1)
+	msg.NamespaceChan = namespaceChan
+	db.sendRequest(msg)
+	return <-namespaceChan
+}
+
+// errCausalOnly è restituito dalle operazioni sui tipi di dato CRDT, che sono replicate tramite multicast causalmente ordinato
+var errCausalOnly = errors.New("operation supported only with CAUSAL consistency")
+
+// Increment non è supportata con consistenza sequenziale
+func (db *DbSequential) Increment(args utils.CounterArgs, result *utils.CounterResult) error {
+	return errCausalOnly
+}
+
+// GetCounter non è supportata con consistenza sequenziale
+func (db *DbSequential) GetCounter(args utils.Args, result *utils.CounterResult) error {
+	return errCausalOnly
+}
+
+// SetAdd non è supportata con consistenza sequenziale
+func (db *DbSequential) SetAdd(args utils.SetArgs, result *utils.SetResult) error {
+	return errCausalOnly
+}
+
+// SetRemove non è supportata con consistenza sequenziale
+func (db *DbSequential) SetRemove(args utils.SetArgs, result *utils.SetResult) error {
+	return errCausalOnly
+}
+
+// SetMembers non è supportata con consistenza sequenziale
+func (db *DbSequential) SetMembers(args utils.Args, result *utils.SetResult) error {
+	return errCausalOnly
+}
+
+// RegisterSet non è supportata con consistenza sequenziale
+func (db *DbSequential) RegisterSet(args utils.Args, result *utils.Result) error {
+	return errCausalOnly
+}
+
+// RegisterGet non è supportata con consistenza sequenziale
+func (db *DbSequential) RegisterGet(args utils.Args, result *utils.Result) error {
+	return errCausalOnly
+}
+
+// MapPut non è supportata con consistenza sequenziale
+func (db *DbSequential) MapPut(args utils.MapArgs, result *utils.MapResult) error {
+	return errCausalOnly
+}
+
+// MapRemove non è supportata con consistenza sequenziale
+func (db *DbSequential) MapRemove(args utils.MapArgs, result *utils.MapResult) error {
+	return errCausalOnly
+}
+
+// MapGet non è supportata con consistenza sequenziale
+func (db *DbSequential) MapGet(args utils.Args, result *utils.MapResult) error {
+	return errCausalOnly
+}
+
 // updateClockOnSend incrementa di 1 il clock scalare
 func (db *DbSequential) updateClockOnSend() {
 	db.Clock.mutex.Lock()
@@ -89,11 +698,11 @@ func (db *DbSequential) updateClockOnReceive(msgClock int) {
 	db.Clock.mutex.Unlock()
 }
 
-// handleGetRequest gestisce la richiesta di GET da parte di un client.
-// Nel caso della GET, a differenza di PUT e DELETE, il server non deve propagare la richiesta alle altre repliche.
-// GET è considerato un evento interno al server.
-// Poiché la GET è un evento interno, e quindi non è un messaggio proveniente da un'altra replica, non può innescare la possibilità di processare un qualche messaggio nella coda.
-func (db *DbSequential) handleGetRequest(key string, responseChan chan string) {
+// handleReadRequest gestisce la richiesta di lettura (GET o SCAN) da parte di un client.
+// Nel caso della lettura, a differenza di PUT e DELETE, il server non deve propagare la richiesta alle altre repliche.
+// La lettura è considerata un evento interno al server.
+// Poiché la lettura è un evento interno, e quindi non è un messaggio proveniente da un'altra replica, non può innescare la possibilità di processare un qualche messaggio nella coda.
+func (db *DbSequential) handleReadRequest(read utils.Message) {
 
 	// Incrementa il clock di 1 anche nel caso di evento interno
 	db.updateClockOnSend()
@@ -101,37 +710,76 @@ func (db *DbSequential) handleGetRequest(key string, responseChan chan string) {
 	// Recupera l' ID del prossimo messaggio
 	nextID := db.getNextMessageID()
 
-	// costruisce un messaggio associato alla richiesta di GET
-	getMessage := utils.Message{
-		MessageID: utils.MessageIdentifier{
-			ID:       nextID,
-			ServerId: db.ID,
-		},
-		Key:          key,
-		Op:           utils.GET,
-		Clock:        db.Clock.value,
-		Type:         utils.REQUEST,
-		ServerID:     db.ID,
-		ResponseChan: responseChan,
+	// completa il messaggio associato alla richiesta di lettura
+	read.MessageID = utils.MessageIdentifier{
+		ID:       nextID,
+		ServerId: db.ID,
 	}
+	read.Clock = db.Clock.value
+	read.Type = utils.REQUEST
+	read.ServerID = db.ID
 
 	// Aggiunge il messaggio alla coda di messaggi, ordinata per clock (e serverID a parità di clock)
-	db.MessageQueue.AddMessage(getMessage)
+	db.MessageQueue.AddMessage(read)
 
-	//Se il messaggio di GET è in testa alla coda può essere immediatamente processato
-	resultMessage := db.MessageQueue.PopGetMessage()
+	//Se il messaggio di lettura è in testa alla coda può essere immediatamente processato
+	resultMessage := db.MessageQueue.PopReadMessage()
 	if resultMessage != nil {
-		// esegue l'operazione di GET richiesta
-		value := db.DbStore.getEntry(resultMessage.Key)
-		// Invia il risultato tramite il canale di risposta
-		if resultMessage.ResponseChan != nil {
-			resultMessage.ResponseChan <- value
+		db.executeRead(*resultMessage)
+	}
+}
+
+// executeRead esegue l'operazione di lettura richiesta e invia il risultato tramite il canale di risposta
+// Se il namespace è stato rimosso prima della lettura, la lettura lo osserva vuoto.
+func (db *DbSequential) executeRead(msg utils.Message) {
+	store := newDbStore()
+	if namespace := db.Namespaces.lookup(msg.Namespace); namespace != nil {
+		store = namespace.Store
+	}
+
+	switch msg.Op {
+	case utils.GET:
+		value := store.getEntry(msg.Key)
+		if msg.ResponseChan != nil {
+			msg.ResponseChan <- value
+		}
+	case utils.SCAN:
+		result := store.scanEntries(*msg.Scan)
+		if msg.ScanChan != nil {
+			msg.ScanChan <- result
+		}
+	case utils.MULTI_GET:
+		results := store.getEntries(msg.Batch)
+		if msg.BatchChan != nil {
+			msg.BatchChan <- results
+		}
+	case utils.QUERY_INDEX:
+		result := store.queryIndex(*msg.Query)
+		if msg.QueryChan != nil {
+			msg.QueryChan <- result
 		}
 	}
 }
 
-// sendUpdate propaga la richiesta di update (PUT o DELETE) verso gli altri processi
-func (db *DbSequential) sendUpdate(op utils.Operation, key string, value string) {
+// expire richiede la rimozione di una versione scaduta di una chiave.
+// La rimozione è propagata come messaggio ordinato nell'ordine totale, e viene applicata solo se la chiave non è stata riscritta nel frattempo.
+func (db *DbSequential) expire(namespace string, key string, version utils.Version) {
+	db.sendRequest(utils.Message{
+		Key:       key,
+		Op:        utils.EXPIRE,
+		Expired:   &version,
+		Namespace: namespace,
+	})
+}
+
+// sendRequest completa il messaggio di REQUEST con identificatore e clock, e lo propaga verso gli altri processi
+func (db *DbSequential) sendRequest(update utils.Message) {
+	db.propagateRequest(db.enqueueRequest(update))
+}
+
+// enqueueRequest completa il messaggio di REQUEST con identificatore, clock e numero di sequenza, e lo aggiunge alla coda di messaggi.
+// Da questo momento la posizione del messaggio nell'ordine totale è fissata, e le altre repliche lo riceveranno prima dei messaggi inviati successivamente.
+func (db *DbSequential) enqueueRequest(update utils.Message) utils.Message {
 
 	// Incrementa il clock di 1
 	db.updateClockOnSend()
@@ -139,28 +787,28 @@ func (db *DbSequential) sendUpdate(op utils.Operation, key string, value string)
 	// Recupera l' ID del prossimo messaggio
 	nextID := db.getNextMessageID()
 
-	// costruisce un messaggio associato alla richiesta di update
-	update := utils.Message{
-		MessageID: utils.MessageIdentifier{
-			ID:       nextID,
-			ServerId: db.ID,
-		},
-		Key:      key,
-		Value:    value,
-		Op:       op,
-		Clock:    db.Clock.value,
-		Type:     utils.REQUEST,
-		ServerID: db.ID,
+	update.MessageID = utils.MessageIdentifier{
+		ID:       nextID,
+		ServerId: db.ID,
 	}
+	update.Clock = db.Clock.value
+	update.Type = utils.REQUEST
+	update.ServerID = db.ID
+	update.SeqNum = db.NextSeqNum.getNextSeqNum()
 
 	// Aggiunge il messaggio alla coda di messaggi, ordinata per clock (e serverID a parità di clock)
 	// A livello concettuale il sender invia il messaggio a se stesso
 	db.MessageQueue.AddMessage(update)
+	return update
+}
+
+// propagateRequest invia il messaggio di REQUEST, già presente nella coda di messaggi, verso gli altri processi
+func (db *DbSequential) propagateRequest(update utils.Message) {
 
 	// Invia il messaggio alle altre repliche, simulando un ritardo di comunicazione
 	// A livello concettuale è come se il sender inviasse il messaggio anche a se stesso
 	// Nella pratica il sender non realizza l'invio del messaggio perché già lo possiede
-	db.sendMessage(update)
+	db.transmit(update)
 
 	// Poiché a livello concettuale il sender invia il messaggio anche a se stesso, anche lui invia l' ACK a tutte le altre repliche
 	db.sendAck(update)
@@ -178,7 +826,7 @@ func (db *DbSequential) sendAck(msg utils.Message) {
 			ServerId: msg.MessageID.ServerId,
 		},
 		Key:      "",
-		Value:    "",
+		Value:    nil,
 		Op:       "",
 		Clock:    db.Clock.value,
 		Type:     utils.ACK,
@@ -193,10 +841,14 @@ func (db *DbSequential) sendAck(msg utils.Message) {
 func (db *DbSequential) sendMessage(msg utils.Message) {
 	// Assegna un numero di sequenza al messaggio da inviare
 	// In questo modo il receiver può processare i messaggi da questo sender nello stesso ordine di invio
-	// Il ritardo nella comunicazione è simulato inviando i messaggi allo scadere di un timer casuale
 	seqNum := db.NextSeqNum.getNextSeqNum()
 	msg.SeqNum = seqNum
+	db.transmit(msg)
+}
 
+// transmit invia alle altre repliche un messaggio a cui è già stato assegnato il numero di sequenza.
+// Il ritardo nella comunicazione è simulato inviando i messaggi allo scadere di un timer casuale
+func (db *DbSequential) transmit(msg utils.Message) {
 	var wg sync.WaitGroup
 
 	for _, address := range db.Addresses {
@@ -210,8 +862,8 @@ func (db *DbSequential) sendMessage(msg utils.Message) {
 				log.Fatal("Error in dialing: ", err)
 			}
 
-			// Codifica il messaggio in json e lo invia al server
-			encoder := json.NewEncoder(conn)
+			// Codifica il messaggio con gob, che trasmette i valori binari senza espanderli, e lo invia al server
+			encoder := gob.NewEncoder(conn)
 			err = encoder.Encode(msg)
 			if err != nil {
 				log.Fatal("Error while coding message : ", err)
@@ -273,8 +925,8 @@ func (db *DbSequential) handleConnection(conn net.Conn) {
 		}
 	}(conn)
 
-	// Decodifica il messaggio JSON ricevuto
-	decoder := json.NewDecoder(conn)
+	// Decodifica il messaggio gob ricevuto
+	decoder := gob.NewDecoder(conn)
 	var msg utils.Message
 	err := decoder.Decode(&msg)
 	if err != nil {
@@ -284,6 +936,7 @@ func (db *DbSequential) handleConnection(conn net.Conn) {
 
 	seqNum := msg.SeqNum
 	idSender := msg.ServerID
+	db.Activity.received(idSender)
 
 	// Controlla se il messaggio ricevuto dal server idSender ha il numero di sequenza atteso
 	// se ha il numero di sequenza atteso il messaggio può essere ricevuto
@@ -312,6 +965,12 @@ func (db *DbSequential) handleConnection(conn net.Conn) {
 
 // receive gestisce la ricezione di messaggi dalle altre repliche (che possono essere REQUEST o ACK)
 func (db *DbSequential) receive(msg utils.Message) {
+	// Un frammento di un valore non entra nella coda di messaggi, ma viene copiato direttamente nel valore a cui appartiene
+	if msg.Op == utils.CHUNK {
+		db.Blobs.addChunk(*msg.Chunk)
+		return
+	}
+
 	// aggiorna il clock sulla ricezione
 	db.updateClockOnReceive(msg.Clock)
 
@@ -325,41 +984,126 @@ func (db *DbSequential) receive(msg utils.Message) {
 		db.MessageQueue.AddMessage(msg)
 	}
 
-	// controlla se l'arrivo di questo messaggio permette di processare il messaggio in testa alla coda
-	resultMessage := db.MessageQueue.PopMessage(db.ID, NumReplicas)
-	if resultMessage != nil {
-		// Dopo aver estratto il messaggio provvede a eliminare tutti gli ACK associati dalla coda (non presenti se il messaggio è una GET)
-		if resultMessage.Op != utils.GET {
+	// controlla se l'arrivo di questo messaggio permette di processare il messaggio in testa alla coda.
+	// Con più messaggi in coda, come accade quando un client invia più richieste senza attenderne la risposta,
+	// uno stesso messaggio può sbloccare più messaggi consecutivi, che vengono processati tutti.
+	for {
+		// Una snapshot di backup non può essere presa tra l'estrazione di un messaggio dalla coda e la sua applicazione,
+		// così che includa sempre un prefisso dell'ordine totale delle scritture
+		db.Snapshots.enter()
+		resultMessage := db.MessageQueue.PopMessage(db.ID, NumReplicas)
+		if resultMessage == nil {
+			// Controlla che in coda ci sia un messaggio di lettura locale come messaggio successivo che può essere processato
+			// Essendo un evento interno al processo se è in testa alla coda sono sicuro che tutti gli eventi precedenti in ordine di programma sono stati eseguiti (perché lo precedevano nella coda)
+			// Questo permette di garantire che la lettura venga processata anche quando non c'è un messaggio di REQUEST o ACK successivo
+			resultMessage = db.MessageQueue.PopReadMessage()
+		}
+		if resultMessage == nil {
+			db.Snapshots.exit()
+			break
+		}
+
+		// Dopo aver estratto il messaggio provvede a eliminare tutti gli ACK associati dalla coda (non presenti se il messaggio è una lettura)
+		if !resultMessage.Op.IsRead() {
 			db.MessageQueue.DeleteAck(resultMessage.MessageID.ID, resultMessage.MessageID.ServerId)
 		}
 		switch resultMessage.Op {
-		case utils.GET:
-			value := db.DbStore.getEntry(resultMessage.Key)
-			// Invia il risultato tramite il canale di risposta
-			if resultMessage.ResponseChan != nil {
-				resultMessage.ResponseChan <- value
-			}
-		case utils.PUT:
-			db.DbStore.putEntry(resultMessage.Key, resultMessage.Value)
-		case utils.DELETE:
-			db.DbStore.deleteEntry(resultMessage.Key)
+		case utils.GET, utils.SCAN, utils.MULTI_GET, utils.QUERY_INDEX:
+			db.executeRead(*resultMessage)
+		case utils.CREATE_NAMESPACE, utils.DROP_NAMESPACE, utils.CREATE_INDEX, utils.DROP_INDEX:
+			db.applyNamespaceOp(*resultMessage)
+		default:
+			db.applyUpdate(*resultMessage)
 		}
+		if !resultMessage.Op.IsRead() {
+			db.Snapshots.advance(resultMessage.Version())
+		}
+		db.Snapshots.exit()
 	}
+}
 
-	// Controlla che in coda ci sia un messaggio di GET locale come messaggio successivo che può essere processato
-	// Essendo un evento interno al processo se è in testa alla coda sono sicuro che tutti gli eventi precedenti in ordine di programma sono stati eseguiti (perché lo precedevano nella coda)
-	// Questo permette di garantire che la GET venga processata anche quando non c'è un messaggio di REQUEST o ACK successivo
-	resultMessage = db.MessageQueue.PopGetMessage()
-	for resultMessage != nil {
-		// esegue l'operazione di GET richiesta
-		value := db.DbStore.getEntry(resultMessage.Key)
-		// Invia il risultato tramite il canale di risposta
-		if resultMessage.ResponseChan != nil {
-			resultMessage.ResponseChan <- value
+// applyUpdate applica allo store del namespace indicato dal messaggio la scrittura (o la transazione) che esso trasporta.
+// Se il namespace è stato rimosso prima della scrittura, la scrittura viene scartata.
+func (db *DbSequential) applyUpdate(msg utils.Message) {
+	// Recupera il valore trasferito a frammenti
+	if msg.BlobID != "" {
+		value, err := db.Blobs.take(msg.BlobID)
+		if err != nil {
+			log.Printf("%s key %s skipped: %v", msg.Op, msg.Key, err)
+			return
 		}
-		resultMessage = db.MessageQueue.PopGetMessage()
+		msg.Value = value
 	}
 
+	namespace := db.Namespaces.lookup(msg.Namespace)
+	if namespace == nil {
+		fmt.Printf("%s key %s skipped, namespace %s does not exist\n", msg.Op, msg.Key, msg.Namespace)
+		// Solo la replica che ha originato la transazione (o la SET_TTL) possiede il canale su cui restituire l'esito
+		if msg.TxnChan != nil {
+			msg.TxnChan <- utils.TxnOutcome{Err: fmt.Errorf("namespace %q does not exist", msg.Namespace)}
+		}
+		return
+	}
+	store := namespace.Store
+
+	switch msg.Op {
+	case utils.PUT:
+		store.putEntry(msg.Key, msg.Value, msg.Version(), msg.ExpiresAt)
+		db.CDC.record(sequentialChanges(msg, true)...)
+	case utils.DELETE:
+		store.deleteEntry(msg.Key, msg.Version())
+		db.CDC.record(sequentialChanges(msg, true)...)
+	case utils.MULTI_PUT, utils.MULTI_DELETE:
+		store.applyBatch(msg.Op, msg.Batch, msg.Version())
+		db.CDC.record(sequentialChanges(msg, true)...)
+	case utils.SET_PATH, utils.DELETE_PATH:
+		if store.applyPath(msg.Op, msg.Key, msg.Path, msg.Value, msg.Version()) {
+			db.CDC.record(sequentialChanges(msg, true)...)
+		}
+	case utils.EXPIRE:
+		if store.expireEntry(msg.Key, *msg.Expired, msg.Version()) {
+			db.CDC.record(sequentialChanges(msg, true)...)
+		}
+	case utils.SET_TTL:
+		applied := store.setExpiry(msg.Key, msg.ExpiresAt)
+		if applied {
+			db.CDC.record(sequentialChanges(msg, true)...)
+		}
+		// Solo la replica che ha originato l'aggiornamento possiede il canale su cui restituire l'esito
+		if msg.TxnChan != nil {
+			msg.TxnChan <- utils.TxnOutcome{Result: utils.TxnResult{Succeeded: applied}}
+		}
+	case utils.TXN:
+		txnResult := store.applyTxn(*msg.Txn, msg.Version())
+		db.CDC.record(sequentialChanges(msg, txnResult.Succeeded)...)
+		// Solo la replica che ha originato la transazione possiede il canale su cui restituire l'esito
+		if msg.TxnChan != nil {
+			msg.TxnChan <- utils.TxnOutcome{Result: txnResult}
+		}
+	}
+}
+
+// applyNamespaceOp crea o rimuove il namespace (o l'indice secondario) indicato dal messaggio.
+// Tutte le repliche applicano le operazioni sui namespace nello stesso ordine, quindi concordano sul loro esito.
+func (db *DbSequential) applyNamespaceOp(msg utils.Message) {
+	var err error
+	switch msg.Op {
+	case utils.CREATE_NAMESPACE:
+		err = db.Namespaces.create(*msg.NamespaceConfig, msg.Version())
+	case utils.DROP_NAMESPACE:
+		err = db.Namespaces.drop(msg.Key)
+	case utils.CREATE_INDEX, utils.DROP_INDEX:
+		err = db.Namespaces.indexOp(msg.Namespace, msg.Op, msg.Key, msg.Index, msg.Version())
+	}
+	if err != nil {
+		fmt.Printf("%s %s skipped: %v\n", msg.Op, msg.Key, err)
+	} else {
+		db.CDC.record(sequentialChanges(msg, true)...)
+	}
+	// Solo la replica che ha originato l'operazione possiede il canale su cui restituire l'esito
+	if msg.NamespaceChan != nil {
+		msg.NamespaceChan <- err
+	}
 }
 
 func simulateDelay() {
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"dbService/utils"
+	"strings"
+	"testing"
+)
+
+func TestCheckTxnWrites(t *testing.T) {
+	maxValueSize, chunkSize := MaxValueSize, ChunkSize
+	MaxValueSize, ChunkSize = 16, 8
+	t.Cleanup(func() {
+		MaxValueSize, ChunkSize = maxValueSize, chunkSize
+	})
+
+	compare := []utils.Compare{{Key: "k", Op: utils.ABSENT}}
+	put := func(key string, value string) utils.TxnOp {
+		return utils.TxnOp{Op: utils.PUT, Key: key, Value: []byte(value)}
+	}
+	tests := []struct {
+		name    string
+		config  utils.NamespaceArgs
+		success []utils.TxnOp
+		failure []utils.TxnOp
+		wantErr string
+	}{
+		{
+			name:    "writes within the limits",
+			config:  utils.NamespaceArgs{Name: "ns", Quota: 8},
+			success: []utils.TxnOp{put("a", "1234567")},
+			failure: []utils.TxnOp{put("b", "1234567"), {Op: utils.GET, Key: "a"}},
+		},
+		{
+			name:    "success value larger than the chunk size",
+			config:  utils.NamespaceArgs{Name: "ns"},
+			success: []utils.TxnOp{put("a", "123456789")},
+			wantErr: "must be written with Put",
+		},
+		{
+			name:    "failure value larger than the chunk size",
+			config:  utils.NamespaceArgs{Name: "ns"},
+			failure: []utils.TxnOp{put("a", "123456789")},
+			wantErr: "must be written with Put",
+		},
+		{
+			name:    "failure value larger than the maximum size",
+			config:  utils.NamespaceArgs{Name: "ns"},
+			failure: []utils.TxnOp{put("a", strings.Repeat("x", 17))},
+			wantErr: "maximum value size",
+		},
+		{
+			name:    "failure writes over the quota",
+			config:  utils.NamespaceArgs{Name: "ns", Quota: 8},
+			failure: []utils.TxnOp{put("a", "1234"), put("b", "1234")},
+			wantErr: "quota exceeded",
+		},
+		{
+			name:    "failure value not a JSON document",
+			config:  utils.NamespaceArgs{Name: "ns", Documents: true},
+			success: []utils.TxnOp{put("a", `{"a":1}`)},
+			failure: []utils.TxnOp{put("a", "{")},
+			wantErr: "not a valid JSON document",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			namespace := &Namespace{Config: test.config, Store: newDbStore()}
+			args := utils.TxnArgs{Namespace: test.config.Name, Compare: compare, Success: test.success, Failure: test.failure}
+			err := checkTxnWrites(namespace, args)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("checkTxnWrites() error = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("checkTxnWrites() error = %v, want an error containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
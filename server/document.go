@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bytes"
+	"dbService/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parsePointer scompone un JSON pointer (RFC 6901) nei suoi token, sostituendo le sequenze di escape ~1 e ~0.
+// Il pointer vuoto indica l'intero documento.
+func parsePointer(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q: it must be empty or start with /", path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// decodeJSON decodifica un valore JSON, mantenendo i numeri nella loro rappresentazione originale
+func decodeJSON(value []byte) (any, error) {
+	if !json.Valid(value) {
+		return nil, errors.New("the value is not valid JSON")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+	var decoded any
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// encodeJSON codifica un valore JSON. I campi degli oggetti sono ordinati, così che tutte le repliche producano lo stesso documento.
+func encodeJSON(value any) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+}
+
+// arrayIndex converte un token del pointer nell'indice di un elemento esistente di un array di lunghezza indicata
+func arrayIndex(token string, length int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index >= length || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return index, nil
+}
+
+// lookupPath restituisce il valore indicato dai token all'interno del nodo, e false se il percorso non esiste
+func lookupPath(node any, tokens []string) (any, bool) {
+	for _, token := range tokens {
+		switch container := node.(type) {
+		case map[string]any:
+			child, exist := container[token]
+			if !exist {
+				return nil, false
+			}
+			node = child
+		case []any:
+			index, err := arrayIndex(token, len(container))
+			if err != nil {
+				return nil, false
+			}
+			node = container[index]
+		default:
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// setPath scrive il valore nel percorso indicato dai token e restituisce il nodo aggiornato.
+// I livelli intermedi mancanti sono creati come oggetti, e il token "-" aggiunge il valore in coda a un array.
+func setPath(node any, tokens []string, value any) (any, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	token := tokens[0]
+	switch container := node.(type) {
+	case nil:
+		child, err := setPath(nil, tokens[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{token: child}, nil
+	case map[string]any:
+		child, err := setPath(container[token], tokens[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		container[token] = child
+		return container, nil
+	case []any:
+		if token == "-" && len(tokens) == 1 {
+			return append(container, value), nil
+		}
+		index, err := arrayIndex(token, len(container))
+		if err != nil {
+			return nil, err
+		}
+		child, err := setPath(container[index], tokens[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		container[index] = child
+		return container, nil
+	default:
+		return nil, fmt.Errorf("%q is not an object or an array", token)
+	}
+}
+
+// deletePath rimuove il valore nel percorso indicato dai token e restituisce il nodo aggiornato, e false se il percorso non esiste
+func deletePath(node any, tokens []string) (any, bool) {
+	token := tokens[0]
+	switch container := node.(type) {
+	case map[string]any:
+		child, exist := container[token]
+		if !exist {
+			return node, false
+		}
+		if len(tokens) == 1 {
+			delete(container, token)
+			return container, true
+		}
+		child, deleted := deletePath(child, tokens[1:])
+		container[token] = child
+		return container, deleted
+	case []any:
+		index, err := arrayIndex(token, len(container))
+		if err != nil {
+			return node, false
+		}
+		if len(tokens) == 1 {
+			return append(container[:index], container[index+1:]...), true
+		}
+		child, deleted := deletePath(container[index], tokens[1:])
+		container[index] = child
+		return container, deleted
+	default:
+		return node, false
+	}
+}
+
+// updateDocument applica a un documento JSON la scrittura (SET_PATH) o la rimozione (DELETE_PATH) di un percorso, e restituisce il documento risultante.
+// Restituisce false se la rimozione non trova il percorso, e quindi il documento non cambia.
+func updateDocument(document []byte, op utils.Operation, path string, value []byte) ([]byte, bool, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, false, err
+	}
+	root, err := decodeJSON(document)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if op == utils.DELETE_PATH {
+		if len(tokens) == 0 {
+			return nil, false, errors.New("the whole document can not be removed with DeletePath, use Delete")
+		}
+		var deleted bool
+		if root, deleted = deletePath(root, tokens); !deleted {
+			return document, false, nil
+		}
+	} else {
+		decoded, err := decodeJSON(value)
+		if err != nil {
+			return nil, false, err
+		}
+		if root, err = setPath(root, tokens, decoded); err != nil {
+			return nil, false, fmt.Errorf("path %s can not be set: %v", path, err)
+		}
+	}
+
+	updated, err := encodeJSON(root)
+	if err != nil {
+		return nil, false, err
+	}
+	return updated, true, nil
+}
+
+// documentPath restituisce il risultato di una lettura in cui il valore è sostituito da quello del percorso indicato all'interno del documento.
+// Se la chiave o il percorso non esistono il valore restituito è NOT FOUND.
+func documentPath(entry utils.Result, path string) (utils.Result, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return utils.Result{}, err
+	}
+	if string(entry.Value) == "NOT FOUND" {
+		return entry, nil
+	}
+	root, err := decodeJSON(entry.Value)
+	if err != nil {
+		return utils.Result{}, fmt.Errorf("value of key %s is not a JSON document: %v", entry.Key, err)
+	}
+
+	result := utils.Result{Key: entry.Key, Version: entry.Version, Context: entry.Context}
+	value, exist := lookupPath(root, tokens)
+	if !exist {
+		result.Value = []byte("NOT FOUND")
+		return result, nil
+	}
+	if result.Value, err = encodeJSON(value); err != nil {
+		return utils.Result{}, err
+	}
+	return result, nil
+}
+
+// checkPathArgs verifica che il namespace sia in modalità documento, che il percorso sia un JSON pointer valido e che il valore da scrivere sia JSON valido
+func checkPathArgs(namespace *Namespace, args utils.PathArgs, op utils.Operation) error {
+	if !namespace.Config.Documents {
+		return fmt.Errorf("namespace %q is not in document mode", namespace.Config.Name)
+	}
+	if _, err := parsePointer(args.Path); err != nil {
+		return err
+	}
+	if op == utils.SET_PATH {
+		if err := checkInlineValue(args.Key, args.Value); err != nil {
+			return err
+		}
+		if !json.Valid(args.Value) {
+			return fmt.Errorf("value of path %s is not valid JSON", args.Path)
+		}
+	}
+	if op == utils.DELETE_PATH && args.Path == "" {
+		return errors.New("the whole document can not be removed with DeletePath, use Delete")
+	}
+	return nil
+}
+
+// previewPath restituisce il documento che la scrittura o la rimozione di un percorso produrrebbe sullo stato corrente della chiave.
+// Permette alla replica che riceve la richiesta di rifiutare le operazioni non applicabili e di verificare la quota del namespace prima di propagarle.
+func (db *DbStore) previewPath(key string, op utils.Operation, path string, value []byte) ([]byte, error) {
+	db.mutex.Lock()
+	entry, exist := db.Store[key]
+	db.mutex.Unlock()
+	if !exist {
+		return nil, fmt.Errorf("key %s does not exist, documents are created with Put", key)
+	}
+	document, _, err := updateDocument(entry.Value, op, path, value)
+	return document, err
+}
+
+// applyPath applica con consistenza sequenziale la scrittura o la rimozione di un percorso di un documento.
+// L'operazione assume la versione del messaggio che la trasporta e mantiene l'istante di scadenza del documento.
+// Tutte le repliche applicano le operazioni nello stesso ordine, quindi producono lo stesso documento. Restituisce true se il documento è cambiato.
+func (db *DbStore) applyPath(op utils.Operation, key string, path string, value []byte, version utils.Version) bool {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	entry, exist := db.Store[key]
+	if !exist {
+		fmt.Printf("%s key %s path %s skipped, the key does not exist\n", op, key, path)
+		return false
+	}
+	document, changed, err := updateDocument(entry.Value, op, path, value)
+	if err != nil {
+		fmt.Printf("%s key %s path %s skipped: %v\n", op, key, path, err)
+		return false
+	}
+	if !changed {
+		fmt.Printf("%s key %s path %s skipped, the path does not exist\n", op, key, path)
+		return false
+	}
+	db.setValue(key, utils.VersionedValue{Value: document, Version: version, ExpiresAt: entry.ExpiresAt})
+	fmt.Printf("%s key %s path %s value %s version %s\n", op, key, path, value, version)
+	return true
+}
+
+// containsPath indica se il percorso JSON pointer path coincide con ancestor o è contenuto nel sottoalbero di ancestor
+func containsPath(ancestor string, path string) bool {
+	return path == ancestor || strings.HasPrefix(path, ancestor+"/")
+}
+
+// mergePath applica con consistenza causale la scrittura o la rimozione di un percorso di un documento.
+// L'operazione modifica solo le versioni del documento che precedono causalmente l'operazione: una Put concorrente prevale sulle operazioni sui percorsi.
+// Le versioni non cambiano, così che operazioni concorrenti su percorsi diversi siano applicate entrambe, in qualsiasi ordine.
+// Tra operazioni concorrenti sullo stesso percorso prevale quella con timestamp ibrido maggiore, che segue sempre le operazioni che la precedono causalmente.
+// Tra operazioni concorrenti su percorsi di cui uno contiene l'altro prevale invece quella sul percorso più esterno, che sostituisce o rimuove l'intero sottoalbero:
+// un'operazione ricevuta dopo una concorrente sul percorso che la contiene è scartata, così che il documento non dipenda dall'ordine di consegna.
+// Se il documento cambia viene invocata onApply, mantenendo il lock sullo store.
+func (db *DbStore) mergePath(op utils.Operation, key string, path string, value []byte, version utils.Version, onApply func()) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	for recorded, last := range db.Paths[key] {
+		// Le operazioni che precedono causalmente questa sono già incluse nelle versioni che essa modifica
+		if last.LessOrEqual(version) || !containsPath(recorded, path) {
+			continue
+		}
+		if recorded != path {
+			fmt.Printf("%s key %s path %s skipped, superseded by concurrent operation on path %s version %s\n", op, key, path, recorded, last)
+			return
+		}
+		winner := LastWriterWins{}.Resolve(utils.VersionedValue{Version: last}, utils.VersionedValue{Version: version})
+		if winner.Version.ServerID == last.ServerID && winner.Version.Timestamp == last.Timestamp {
+			fmt.Printf("%s key %s path %s skipped, superseded by version %s\n", op, key, path, last)
+			return
+		}
+	}
+	if db.Paths[key] == nil {
+		db.Paths[key] = make(map[string]utils.Version)
+	}
+	// Le operazioni sui percorsi contenuti sono superate da questa, e non devono più scartare le operazioni concorrenti ricevute in seguito
+	for recorded := range db.Paths[key] {
+		if recorded != path && containsPath(path, recorded) {
+			delete(db.Paths[key], recorded)
+		}
+	}
+	db.Paths[key][path] = version
+
+	current := db.Siblings[key]
+	if stored, exist := db.Store[key]; exist && current == nil {
+		current = []utils.VersionedValue{stored}
+	}
+	changed := false
+	for i, sibling := range current {
+		if !sibling.Version.LessOrEqual(version) {
+			continue
+		}
+		document, updated, err := updateDocument(sibling.Value, op, path, value)
+		if err != nil {
+			fmt.Printf("%s key %s path %s on version %s skipped: %v\n", op, key, path, sibling.Version, err)
+			continue
+		}
+		if updated {
+			current[i].Value = document
+			changed = true
+		}
+	}
+	if !changed {
+		fmt.Printf("%s key %s path %s skipped, no version of the document was changed\n", op, key, path)
+		return
+	}
+
+	onApply()
+	// Il valore corrente è la versione originata dalla replica con ID maggiore, come per le versioni concorrenti
+	db.storeEntry(key, current[len(current)-1], version)
+	db.appendHistory(key, current[len(current)-1])
+	fmt.Printf("%s key %s path %s value %s version %s\n", op, key, path, value, version)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePointer(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "whole document", path: "", want: nil},
+		{name: "root field", path: "/a", want: []string{"a"}},
+		{name: "nested field", path: "/a/b/0", want: []string{"a", "b", "0"}},
+		{name: "empty field", path: "/", want: []string{""}},
+		{name: "escaped slash", path: "/a~1b", want: []string{"a/b"}},
+		{name: "escaped tilde", path: "/a~0b", want: []string{"a~b"}},
+		{name: "escaped tilde before 1", path: "/~01", want: []string{"~1"}},
+		{name: "missing leading slash", path: "a/b", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokens, err := parsePointer(test.path)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("parsePointer(%q) error = %v, want error %v", test.path, err, test.wantErr)
+			}
+			if !reflect.DeepEqual(tokens, test.want) {
+				t.Fatalf("parsePointer(%q) = %q, want %q", test.path, tokens, test.want)
+			}
+		})
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		path     string
+		value    string
+		want     string
+		wantErr  bool
+	}{
+		{name: "whole document", document: `{"a":1}`, path: "", value: `[1,2]`, want: `[1,2]`},
+		{name: "new field", document: `{"a":1}`, path: "/b", value: `"x"`, want: `{"a":1,"b":"x"}`},
+		{name: "existing field", document: `{"a":1}`, path: "/a", value: `{"c":true}`, want: `{"a":{"c":true}}`},
+		{name: "missing intermediate objects", document: `{}`, path: "/a/b/c", value: `1.50`, want: `{"a":{"b":{"c":1.50}}}`},
+		{name: "escaped field", document: `{}`, path: "/a~1b", value: `null`, want: `{"a/b":null}`},
+		{name: "array element", document: `{"a":[1,2,3]}`, path: "/a/1", value: `"x"`, want: `{"a":[1,"x",3]}`},
+		{name: "array append", document: `{"a":[1]}`, path: "/a/-", value: `2`, want: `{"a":[1,2]}`},
+		{name: "field of an array element", document: `[{"a":1}]`, path: "/0/b", value: `2`, want: `[{"a":1,"b":2}]`},
+		{name: "array index out of range", document: `{"a":[1]}`, path: "/a/1", value: `2`, wantErr: true},
+		{name: "array index with leading zero", document: `{"a":[1,2]}`, path: "/a/01", value: `2`, wantErr: true},
+		{name: "array index not a number", document: `{"a":[1]}`, path: "/a/b", value: `2`, wantErr: true},
+		{name: "field of a scalar", document: `{"a":1}`, path: "/a/b", value: `2`, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root, err := decodeJSON([]byte(test.document))
+			if err != nil {
+				t.Fatal(err)
+			}
+			value, err := decodeJSON([]byte(test.value))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tokens, err := parsePointer(test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			root, err = setPath(root, tokens, value)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("setPath(%s, %q) error = %v, want error %v", test.document, test.path, err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			updated, err := encodeJSON(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(updated) != test.want {
+				t.Fatalf("setPath(%s, %q) = %s, want %s", test.document, test.path, updated, test.want)
+			}
+		})
+	}
+}
+
+func TestDeletePath(t *testing.T) {
+	tests := []struct {
+		name        string
+		document    string
+		path        string
+		want        string
+		wantDeleted bool
+	}{
+		{name: "root field", document: `{"a":1,"b":2}`, path: "/a", want: `{"b":2}`, wantDeleted: true},
+		{name: "nested field", document: `{"a":{"b":1,"c":2}}`, path: "/a/b", want: `{"a":{"c":2}}`, wantDeleted: true},
+		{name: "array element", document: `{"a":[1,2,3]}`, path: "/a/1", want: `{"a":[1,3]}`, wantDeleted: true},
+		{name: "field of an array element", document: `[{"a":1,"b":2}]`, path: "/0/a", want: `[{"b":2}]`, wantDeleted: true},
+		{name: "escaped field", document: `{"a~b":1}`, path: "/a~0b", want: `{}`, wantDeleted: true},
+		{name: "missing field", document: `{"a":1}`, path: "/b", want: `{"a":1}`},
+		{name: "missing nested field", document: `{"a":{}}`, path: "/a/b/c", want: `{"a":{}}`},
+		{name: "array index out of range", document: `{"a":[1]}`, path: "/a/1", want: `{"a":[1]}`},
+		{name: "array append token", document: `{"a":[1]}`, path: "/a/-", want: `{"a":[1]}`},
+		{name: "field of a scalar", document: `{"a":1}`, path: "/a/b", want: `{"a":1}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root, err := decodeJSON([]byte(test.document))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tokens, err := parsePointer(test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			root, deleted := deletePath(root, tokens)
+			if deleted != test.wantDeleted {
+				t.Fatalf("deletePath(%s, %q) deleted = %v, want %v", test.document, test.path, deleted, test.wantDeleted)
+			}
+			updated, err := encodeJSON(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(updated) != test.want {
+				t.Fatalf("deletePath(%s, %q) = %s, want %s", test.document, test.path, updated, test.want)
+			}
+		})
+	}
+}
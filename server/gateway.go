@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"net/http"
+	"strings"
+)
+
+// Funzioni comuni ai gateway HTTP/JSON e gRPC, che traducono le proprie richieste nelle operazioni del DataStore
+
+// apiErrors associa i messaggi d'errore del DataStore ai codici di stato HTTP e gRPC corrispondenti, restituiti dai gateway.
+// Vale la prima corrispondenza trovata, gli altri errori sono dovuti a una richiesta non valida.
+var apiErrors = []struct {
+	contains string
+	status   int
+	code     codes.Code
+}{
+	{"does not exist", http.StatusNotFound, codes.NotFound},
+	{"already exists", http.StatusConflict, codes.AlreadyExists},
+	{"quota exceeded", http.StatusInsufficientStorage, codes.ResourceExhausted},
+	{"maximum value size", http.StatusRequestEntityTooLarge, codes.InvalidArgument},
+	{"must be written with Put", http.StatusRequestEntityTooLarge, codes.InvalidArgument},
+	{"operation supported only with", http.StatusNotImplemented, codes.Unimplemented},
+	{"not delivered by replica", http.StatusServiceUnavailable, codes.Unavailable},
+}
+
+// classifyError restituisce i codici di stato HTTP e gRPC corrispondenti all'errore di un'operazione del DataStore
+func classifyError(err error) (int, codes.Code) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout, codes.DeadlineExceeded
+	}
+	if errors.Is(err, context.Canceled) {
+		return http.StatusGatewayTimeout, codes.Canceled
+	}
+	for _, apiErr := range apiErrors {
+		if strings.Contains(err.Error(), apiErr.contains) {
+			return apiErr.status, apiErr.code
+		}
+	}
+	return http.StatusBadRequest, codes.InvalidArgument
+}
+
+// invokeContext esegue un'operazione del DataStore e ne attende il termine, oppure la cancellazione del context della richiesta.
+// L'operazione non viene interrotta, ma il suo esito non è più restituito al client.
+func invokeContext(ctx context.Context, operation func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- operation()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"dbService/datastorepb"
+	"dbService/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log"
+	"net"
+	"time"
+)
+
+// grpcWatchPoll è il tempo massimo di attesa di ogni richiesta di Watch con cui il server gRPC alimenta uno stream.
+// Allo scadere l'osservazione riprende dalla revisione raggiunta, così che uno stream chiuso dal client non lasci attese pendenti a lungo.
+const grpcWatchPoll = 5 * time.Second
+
+// GRPCServer espone le operazioni del DataStore tramite il servizio gRPC definito in datastorepb.
+// Ogni richiesta gRPC è tradotta nella chiamata al metodo corrispondente del DataStore, con le stesse garanzie di consistenza.
+type GRPCServer struct {
+	datastorepb.UnimplementedDatastoreServer
+	dataStore DataStore
+}
+
+// startGRPCServer avvia il server gRPC della replica sull'indirizzo indicato
+func startGRPCServer(dataStore DataStore, address utils.ServerAddress) {
+	listener, err := net.Listen("tcp", address.GetFullAddress())
+	if err != nil {
+		log.Fatal("Error while starting gRPC server:", err)
+	}
+	server := grpc.NewServer()
+	datastorepb.RegisterDatastoreServer(server, &GRPCServer{dataStore: dataStore})
+
+	log.Printf("gRPC server listens on port %s", address.Port)
+	err = server.Serve(listener)
+	if err != nil {
+		log.Fatal("Error while serving gRPC requests:", err)
+	}
+}
+
+// Get recupera il valore corrispondente a una chiave, restituisce NOT_FOUND se la chiave non esiste
+func (server *GRPCServer) Get(ctx context.Context, request *datastorepb.GetRequest) (*datastorepb.GetResponse, error) {
+	if request.Key == "" {
+		return nil, status.Error(codes.InvalidArgument, utils.ErrEmptyKey.Error())
+	}
+	args := utils.Args{Namespace: request.Namespace, Key: request.Key, After: fromPBClock(request.After)}
+	var result utils.Result
+	err := invokeContext(ctx, func() error {
+		return server.dataStore.Get(args, &result)
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if string(result.Value) == "NOT FOUND" {
+		return nil, status.Errorf(codes.NotFound, "key %s not found", request.Key)
+	}
+
+	response := &datastorepb.GetResponse{
+		Key:     result.Key,
+		Value:   result.Value,
+		Version: toPBVersion(result.Version),
+		Context: toPBClock(result.Context),
+	}
+	for _, sibling := range result.Siblings {
+		response.Siblings = append(response.Siblings, &datastorepb.VersionedValue{Value: sibling.Value, Version: toPBVersion(sibling.Version), Deleted: sibling.Deleted})
+	}
+	return response, nil
+}
+
+// Put inserisce una nuova coppia key-value, o aggiorna il valore corrente se la chiave già esiste
+func (server *GRPCServer) Put(ctx context.Context, request *datastorepb.PutRequest) (*datastorepb.PutResponse, error) {
+	if request.Key == "" {
+		return nil, status.Error(codes.InvalidArgument, utils.ErrEmptyKey.Error())
+	}
+	args := utils.Args{
+		Namespace: request.Namespace,
+		Key:       request.Key,
+		Value:     request.Value,
+		Context:   fromPBClock(request.Context),
+		After:     fromPBClock(request.After),
+	}
+	if request.Ttl != nil {
+		if err := request.Ttl.CheckValid(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid ttl: %v", err)
+		}
+		args.TTL = request.Ttl.AsDuration()
+	}
+	var result utils.Result
+	err := invokeContext(ctx, func() error {
+		return server.dataStore.Put(args, &result)
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &datastorepb.PutResponse{Version: toPBVersion(result.Version)}, nil
+}
+
+// Delete rimuove la entry corrispondente a una chiave
+func (server *GRPCServer) Delete(ctx context.Context, request *datastorepb.DeleteRequest) (*datastorepb.DeleteResponse, error) {
+	if request.Key == "" {
+		return nil, status.Error(codes.InvalidArgument, utils.ErrEmptyKey.Error())
+	}
+	args := utils.Args{
+		Namespace: request.Namespace,
+		Key:       request.Key,
+		Context:   fromPBClock(request.Context),
+		After:     fromPBClock(request.After),
+	}
+	var result utils.Result
+	err := invokeContext(ctx, func() error {
+		return server.dataStore.Delete(args, &result)
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &datastorepb.DeleteResponse{Version: toPBVersion(result.Version)}, nil
+}
+
+// MultiGet recupera con un'unica richiesta i valori corrispondenti a più chiavi
+func (server *GRPCServer) MultiGet(ctx context.Context, request *datastorepb.MultiGetRequest) (*datastorepb.BatchResponse, error) {
+	args := utils.BatchArgs{Namespace: request.Namespace}
+	for _, key := range request.Keys {
+		args.Entries = append(args.Entries, utils.Args{Key: key})
+	}
+	return server.batch(ctx, utils.MULTI_GET, args)
+}
+
+// MultiPut inserisce o aggiorna con un'unica richiesta più coppie key-value
+func (server *GRPCServer) MultiPut(ctx context.Context, request *datastorepb.MultiPutRequest) (*datastorepb.BatchResponse, error) {
+	args := utils.BatchArgs{Namespace: request.Namespace}
+	for _, entry := range request.Entries {
+		batchEntry := utils.Args{Key: entry.Key, Value: entry.Value}
+		if entry.Ttl != nil {
+			if err := entry.Ttl.CheckValid(); err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "invalid ttl of key %s: %v", entry.Key, err)
+			}
+			batchEntry.TTL = entry.Ttl.AsDuration()
+		}
+		args.Entries = append(args.Entries, batchEntry)
+	}
+	return server.batch(ctx, utils.MULTI_PUT, args)
+}
+
+// MultiDelete rimuove con un'unica richiesta le entry corrispondenti a più chiavi
+func (server *GRPCServer) MultiDelete(ctx context.Context, request *datastorepb.MultiDeleteRequest) (*datastorepb.BatchResponse, error) {
+	args := utils.BatchArgs{Namespace: request.Namespace}
+	for _, key := range request.Keys {
+		args.Entries = append(args.Entries, utils.Args{Key: key})
+	}
+	return server.batch(ctx, utils.MULTI_DELETE, args)
+}
+
+// batch esegue una richiesta batch e restituisce l'esito di ogni elemento, allineato con gli elementi della richiesta
+func (server *GRPCServer) batch(ctx context.Context, op utils.Operation, args utils.BatchArgs) (*datastorepb.BatchResponse, error) {
+	var result utils.BatchResult
+	err := invokeContext(ctx, func() error {
+		switch op {
+		case utils.MULTI_GET:
+			return server.dataStore.MultiGet(args, &result)
+		case utils.MULTI_PUT:
+			return server.dataStore.MultiPut(args, &result)
+		default:
+			return server.dataStore.MultiDelete(args, &result)
+		}
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	response := &datastorepb.BatchResponse{}
+	for i, entry := range args.Entries {
+		batchResult := &datastorepb.BatchResult{Key: entry.Key}
+		if i < len(result.Errors) && result.Errors[i] != "" {
+			batchResult.Error = result.Errors[i]
+		} else if op == utils.MULTI_GET && i < len(result.Results) && string(result.Results[i].Value) != "NOT FOUND" {
+			batchResult.Found = true
+			batchResult.Value = result.Results[i].Value
+			batchResult.Version = toPBVersion(result.Results[i].Version)
+		}
+		response.Results = append(response.Results, batchResult)
+	}
+	return response, nil
+}
+
+// Watch invia in streaming le modifiche applicate dalla replica, finché il client non chiude lo stream.
+// Lo stream è alimentato da richieste di Watch successive, ognuna delle quali riprende dalla revisione raggiunta dalla precedente.
+func (server *GRPCServer) Watch(request *datastorepb.WatchRequest, stream datastorepb.Datastore_WatchServer) error {
+	args := utils.WatchArgs{
+		Namespace:    request.Namespace,
+		Key:          request.Key,
+		Prefix:       request.Prefix,
+		FromRevision: int(request.FromRevision),
+		Timeout:      grpcWatchPoll,
+	}
+	for {
+		var result utils.WatchResult
+		err := invokeContext(stream.Context(), func() error {
+			return server.dataStore.Watch(args, &result)
+		})
+		if err != nil {
+			return grpcError(err)
+		}
+		for _, event := range result.Events {
+			if err := stream.Send(toPBWatchEvent(event)); err != nil {
+				return err
+			}
+		}
+		args.FromRevision = result.Revision
+	}
+}
+
+// grpcError converte l'errore di un'operazione del DataStore nell'errore gRPC con il codice di stato corrispondente
+func grpcError(err error) error {
+	_, code := classifyError(err)
+	return status.Error(code, err.Error())
+}
+
+// toPBVersion converte una versione nel messaggio protobuf corrispondente, nil se la versione non è stata assegnata
+func toPBVersion(version utils.Version) *datastorepb.Version {
+	if !hasVersion(version) {
+		return nil
+	}
+	pbVersion := &datastorepb.Version{
+		Clock:       int64(version.Clock),
+		ServerId:    int32(version.ServerID),
+		VectorClock: toPBClock(version.VectorClock),
+	}
+	if version.VectorClock != nil {
+		pbVersion.Timestamp = &datastorepb.HybridTimestamp{WallTime: version.Timestamp.WallTime, Logical: int32(version.Timestamp.Logical)}
+	}
+	return pbVersion
+}
+
+// toPBWatchEvent converte una modifica osservata nel messaggio protobuf corrispondente
+func toPBWatchEvent(event utils.WatchEvent) *datastorepb.WatchEvent {
+	pbEvent := &datastorepb.WatchEvent{
+		Revision: int64(event.Revision),
+		Op:       datastorepb.WatchEvent_PUT,
+		Key:      event.Key,
+		Version:  toPBVersion(event.Version),
+	}
+	if event.Op == utils.DELETE {
+		pbEvent.Op = datastorepb.WatchEvent_DELETE
+	} else {
+		pbEvent.NewValue = event.NewValue
+	}
+	if string(event.OldValue) == "NOT FOUND" {
+		pbEvent.Created = true
+	} else {
+		pbEvent.OldValue = event.OldValue
+	}
+	return pbEvent
+}
+
+// toPBClock converte un clock vettoriale nel formato dei messaggi protobuf
+func toPBClock(clock []int) []int64 {
+	if clock == nil {
+		return nil
+	}
+	pbClock := make([]int64, len(clock))
+	for i, value := range clock {
+		pbClock[i] = int64(value)
+	}
+	return pbClock
+}
+
+// fromPBClock converte un clock vettoriale dal formato dei messaggi protobuf, nil se il clock è assente
+func fromPBClock(pbClock []int64) []int {
+	if len(pbClock) == 0 {
+		return nil
+	}
+	clock := make([]int, len(pbClock))
+	for i, value := range pbClock {
+		clock[i] = int(value)
+	}
+	return clock
+}
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"context"
+	"dbService/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPGateway espone le operazioni del DataStore tramite endpoint REST, per i client che non utilizzano il protocollo net/rpc di Go.
+// Ogni richiesta HTTP è tradotta nella chiamata al metodo corrispondente del DataStore, con le stesse garanzie di consistenza.
+type HTTPGateway struct {
+	dataStore DataStore
+}
+
+// httpVersion rappresenta nelle risposte JSON la versione di un valore
+type httpVersion struct {
+	Clock       int                    `json:"clock,omitempty"`
+	ServerID    int                    `json:"server_id"`
+	VectorClock []int                  `json:"vector_clock,omitempty"`
+	Timestamp   *utils.HybridTimestamp `json:"timestamp,omitempty"` // Assente con consistenza sequenziale
+}
+
+// httpEntry rappresenta nelle risposte JSON una entry dello store, o l'errore dell'operazione su una singola chiave
+type httpEntry struct {
+	Key      string        `json:"key"`
+	Value    string        `json:"value,omitempty"`
+	Version  *httpVersion  `json:"version,omitempty"`
+	Siblings []httpSibling `json:"siblings,omitempty"`
+	Context  []int         `json:"context,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// httpSibling rappresenta nelle risposte JSON una versione concorrente di una chiave
+type httpSibling struct {
+	Value   string      `json:"value"`
+	Version httpVersion `json:"version"`
+	Deleted bool        `json:"deleted,omitempty"`
+}
+
+// httpBatchRequest è il corpo delle richieste batch: Keys per le letture e le rimozioni, Entries per le scritture
+type httpBatchRequest struct {
+	Keys    []string `json:"keys"`
+	Entries []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+		TTL   string `json:"ttl"` // Durata nel formato di time.ParseDuration, ad esempio 30s
+	} `json:"entries"`
+}
+
+// startHTTPGateway avvia il gateway HTTP/JSON della replica sull'indirizzo indicato
+func startHTTPGateway(dataStore DataStore, address utils.ServerAddress) {
+	gateway := &HTTPGateway{dataStore: dataStore}
+
+	mux := http.NewServeMux()
+	// Le chiavi possono contenere il carattere /, quindi il parametro occupa il resto del percorso
+	mux.HandleFunc("GET /v1/keys/{key...}", gateway.getKey)
+	mux.HandleFunc("PUT /v1/keys/{key...}", gateway.putKey)
+	mux.HandleFunc("DELETE /v1/keys/{key...}", gateway.deleteKey)
+	mux.HandleFunc("GET /v1/keys", gateway.scan)
+	mux.HandleFunc("POST /v1/batch/get", gateway.batchGet)
+	mux.HandleFunc("POST /v1/batch/put", gateway.batchPut)
+	mux.HandleFunc("POST /v1/batch/delete", gateway.batchDelete)
+
+	log.Printf("HTTP gateway listens on port %s", address.Port)
+	err := http.ListenAndServe(address.GetFullAddress(), mux)
+	if err != nil {
+		log.Fatal("Error while starting HTTP gateway:", err)
+	}
+}
+
+// getKey gestisce GET /v1/keys/{key}.
+// Il valore è restituito nel corpo della risposta così come è stato scritto, e la sua versione negli header.
+// Con Accept: application/json viene invece restituita la entry in JSON, comprese le eventuali versioni concorrenti.
+func (gateway *HTTPGateway) getKey(w http.ResponseWriter, r *http.Request) {
+	args, err := keyArgs(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var result utils.Result
+	err = invoke(r, func() error {
+		return gateway.dataStore.Get(args, &result)
+	})
+	if err != nil {
+		writeDataStoreError(w, err)
+		return
+	}
+	if string(result.Value) == "NOT FOUND" {
+		writeError(w, http.StatusNotFound, fmt.Errorf("key %s not found", args.Key))
+		return
+	}
+
+	writeVersionHeaders(w.Header(), result)
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		writeJSON(w, http.StatusOK, newHTTPEntry(result))
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(result.Value)
+}
+
+// putKey gestisce PUT /v1/keys/{key}: il corpo della richiesta è il valore da scrivere
+func (gateway *HTTPGateway) putKey(w http.ResponseWriter, r *http.Request) {
+	args, err := keyArgs(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if ttl := r.URL.Query().Get("ttl"); ttl != "" {
+		if args.TTL, err = time.ParseDuration(ttl); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid ttl %q: %v", ttl, err))
+			return
+		}
+	}
+	body := r.Body
+	if MaxValueSize > 0 {
+		body = http.MaxBytesReader(w, r.Body, int64(MaxValueSize))
+	}
+	if args.Value, err = io.ReadAll(body); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("the maximum value size is %d bytes", MaxValueSize))
+			return
+		}
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var result utils.Result
+	err = invoke(r, func() error {
+		return gateway.dataStore.Put(args, &result)
+	})
+	if err != nil {
+		writeDataStoreError(w, err)
+		return
+	}
+	writeVersionHeaders(w.Header(), result)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteKey gestisce DELETE /v1/keys/{key}
+func (gateway *HTTPGateway) deleteKey(w http.ResponseWriter, r *http.Request) {
+	args, err := keyArgs(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var result utils.Result
+	err = invoke(r, func() error {
+		return gateway.dataStore.Delete(args, &result)
+	})
+	if err != nil {
+		writeDataStoreError(w, err)
+		return
+	}
+	writeVersionHeaders(w.Header(), result)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// scan gestisce GET /v1/keys, con i parametri start, end, prefix, limit e cursor.
+// Se è indicato prefix vengono restituite le chiavi con quel prefisso, altrimenti quelle comprese tra start ed end.
+func (gateway *HTTPGateway) scan(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	args := utils.ScanArgs{
+		Namespace: query.Get("namespace"),
+		Start:     query.Get("start"),
+		End:       query.Get("end"),
+		Prefix:    query.Get("prefix"),
+		Cursor:    query.Get("cursor"),
+	}
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		if args.Limit, err = strconv.Atoi(limit); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", limit))
+			return
+		}
+	}
+
+	var result utils.ScanResult
+	err := invoke(r, func() error {
+		if args.Prefix != "" {
+			return gateway.dataStore.ScanPrefix(args, &result)
+		}
+		return gateway.dataStore.Scan(args, &result)
+	})
+	if err != nil {
+		writeDataStoreError(w, err)
+		return
+	}
+
+	entries := make([]httpEntry, 0, len(result.Entries))
+	for _, entry := range result.Entries {
+		entries = append(entries, newHTTPEntry(entry))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"entries": entries, "cursor": result.Cursor})
+}
+
+// batchGet gestisce POST /v1/batch/get, con corpo {"keys": [...]}
+func (gateway *HTTPGateway) batchGet(w http.ResponseWriter, r *http.Request) {
+	gateway.batch(w, r, utils.MULTI_GET)
+}
+
+// batchPut gestisce POST /v1/batch/put, con corpo {"entries": [{"key": ..., "value": ..., "ttl": ...}]}
+func (gateway *HTTPGateway) batchPut(w http.ResponseWriter, r *http.Request) {
+	gateway.batch(w, r, utils.MULTI_PUT)
+}
+
+// batchDelete gestisce POST /v1/batch/delete, con corpo {"keys": [...]}
+func (gateway *HTTPGateway) batchDelete(w http.ResponseWriter, r *http.Request) {
+	gateway.batch(w, r, utils.MULTI_DELETE)
+}
+
+// batch esegue una richiesta batch e restituisce l'esito di ogni elemento, allineato con gli elementi della richiesta.
+// I valori sono trasmessi in JSON come stringhe, quindi i valori binari vanno scritti e letti con gli endpoint per singola chiave.
+func (gateway *HTTPGateway) batch(w http.ResponseWriter, r *http.Request, op utils.Operation) {
+	var request httpBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON body: %v", err))
+		return
+	}
+
+	args := utils.BatchArgs{Namespace: r.URL.Query().Get("namespace")}
+	var err error
+	if args.After, err = parseClock(r.Header.Get("X-Session-Clock")); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid X-Session-Clock header: %v", err))
+		return
+	}
+	if op == utils.MULTI_PUT {
+		for _, entry := range request.Entries {
+			batchEntry := utils.Args{Key: entry.Key, Value: []byte(entry.Value)}
+			if entry.TTL != "" {
+				ttl, err := time.ParseDuration(entry.TTL)
+				if err != nil {
+					writeError(w, http.StatusBadRequest, fmt.Errorf("invalid ttl %q of key %s: %v", entry.TTL, entry.Key, err))
+					return
+				}
+				batchEntry.TTL = ttl
+			}
+			args.Entries = append(args.Entries, batchEntry)
+		}
+	} else {
+		for _, key := range request.Keys {
+			args.Entries = append(args.Entries, utils.Args{Key: key})
+		}
+	}
+
+	var result utils.BatchResult
+	err = invoke(r, func() error {
+		switch op {
+		case utils.MULTI_GET:
+			return gateway.dataStore.MultiGet(args, &result)
+		case utils.MULTI_PUT:
+			return gateway.dataStore.MultiPut(args, &result)
+		default:
+			return gateway.dataStore.MultiDelete(args, &result)
+		}
+	})
+	if err != nil {
+		writeDataStoreError(w, err)
+		return
+	}
+
+	results := make([]httpEntry, len(args.Entries))
+	for i, entry := range args.Entries {
+		results[i] = httpEntry{Key: entry.Key}
+		if i < len(result.Errors) && result.Errors[i] != "" {
+			results[i].Error = result.Errors[i]
+			continue
+		}
+		if op != utils.MULTI_GET || i >= len(result.Results) {
+			continue
+		}
+		if string(result.Results[i].Value) == "NOT FOUND" {
+			results[i].Error = "not found"
+			continue
+		}
+		results[i] = newHTTPEntry(result.Results[i])
+		results[i].Key = entry.Key
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"results": results})
+}
+
+// keyArgs costruisce gli argomenti di un'operazione su una singola chiave dal percorso, dai parametri e dagli header della richiesta
+func keyArgs(r *http.Request) (utils.Args, error) {
+	args := utils.Args{
+		Namespace: r.URL.Query().Get("namespace"),
+		Key:       r.PathValue("key"),
+	}
+	if args.Key == "" {
+		return args, utils.ErrEmptyKey
+	}
+	var err error
+	if args.Context, err = parseClock(r.Header.Get("X-Causal-Context")); err != nil {
+		return args, fmt.Errorf("invalid X-Causal-Context header: %v", err)
+	}
+	if args.After, err = parseClock(r.Header.Get("X-Session-Clock")); err != nil {
+		return args, fmt.Errorf("invalid X-Session-Clock header: %v", err)
+	}
+	return args, nil
+}
+
+// invoke esegue un'operazione del DataStore e ne attende il termine, oppure la chiusura della richiesta HTTP o la scadenza del parametro timeout
+func invoke(r *http.Request, operation func() error) error {
+	ctx := r.Context()
+	if timeout := r.URL.Query().Get("timeout"); timeout != "" {
+		duration, err := time.ParseDuration(timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout %q: %v", timeout, err)
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, duration)
+		defer cancel()
+	}
+	return invokeContext(ctx, operation)
+}
+
+// newHTTPEntry converte il risultato di una lettura nella sua rappresentazione JSON
+func newHTTPEntry(result utils.Result) httpEntry {
+	entry := httpEntry{
+		Key:     result.Key,
+		Value:   string(result.Value),
+		Context: result.Context,
+	}
+	if hasVersion(result.Version) {
+		version := newHTTPVersion(result.Version)
+		entry.Version = &version
+	}
+	for _, sibling := range result.Siblings {
+		entry.Siblings = append(entry.Siblings, httpSibling{Value: string(sibling.Value), Version: newHTTPVersion(sibling.Version), Deleted: sibling.Deleted})
+	}
+	return entry
+}
+
+// newHTTPVersion converte una versione nella sua rappresentazione JSON
+func newHTTPVersion(version utils.Version) httpVersion {
+	httpVersion := httpVersion{Clock: version.Clock, ServerID: version.ServerID, VectorClock: version.VectorClock}
+	if version.VectorClock != nil {
+		httpVersion.Timestamp = &version.Timestamp
+	}
+	return httpVersion
+}
+
+// hasVersion indica se la versione è stata assegnata, ossia se il risultato proviene da una scrittura
+func hasVersion(version utils.Version) bool {
+	return version.Clock > 0 || version.VectorClock != nil
+}
+
+// writeVersionHeaders riporta negli header della risposta la versione del valore letto o scritto
+func writeVersionHeaders(header http.Header, result utils.Result) {
+	if hasVersion(result.Version) {
+		header.Set("X-Version-Server-Id", strconv.Itoa(result.Version.ServerID))
+		if result.Version.VectorClock != nil {
+			header.Set("X-Version-Vector-Clock", formatClock(result.Version.VectorClock))
+			header.Set("X-Version-Timestamp", fmt.Sprintf("%d.%d", result.Version.Timestamp.WallTime, result.Version.Timestamp.Logical))
+		} else {
+			header.Set("X-Version-Clock", strconv.Itoa(result.Version.Clock))
+		}
+	}
+	if result.Context != nil {
+		header.Set("X-Causal-Context", formatClock(result.Context))
+	}
+	if len(result.Siblings) > 0 {
+		header.Set("X-Siblings", strconv.Itoa(len(result.Siblings)))
+	}
+}
+
+// parseClock converte un clock vettoriale dal formato degli header (valori separati da virgola) in uno slice, nil se l'header è vuoto
+func parseClock(value string) ([]int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parts := strings.Split(value, ",")
+	clock := make([]int, len(parts))
+	for i, part := range parts {
+		var err error
+		if clock[i], err = strconv.Atoi(strings.TrimSpace(part)); err != nil {
+			return nil, fmt.Errorf("%q is not a number", part)
+		}
+	}
+	return clock, nil
+}
+
+// formatClock converte un clock vettoriale nel formato degli header
+func formatClock(clock []int) string {
+	parts := make([]string, len(clock))
+	for i, value := range clock {
+		parts[i] = strconv.Itoa(value)
+	}
+	return strings.Join(parts, ",")
+}
+
+// writeDataStoreError restituisce al client l'errore di un'operazione del DataStore, con il codice di stato corrispondente
+func writeDataStoreError(w http.ResponseWriter, err error) {
+	status, _ := classifyError(err)
+	writeError(w, status, err)
+}
+
+// writeError restituisce al client un errore in JSON, nella forma {"error": "..."}
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// writeJSON restituisce al client il valore indicato codificato in JSON
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Println("Error while writing HTTP response:", err)
+	}
+}
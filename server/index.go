@@ -0,0 +1,183 @@
+package main
+
+import (
+	"dbService/utils"
+	"fmt"
+	"sort"
+)
+
+// SecondaryIndex è un indice secondario su un campo dei documenti JSON di uno store.
+// È aggiornato dallo store a ogni modifica del valore corrente di una chiave, quindi è sempre consistente con i dati della replica.
+type SecondaryIndex struct {
+	Config  utils.IndexArgs
+	field   []string
+	entries map[string]map[string]bool // Per ogni valore del campo (codificato in JSON), l'insieme delle chiavi che lo contengono
+	values  map[string]string          // Valore del campo indicizzato di ogni chiave
+	created utils.Version              // Versione della creazione dell'indice, con cui sono risolte le creazioni concorrenti (consistenza causale)
+}
+
+// newSecondaryIndex crea un indice vuoto con la definizione indicata
+func newSecondaryIndex(config utils.IndexArgs) *SecondaryIndex {
+	return &SecondaryIndex{
+		Config:  config,
+		field:   config.FieldPath(),
+		entries: make(map[string]map[string]bool),
+		values:  make(map[string]string),
+	}
+}
+
+// fieldValue restituisce il valore del campo indicizzato all'interno del documento, codificato in JSON.
+// Restituisce false se il valore non è un documento JSON che contiene il campo.
+func (index *SecondaryIndex) fieldValue(document []byte) (string, bool) {
+	root, err := decodeJSON(document)
+	if err != nil {
+		return "", false
+	}
+	value, exist := lookupPath(root, index.field)
+	if !exist {
+		return "", false
+	}
+	encoded, err := encodeJSON(value)
+	if err != nil {
+		return "", false
+	}
+	return string(encoded), true
+}
+
+// update aggiorna l'indice con il nuovo valore corrente della chiave
+func (index *SecondaryIndex) update(key string, document []byte) {
+	if !index.Config.Matches(key) {
+		return
+	}
+	index.remove(key)
+	value, exist := index.fieldValue(document)
+	if !exist {
+		return
+	}
+	if index.entries[value] == nil {
+		index.entries[value] = make(map[string]bool)
+	}
+	index.entries[value][key] = true
+	index.values[key] = value
+}
+
+// remove rimuove la chiave dall'indice
+func (index *SecondaryIndex) remove(key string) {
+	value, exist := index.values[key]
+	if !exist {
+		return
+	}
+	delete(index.entries[value], key)
+	if len(index.entries[value]) == 0 {
+		delete(index.entries, value)
+	}
+	delete(index.values, key)
+}
+
+// query restituisce le chiavi il cui campo indicizzato ha il valore indicato, in ordine lessicografico
+func (index *SecondaryIndex) query(value []byte) []string {
+	keys := []string{}
+	for key := range index.entries[queryValue(value)] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// queryValue restituisce la codifica JSON del valore cercato, la stessa con cui i valori dei campi sono indicizzati.
+// Un valore che non è JSON valido è cercato come stringa.
+func queryValue(value []byte) string {
+	if root, err := decodeJSON(value); err == nil {
+		if encoded, err := encodeJSON(root); err == nil {
+			return string(encoded)
+		}
+	}
+	encoded, _ := encodeJSON(string(value))
+	return string(encoded)
+}
+
+// indexOp crea (CREATE_INDEX) o rimuove (DROP_INDEX) un indice secondario del namespace indicato, con la versione del messaggio che lo richiede
+func (namespaces *Namespaces) indexOp(namespace string, op utils.Operation, name string, config *utils.IndexArgs, version utils.Version) error {
+	target, err := namespaces.get(namespace)
+	if err != nil {
+		return err
+	}
+	if op == utils.CREATE_INDEX {
+		return target.Store.createIndex(*config, version)
+	}
+	return target.Store.dropIndex(name)
+}
+
+// createIndex crea un indice secondario e lo costruisce a partire dalle chiavi già presenti nello store.
+// Con consistenza causale, tra due creazioni concorrenti dello stesso indice tutte le repliche mantengono la definizione della creazione che prevale secondo supersedes.
+func (db *DbStore) createIndex(config utils.IndexArgs, version utils.Version) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	if existing, exist := db.Indexes[config.Name]; exist {
+		if !version.IsVector() || !supersedes(existing.created, version) {
+			return fmt.Errorf("index %q already exists", config.Name)
+		}
+		fmt.Printf("CREATE INDEX %s replaces concurrent creation %s\n", config.Name, existing.created)
+	}
+	index := newSecondaryIndex(config)
+	index.created = version
+	for key, entry := range db.Store {
+		index.update(key, entry.Value)
+	}
+	db.Indexes[config.Name] = index
+	fmt.Printf("CREATE INDEX %s keys %s field %s, %d keys indexed\n", config.Name, config.Keys, config.Field, len(index.values))
+	return nil
+}
+
+// dropIndex rimuove un indice secondario
+func (db *DbStore) dropIndex(name string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	if _, exist := db.Indexes[name]; !exist {
+		return fmt.Errorf("index %q does not exist", name)
+	}
+	delete(db.Indexes, name)
+	fmt.Printf("DROP INDEX %s\n", name)
+	return nil
+}
+
+// queryIndex restituisce le chiavi il cui campo indicizzato ha il valore cercato.
+// Se l'indice non esiste (ad esempio perché rimosso prima della lettura) non restituisce alcuna chiave.
+func (db *DbStore) queryIndex(args utils.QueryArgs) utils.QueryResult {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	result := utils.QueryResult{Keys: []string{}}
+	if index, exist := db.Indexes[args.Index]; exist {
+		result.Keys = index.query(args.Value)
+	}
+	fmt.Printf("QUERY INDEX %s value %s, %d keys\n", args.Index, args.Value, len(result.Keys))
+	return result
+}
+
+// lookupIndex restituisce la definizione dell'indice indicato, oppure un errore se non esiste
+func (db *DbStore) lookupIndex(name string) (utils.IndexInfo, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	index, exist := db.Indexes[name]
+	if !exist {
+		return utils.IndexInfo{}, fmt.Errorf("index %q does not exist", name)
+	}
+	return utils.IndexInfo{Config: index.Config, Keys: len(index.values)}, nil
+}
+
+// listIndexes restituisce gli indici secondari dello store, in ordine lessicografico
+func (db *DbStore) listIndexes() []utils.IndexInfo {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	var infos []utils.IndexInfo
+	for _, index := range db.Indexes {
+		infos = append(infos, utils.IndexInfo{Config: index.Config, Keys: len(index.values)})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Config.Name < infos[j].Config.Name
+	})
+	return infos
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"dbService/utils"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Namespace è uno spazio delle chiavi isolato, con uno store separato e una propria configurazione
+type Namespace struct {
+	Config   utils.NamespaceArgs
+	Store    *DbStore
+	resolver ConflictResolver // Resolver della politica configurata per il namespace, usato solo se Config.ConflictPolicy non è vuota
+	stop     chan struct{}    // Chiuso alla rimozione del namespace, per terminare il suo expirer
+	created  utils.Version    // Versione della creazione del namespace, con cui sono risolte le creazioni concorrenti (consistenza causale)
+}
+
+// Namespaces mantiene i namespace presenti sulla replica.
+// Il namespace di default usa lo store della replica, ed è sempre presente.
+type Namespaces struct {
+	byName   map[string]*Namespace
+	serverID int
+	expire   func(namespace string, key string, version utils.Version) // Richiede la rimozione di una versione scaduta secondo il protocollo di consistenza attivo
+	mutex    sync.Mutex
+}
+
+// NewNamespaces costruisce i namespace della replica, a partire dal namespace di default, e avvia l'expirer del namespace di default
+func NewNamespaces(defaultStore *DbStore, serverID int, expire func(namespace string, key string, version utils.Version)) *Namespaces {
+	namespaces := &Namespaces{
+		byName:   make(map[string]*Namespace),
+		serverID: serverID,
+		expire:   expire,
+	}
+	namespaces.add(&Namespace{Config: utils.NamespaceArgs{Name: utils.DefaultNamespace, Documents: DocumentMode}, Store: defaultStore})
+	return namespaces
+}
+
+// get restituisce il namespace indicato, oppure un errore se non esiste sulla replica
+func (namespaces *Namespaces) get(name string) (*Namespace, error) {
+	namespaces.mutex.Lock()
+	defer namespaces.mutex.Unlock()
+	namespace, exist := namespaces.byName[name]
+	if !exist {
+		return nil, fmt.Errorf("namespace %q does not exist", name)
+	}
+	return namespace, nil
+}
+
+// lookup restituisce il namespace indicato, oppure nil se non esiste sulla replica
+func (namespaces *Namespaces) lookup(name string) *Namespace {
+	namespace, _ := namespaces.get(name)
+	return namespace
+}
+
+// create crea un namespace vuoto con la configurazione indicata, con la versione del messaggio di creazione.
+// Restituisce un errore se il namespace esiste già o se la configurazione non è valida.
+// Con consistenza causale due repliche possono creare lo stesso namespace in concorrenza, e ognuna applica prima la propria creazione:
+// tutte le repliche mantengono allora la configurazione della creazione che prevale secondo supersedes, conservando le chiavi già scritte.
+func (namespaces *Namespaces) create(config utils.NamespaceArgs, version utils.Version) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	resolver, err := NewConflictResolver(config.ConflictPolicy)
+	if err != nil {
+		return err
+	}
+	if existing := namespaces.lookup(config.Name); existing != nil {
+		if !version.IsVector() || !supersedes(existing.created, version) {
+			return fmt.Errorf("namespace %q already exists", config.Name)
+		}
+		namespaces.mutex.Lock()
+		namespaces.byName[config.Name] = &Namespace{Config: config, Store: existing.Store, resolver: resolver, stop: existing.stop, created: version}
+		namespaces.mutex.Unlock()
+		fmt.Printf("CREATE NAMESPACE %s ttl %s quota %d conflict policy %q documents %t, replaces concurrent creation %s\n", config.Name, config.TTL, config.Quota, config.ConflictPolicy, config.Documents, existing.created)
+		return nil
+	}
+	namespaces.add(&Namespace{Config: config, Store: newDbStore(), resolver: resolver, stop: make(chan struct{}), created: version})
+	fmt.Printf("CREATE NAMESPACE %s ttl %s quota %d conflict policy %q documents %t\n", config.Name, config.TTL, config.Quota, config.ConflictPolicy, config.Documents)
+	return nil
+}
+
+// drop rimuove il namespace indicato con tutte le sue chiavi
+func (namespaces *Namespaces) drop(name string) error {
+	if name == utils.DefaultNamespace {
+		return fmt.Errorf("the default namespace can not be dropped")
+	}
+	namespaces.mutex.Lock()
+	defer namespaces.mutex.Unlock()
+	namespace, exist := namespaces.byName[name]
+	if !exist {
+		return fmt.Errorf("namespace %q does not exist", name)
+	}
+	delete(namespaces.byName, name)
+	close(namespace.stop)
+	fmt.Printf("DROP NAMESPACE %s\n", name)
+	return nil
+}
+
+// list restituisce la descrizione dei namespace presenti sulla replica, in ordine lessicografico
+func (namespaces *Namespaces) list() []utils.NamespaceInfo {
+	namespaces.mutex.Lock()
+	var all []*Namespace
+	for _, namespace := range namespaces.byName {
+		all = append(all, namespace)
+	}
+	namespaces.mutex.Unlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Config.Name < all[j].Config.Name
+	})
+	var infos []utils.NamespaceInfo
+	for _, namespace := range all {
+		keys, size := namespace.Store.usage()
+		infos = append(infos, utils.NamespaceInfo{Config: namespace.Config, Keys: keys, Size: size})
+	}
+	return infos
+}
+
+// add registra il namespace e avvia il controllo periodico delle sue entry scadute
+func (namespaces *Namespaces) add(namespace *Namespace) {
+	namespaces.mutex.Lock()
+	namespaces.byName[namespace.Config.Name] = namespace
+	namespaces.mutex.Unlock()
+
+	name := namespace.Config.Name
+	go namespace.Store.runExpirer(namespaces.serverID, namespace.stop, func(key string, version utils.Version) {
+		namespaces.expire(name, key, version)
+	})
+}
+
+// withDefaultTTL applica alla richiesta il TTL di default del namespace, se il client non ne ha indicato uno
+func (namespace *Namespace) withDefaultTTL(args utils.Args) utils.Args {
+	return args.WithDefaultTTL(namespace.Config.TTL)
+}
+
+// admit verifica che le scritture indicate siano ammesse dal namespace: in modalità documento i valori devono essere JSON valido,
+// e le scritture non devono portare il namespace oltre la sua quota.
+// La verifica avviene sulla replica che riceve la richiesta, prima di propagare le scritture:
+// scritture concorrenti ricevute da repliche diverse possono quindi superare la quota di poco.
+func (namespace *Namespace) admit(entries ...utils.BatchEntry) error {
+	if namespace.Config.Documents {
+		for _, entry := range entries {
+			if !json.Valid(entry.Value) {
+				return fmt.Errorf("value of key %s is not a valid JSON document", entry.Key)
+			}
+		}
+	}
+	if namespace.Config.Quota <= 0 {
+		return nil
+	}
+	_, size := namespace.Store.usage()
+	for _, entry := range entries {
+		size += namespace.Store.sizeDelta(entry.Key, entry.Value)
+	}
+	if size > namespace.Config.Quota {
+		return fmt.Errorf("namespace %q quota exceeded: %d bytes requested, quota is %d bytes", namespace.Config.Name, size, namespace.Config.Quota)
+	}
+	return nil
+}
+
+// ResolverFor restituisce il resolver da applicare alla chiave del namespace (consistenza causale).
+// Se per il namespace non è configurata una politica, si applicano le politiche della replica.
+func (namespace *Namespace) ResolverFor(key string, policies *ConflictPolicies) ConflictResolver {
+	if namespace.Config.ConflictPolicy != "" {
+		return namespace.resolver
+	}
+	return policies.ResolverFor(key)
+}
@@ -0,0 +1,626 @@
+package main
+
+import (
+	"bufio"
+	"dbService/utils"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	respMaxBulkLength   = 512 * 1024 * 1024 // Dimensione massima in byte di una bulk string ricevuta, come in Redis
+	respMaxArgs         = 1024 * 1024       // Numero massimo di argomenti di un comando
+	respDefaultScanSize = 10                // Numero di chiavi restituite da SCAN, se non indicato con COUNT
+)
+
+// RESPServer espone le operazioni del DataStore tramite il protocollo RESP di Redis, così che redis-cli e le librerie client di Redis possano interagire con lo store.
+// I comandi supportati sono tradotti nei metodi del DataStore, con le garanzie di consistenza della replica, e operano sul namespace di default.
+type RESPServer struct {
+	dataStore DataStore
+}
+
+// respConn rappresenta la connessione con un client RESP
+type respConn struct {
+	server     *RESPServer
+	reader     *bufio.Reader
+	writer     *bufio.Writer
+	cursors    map[int]string // Cursori delle scansioni in corso con SCAN, indicizzati dal cursore numerico restituito al client
+	nextCursor int
+}
+
+// errRESPSyntax è restituito per i comandi con argomenti non validi
+var errRESPSyntax = errors.New("syntax error")
+
+// startRESPServer avvia il server RESP della replica sull'indirizzo indicato
+func startRESPServer(dataStore DataStore, address utils.ServerAddress) {
+	listener, err := net.Listen("tcp", address.GetFullAddress())
+	if err != nil {
+		log.Fatal("Error while starting RESP server:", err)
+	}
+	server := &RESPServer{dataStore: dataStore}
+	log.Printf("RESP server listens on port %s", address.Port)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			fmt.Println("Error while accepting RESP connection:", err)
+			continue
+		}
+		go server.handleConnection(conn)
+	}
+}
+
+// handleConnection esegue in ordine i comandi ricevuti su una connessione, finché il client non la chiude
+func (server *RESPServer) handleConnection(conn net.Conn) {
+	defer func(conn net.Conn) {
+		_ = conn.Close()
+	}(conn)
+
+	client := &respConn{
+		server:  server,
+		reader:  bufio.NewReader(conn),
+		writer:  bufio.NewWriter(conn),
+		cursors: make(map[int]string),
+	}
+	for {
+		args, err := client.readCommand()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				client.writeError("ERR Protocol error: " + err.Error())
+				_ = client.writer.Flush()
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		quit := client.execute(args)
+		// Le risposte sono inviate quando non restano comandi da leggere, così che i comandi in pipeline siano serviti con un'unica scrittura
+		if client.reader.Buffered() == 0 || quit {
+			if err := client.writer.Flush(); err != nil {
+				return
+			}
+		}
+		if quit {
+			return
+		}
+	}
+}
+
+// readCommand legge il prossimo comando, inviato come array di bulk string oppure come comando inline (parole separate da spazi)
+func (client *respConn) readCommand() ([][]byte, error) {
+	line, err := client.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		var args [][]byte
+		for _, field := range strings.Fields(line) {
+			args = append(args, []byte(field))
+		}
+		return args, nil
+	}
+
+	count, err := strconv.Atoi(line[1:])
+	if err != nil || count > respMaxArgs {
+		return nil, fmt.Errorf("invalid multibulk length")
+	}
+	args := make([][]byte, 0, max(count, 0))
+	for i := 0; i < count; i++ {
+		header, err := client.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(header) == 0 || header[0] != '$' {
+			return nil, fmt.Errorf("expected '$', got %q", header)
+		}
+		length, err := strconv.Atoi(header[1:])
+		if err != nil || length < 0 || length > respMaxBulkLength {
+			return nil, fmt.Errorf("invalid bulk length")
+		}
+		arg := make([]byte, length+2)
+		if _, err := io.ReadFull(client.reader, arg); err != nil {
+			return nil, err
+		}
+		args = append(args, arg[:length])
+	}
+	return args, nil
+}
+
+// readLine legge una riga terminata da CRLF, senza il terminatore
+func (client *respConn) readLine() (string, error) {
+	line, err := client.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// execute esegue un comando e ne scrive la risposta, restituisce true se il client ha chiesto di chiudere la connessione
+func (client *respConn) execute(args [][]byte) bool {
+	command := strings.ToUpper(string(args[0]))
+	args = args[1:]
+
+	switch command {
+	case "PING":
+		if len(args) > 0 {
+			client.writeBulk(args[0])
+		} else {
+			client.writeSimple("PONG")
+		}
+	case "ECHO":
+		if client.checkArgs(command, args, 1, 1) {
+			client.writeBulk(args[0])
+		}
+	case "QUIT":
+		client.writeSimple("OK")
+		return true
+	case "SELECT":
+		// Esiste un solo database, corrispondente al namespace di default
+		if client.checkArgs(command, args, 1, 1) {
+			if string(args[0]) == "0" {
+				client.writeSimple("OK")
+			} else {
+				client.writeError("ERR DB index is out of range")
+			}
+		}
+	case "COMMAND", "CLIENT":
+		// Richiesti da alcuni client alla connessione, non hanno effetto
+		if command == "COMMAND" {
+			client.writeArrayHeader(0)
+		} else {
+			client.writeSimple("OK")
+		}
+	case "GET":
+		if client.checkArgs(command, args, 1, 1) {
+			client.get(args[0])
+		}
+	case "SET":
+		if client.checkArgs(command, args, 2, -1) {
+			client.set(args)
+		}
+	case "DEL":
+		if client.checkArgs(command, args, 1, -1) {
+			client.del(args)
+		}
+	case "EXISTS":
+		if client.checkArgs(command, args, 1, -1) {
+			client.exists(args)
+		}
+	case "MGET":
+		if client.checkArgs(command, args, 1, -1) {
+			client.mget(args)
+		}
+	case "MSET":
+		if client.checkArgs(command, args, 2, -1) {
+			client.mset(args)
+		}
+	case "KEYS":
+		if client.checkArgs(command, args, 1, 1) {
+			client.keys(string(args[0]))
+		}
+	case "SCAN":
+		if client.checkArgs(command, args, 1, -1) {
+			client.scan(args)
+		}
+	case "EXPIRE":
+		if client.checkArgs(command, args, 2, 2) {
+			client.expire(args[0], args[1])
+		}
+	default:
+		client.writeError(fmt.Sprintf("ERR unknown command '%s'", strings.ToLower(command)))
+	}
+	return false
+}
+
+// checkArgs verifica che il numero di argomenti del comando sia compreso tra minArgs e maxArgs (-1 se illimitato), altrimenti scrive l'errore
+func (client *respConn) checkArgs(command string, args [][]byte, minArgs int, maxArgs int) bool {
+	valid := len(args) >= minArgs && (maxArgs < 0 || len(args) <= maxArgs)
+	// MSET richiede coppie chiave-valore
+	if command == "MSET" && len(args)%2 != 0 {
+		valid = false
+	}
+	if !valid {
+		client.writeError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(command)))
+	}
+	return valid
+}
+
+// lookup legge i valori correnti delle chiavi indicate, con nil per le chiavi non presenti.
+// Usa MultiGet, che con entrambe le consistenze restituisce subito NOT FOUND per le chiavi non presenti, come si aspettano i client Redis.
+func (client *respConn) lookup(keys [][]byte) ([][]byte, error) {
+	args := utils.BatchArgs{}
+	for _, key := range keys {
+		args.Entries = append(args.Entries, utils.Args{Key: string(key)})
+	}
+	var result utils.BatchResult
+	if err := client.server.dataStore.MultiGet(args, &result); err != nil {
+		return nil, err
+	}
+	values := make([][]byte, len(keys))
+	for i := range keys {
+		if i < len(result.Errors) && result.Errors[i] != "" {
+			continue
+		}
+		if i < len(result.Results) && string(result.Results[i].Value) != "NOT FOUND" {
+			values[i] = result.Results[i].Value
+			if values[i] == nil {
+				values[i] = []byte{}
+			}
+		}
+	}
+	return values, nil
+}
+
+// get gestisce GET key
+func (client *respConn) get(key []byte) {
+	values, err := client.lookup([][]byte{key})
+	if err != nil {
+		client.writeDataStoreError(err)
+		return
+	}
+	client.writeBulk(values[0])
+}
+
+// set gestisce SET key value [EX seconds | PX milliseconds] [NX].
+// NX è tradotto in PutIfAbsent con l'eventuale TTL, supportata solo con consistenza sequenziale.
+func (client *respConn) set(args [][]byte) {
+	putArgs := utils.Args{Key: string(args[0]), Value: args[1]}
+	ifAbsent := false
+	for i := 2; i < len(args); i++ {
+		option := strings.ToUpper(string(args[i]))
+		switch option {
+		case "NX":
+			ifAbsent = true
+		case "EX", "PX":
+			if i+1 >= len(args) || putArgs.TTL > 0 {
+				client.writeError("ERR " + errRESPSyntax.Error())
+				return
+			}
+			amount, err := strconv.Atoi(string(args[i+1]))
+			if err != nil || amount <= 0 {
+				client.writeError(fmt.Sprintf("ERR invalid expire time in '%s' command", "set"))
+				return
+			}
+			if option == "EX" {
+				putArgs.TTL = time.Duration(amount) * time.Second
+			} else {
+				putArgs.TTL = time.Duration(amount) * time.Millisecond
+			}
+			i++
+		default:
+			client.writeError("ERR " + errRESPSyntax.Error())
+			return
+		}
+	}
+
+	if ifAbsent {
+		var result utils.CondResult
+		if err := client.server.dataStore.PutIfAbsent(putArgs, &result); err != nil {
+			client.writeDataStoreError(err)
+			return
+		}
+		if !result.Succeeded {
+			client.writeBulk(nil)
+			return
+		}
+		client.writeSimple("OK")
+		return
+	}
+
+	if err := client.server.dataStore.Put(putArgs, &utils.Result{}); err != nil {
+		client.writeDataStoreError(err)
+		return
+	}
+	client.writeSimple("OK")
+}
+
+// del gestisce DEL key [key ...] e restituisce il numero di chiavi rimosse, contando una sola volta le chiavi ripetute.
+// Le chiavi presenti sono individuate prima della rimozione, quindi il conteggio non considera le scritture concorrenti.
+func (client *respConn) del(keys [][]byte) {
+	values, err := client.lookup(keys)
+	if err != nil {
+		client.writeDataStoreError(err)
+		return
+	}
+	args := utils.BatchArgs{}
+	seen := make(map[string]bool)
+	for i, key := range keys {
+		if values[i] != nil && !seen[string(key)] {
+			seen[string(key)] = true
+			args.Entries = append(args.Entries, utils.Args{Key: string(key)})
+		}
+	}
+	if len(args.Entries) > 0 {
+		if err := client.server.dataStore.MultiDelete(args, &utils.BatchResult{}); err != nil {
+			client.writeDataStoreError(err)
+			return
+		}
+	}
+	client.writeInteger(len(args.Entries))
+}
+
+// exists gestisce EXISTS key [key ...] e restituisce il numero di chiavi presenti, contando più volte le chiavi ripetute
+func (client *respConn) exists(keys [][]byte) {
+	values, err := client.lookup(keys)
+	if err != nil {
+		client.writeDataStoreError(err)
+		return
+	}
+	count := 0
+	for _, value := range values {
+		if value != nil {
+			count++
+		}
+	}
+	client.writeInteger(count)
+}
+
+// mget gestisce MGET key [key ...]
+func (client *respConn) mget(keys [][]byte) {
+	values, err := client.lookup(keys)
+	if err != nil {
+		client.writeDataStoreError(err)
+		return
+	}
+	client.writeArrayHeader(len(values))
+	for _, value := range values {
+		client.writeBulk(value)
+	}
+}
+
+// mset gestisce MSET key value [key value ...].
+// Come in Redis, di una chiave ripetuta viene scritto l'ultimo valore.
+func (client *respConn) mset(args [][]byte) {
+	batch := utils.BatchArgs{}
+	positions := make(map[string]int)
+	for i := 0; i < len(args); i += 2 {
+		key := string(args[i])
+		if position, exist := positions[key]; exist {
+			batch.Entries[position].Value = args[i+1]
+			continue
+		}
+		positions[key] = len(batch.Entries)
+		batch.Entries = append(batch.Entries, utils.Args{Key: key, Value: args[i+1]})
+	}
+	var result utils.BatchResult
+	if err := client.server.dataStore.MultiPut(batch, &result); err != nil {
+		client.writeDataStoreError(err)
+		return
+	}
+	for _, entryErr := range result.Errors {
+		if entryErr != "" {
+			client.writeError("ERR " + entryErr)
+			return
+		}
+	}
+	client.writeSimple("OK")
+}
+
+// keys gestisce KEYS pattern, scandendo tutte le chiavi che corrispondono al pattern
+func (client *respConn) keys(pattern string) {
+	matcher, err := globToRegexp(pattern)
+	if err != nil {
+		client.writeError("ERR " + err.Error())
+		return
+	}
+	var keys []string
+	args := utils.ScanArgs{Prefix: globPrefix(pattern)}
+	for {
+		result, err := client.scanPage(args)
+		if err != nil {
+			client.writeDataStoreError(err)
+			return
+		}
+		for _, entry := range result.Entries {
+			if string(entry.Value) != "NOT FOUND" && matcher.MatchString(entry.Key) {
+				keys = append(keys, entry.Key)
+			}
+		}
+		if result.Cursor == "" {
+			break
+		}
+		args.Cursor = result.Cursor
+	}
+	client.writeArrayHeader(len(keys))
+	for _, key := range keys {
+		client.writeBulk([]byte(key))
+	}
+}
+
+// scan gestisce SCAN cursor [MATCH pattern] [COUNT count].
+// Il cursore restituito al client è un numero, associato dalla connessione al cursore della scansione dello store; 0 indica l'inizio o la fine della scansione.
+func (client *respConn) scan(args [][]byte) {
+	cursor, err := strconv.Atoi(string(args[0]))
+	if err != nil || cursor < 0 {
+		client.writeError("ERR invalid cursor")
+		return
+	}
+	pattern := "*"
+	count := respDefaultScanSize
+	for i := 1; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			client.writeError("ERR " + errRESPSyntax.Error())
+			return
+		}
+		switch strings.ToUpper(string(args[i])) {
+		case "MATCH":
+			pattern = string(args[i+1])
+		case "COUNT":
+			count, err = strconv.Atoi(string(args[i+1]))
+			if err != nil || count <= 0 {
+				client.writeError("ERR " + errRESPSyntax.Error())
+				return
+			}
+		default:
+			client.writeError("ERR " + errRESPSyntax.Error())
+			return
+		}
+	}
+	matcher, err := globToRegexp(pattern)
+	if err != nil {
+		client.writeError("ERR " + err.Error())
+		return
+	}
+
+	scanArgs := utils.ScanArgs{Prefix: globPrefix(pattern), Limit: count}
+	if cursor != 0 {
+		storeCursor, ok := client.cursors[cursor]
+		if !ok {
+			client.writeError("ERR invalid cursor")
+			return
+		}
+		delete(client.cursors, cursor)
+		scanArgs.Cursor = storeCursor
+	}
+	result, err := client.scanPage(scanArgs)
+	if err != nil {
+		client.writeDataStoreError(err)
+		return
+	}
+
+	nextCursor := 0
+	if result.Cursor != "" {
+		client.nextCursor++
+		nextCursor = client.nextCursor
+		client.cursors[nextCursor] = result.Cursor
+	}
+	var keys []string
+	for _, entry := range result.Entries {
+		if string(entry.Value) != "NOT FOUND" && matcher.MatchString(entry.Key) {
+			keys = append(keys, entry.Key)
+		}
+	}
+	client.writeArrayHeader(2)
+	client.writeBulk([]byte(strconv.Itoa(nextCursor)))
+	client.writeArrayHeader(len(keys))
+	for _, key := range keys {
+		client.writeBulk([]byte(key))
+	}
+}
+
+// scanPage legge una pagina della scansione dello store, per prefisso se indicato
+func (client *respConn) scanPage(args utils.ScanArgs) (utils.ScanResult, error) {
+	var result utils.ScanResult
+	var err error
+	if args.Prefix != "" {
+		err = client.server.dataStore.ScanPrefix(args, &result)
+	} else {
+		err = client.server.dataStore.Scan(args, &result)
+	}
+	return result, err
+}
+
+// expire gestisce EXPIRE key seconds tramite SetTTL, che aggiorna la scadenza della versione corrente senza riscriverne il valore.
+// Con un TTL non positivo la chiave viene rimossa come con DEL. Restituisce 1 se la chiave esiste, altrimenti 0.
+func (client *respConn) expire(key []byte, seconds []byte) {
+	ttl, err := strconv.Atoi(string(seconds))
+	if err != nil {
+		client.writeError("ERR value is not an integer or out of range")
+		return
+	}
+	if ttl <= 0 {
+		client.del([][]byte{key})
+		return
+	}
+	var result utils.CondResult
+	if err := client.server.dataStore.SetTTL(utils.Args{Key: string(key), TTL: time.Duration(ttl) * time.Second}, &result); err != nil {
+		client.writeDataStoreError(err)
+		return
+	}
+	if !result.Succeeded {
+		client.writeInteger(0)
+		return
+	}
+	client.writeInteger(1)
+}
+
+// globPrefix restituisce la parte iniziale del pattern che non contiene caratteri speciali, usata per limitare la scansione
+func globPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
+
+// globToRegexp converte un pattern glob di Redis (*, ?, [...] e \ per l'escape) nell'espressione regolare corrispondente
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	var builder strings.Builder
+	builder.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			builder.WriteString(".*")
+		case '?':
+			builder.WriteString(".")
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				builder.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			}
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				builder.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "^") {
+				class = "^" + regexp.QuoteMeta(class[1:])
+			} else {
+				class = regexp.QuoteMeta(class)
+			}
+			// Gli intervalli come a-z restano validi, il trattino non è modificato da QuoteMeta
+			builder.WriteString("[" + class + "]")
+			i += end
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	builder.WriteString("$")
+	return regexp.Compile(builder.String())
+}
+
+// writeDataStoreError scrive l'errore di un'operazione del DataStore
+func (client *respConn) writeDataStoreError(err error) {
+	client.writeError("ERR " + err.Error())
+}
+
+// writeSimple scrive una simple string
+func (client *respConn) writeSimple(value string) {
+	_, _ = client.writer.WriteString("+" + value + "\r\n")
+}
+
+// writeError scrive un errore, il cui messaggio non può contenere interruzioni di riga
+func (client *respConn) writeError(message string) {
+	message = strings.NewReplacer("\r", " ", "\n", " ").Replace(message)
+	_, _ = client.writer.WriteString("-" + message + "\r\n")
+}
+
+// writeInteger scrive un intero
+func (client *respConn) writeInteger(value int) {
+	_, _ = client.writer.WriteString(":" + strconv.Itoa(value) + "\r\n")
+}
+
+// writeBulk scrive una bulk string, oppure la bulk string nulla se value è nil
+func (client *respConn) writeBulk(value []byte) {
+	if value == nil {
+		_, _ = client.writer.WriteString("$-1\r\n")
+		return
+	}
+	_, _ = client.writer.WriteString("$" + strconv.Itoa(len(value)) + "\r\n")
+	_, _ = client.writer.Write(value)
+	_, _ = client.writer.WriteString("\r\n")
+}
+
+// writeArrayHeader scrive l'intestazione di un array di n elementi, che vanno scritti di seguito
+func (client *respConn) writeArrayHeader(n int) {
+	_, _ = client.writer.WriteString("*" + strconv.Itoa(n) + "\r\n")
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		name      string
+		pattern   string
+		match     []string
+		dontMatch []string
+	}{
+		{name: "literal", pattern: "user:1", match: []string{"user:1"}, dontMatch: []string{"user:10", "xuser:1"}},
+		{name: "any sequence", pattern: "user:*", match: []string{"user:", "user:1", "user:1:name"}, dontMatch: []string{"users:1"}},
+		{name: "any character", pattern: "h?llo", match: []string{"hello", "hallo"}, dontMatch: []string{"hllo", "heello"}},
+		{name: "class", pattern: "h[ae]llo", match: []string{"hello", "hallo"}, dontMatch: []string{"hillo"}},
+		{name: "negated class", pattern: "h[^e]llo", match: []string{"hallo", "hbllo"}, dontMatch: []string{"hello"}},
+		{name: "range", pattern: "key[0-9]", match: []string{"key0", "key9"}, dontMatch: []string{"keya", "key10"}},
+		{name: "escaped special characters", pattern: `a\*b\?`, match: []string{"a*b?"}, dontMatch: []string{"axb?", "a*bc"}},
+		{name: "unterminated class", pattern: "a[b", match: []string{"a[b"}, dontMatch: []string{"ab"}},
+		{name: "regexp metacharacters", pattern: "a.b+(c)", match: []string{"a.b+(c)"}, dontMatch: []string{"axbb(c)"}},
+		{name: "trailing backslash", pattern: `ab\`, match: []string{"ab"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expression, err := globToRegexp(test.pattern)
+			if err != nil {
+				t.Fatalf("globToRegexp(%q) error = %v", test.pattern, err)
+			}
+			for _, key := range test.match {
+				if !expression.MatchString(key) {
+					t.Errorf("pattern %q does not match %q", test.pattern, key)
+				}
+			}
+			for _, key := range test.dontMatch {
+				if expression.MatchString(key) {
+					t.Errorf("pattern %q matches %q", test.pattern, key)
+				}
+			}
+		})
+	}
+}
+
+func TestReadCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    [][]string // Comandi letti in ordine, dopo i quali la lettura termina con io.EOF oppure con un errore
+		wantErr bool
+	}{
+		{name: "array of bulk strings", input: "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$5\r\nvalue\r\n", want: [][]string{{"SET", "k", "value"}}},
+		{name: "binary bulk string", input: "*2\r\n$4\r\nECHO\r\n$4\r\na\r\nb\r\n", want: [][]string{{"ECHO", "a\r\nb"}}},
+		{name: "empty bulk string", input: "*2\r\n$4\r\nECHO\r\n$0\r\n\r\n", want: [][]string{{"ECHO", ""}}},
+		{name: "empty array", input: "*0\r\n", want: [][]string{{}}},
+		{name: "inline command", input: "GET  key\r\n", want: [][]string{{"GET", "key"}}},
+		{name: "inline command without CR", input: "PING\n", want: [][]string{{"PING"}}},
+		{name: "empty line", input: "\r\n", want: [][]string{{}}},
+		{name: "pipelined commands", input: "*1\r\n$4\r\nPING\r\nGET k\r\n*2\r\n$3\r\nDEL\r\n$1\r\nk\r\n", want: [][]string{{"PING"}, {"GET", "k"}, {"DEL", "k"}}},
+		{name: "invalid multibulk length", input: "*x\r\n", wantErr: true},
+		{name: "too many arguments", input: "*2000000\r\n", wantErr: true},
+		{name: "missing bulk header", input: "*1\r\nPING\r\n", wantErr: true},
+		{name: "negative bulk length", input: "*1\r\n$-1\r\n", wantErr: true},
+		{name: "truncated bulk string", input: "*1\r\n$10\r\nPING\r\n", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &respConn{reader: bufio.NewReader(strings.NewReader(test.input))}
+			for i, want := range test.want {
+				args, err := client.readCommand()
+				if err != nil {
+					t.Fatalf("command %d: readCommand() error = %v", i, err)
+				}
+				if len(args) != len(want) {
+					t.Fatalf("command %d: readCommand() = %q, want %q", i, args, want)
+				}
+				for j := range want {
+					if string(args[j]) != want[j] {
+						t.Fatalf("command %d: readCommand() = %q, want %q", i, args, want)
+					}
+				}
+			}
+
+			_, err := client.readCommand()
+			if test.wantErr {
+				if err == nil || errors.Is(err, io.EOF) {
+					t.Fatalf("readCommand() error = %v, want a protocol error", err)
+				}
+			} else if !errors.Is(err, io.EOF) {
+				t.Fatalf("readCommand() error = %v after the last command, want io.EOF", err)
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net"
 	"net/rpc"
 	"os"
+	"path/filepath"
 	"strconv"
 	"sync"
 	"time"
@@ -21,11 +22,27 @@ var (
 	BaseName         string
 	Container        bool          // Questa variabile distingue tra l'esecuzione con Docker o senza
 	TimeoutDuration  time.Duration // Durata del timeout di inattività
+	MaxVersions      int           // Numero di versioni mantenute nella storia di ogni chiave
+	ConflictPolicy   string        // Politica di risoluzione dei conflitti di default (consistenza causale)
+	PrefixPolicies   string        // Politiche di risoluzione dei conflitti per prefisso di chiave (consistenza causale)
+	CDCDir           string        // Cartella del flusso CDC delle modifiche applicate, vuota se il flusso è disabilitato
+	CDCMaxFileSize   int64         // Dimensione massima in byte di un segmento del flusso CDC
+	CDCMaxFiles      int           // Numero massimo di segmenti del flusso CDC mantenuti, 0 per mantenerli tutti
+	MaxValueSize     int           // Dimensione massima in byte di un valore, 0 se illimitata
+	ChunkSize        int           // Dimensione in byte dei frammenti con cui sono trasferiti i valori più grandi, 0 per non frammentare i valori
+	DocumentMode     bool          // Modalità documento del namespace di default: i valori devono essere documenti JSON
+	BaseHTTPPort     int           // Porta del gateway HTTP/JSON esposto ai client, 0 se il gateway è disabilitato
+	BaseGRPCPort     int           // Porta del server gRPC esposto ai client, 0 se il server gRPC è disabilitato
+	BaseRESPPort     int           // Porta del server RESP (protocollo Redis) esposto ai client, 0 se il server RESP è disabilitato
+	NetRPCEnabled    bool          // Indica se la replica espone ai client il server net/rpc
 )
 
 func init() {
-	// Carica le variabili d'ambiente dal file .env
+	// Carica le variabili d'ambiente dal file .env, cercato anche nella directory superiore (go test esegue i test nella directory del package)
 	err := godotenv.Load()
+	if err != nil {
+		err = godotenv.Load("../.env")
+	}
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
@@ -38,6 +55,20 @@ func init() {
 	Timeout, err := strconv.Atoi(os.Getenv("TIMEOUT"))
 	TimeoutDuration = time.Duration(Timeout) * time.Second
 	ConsistencyType = os.Getenv("CONSISTENCY_TYPE")
+	MaxVersions, _ = strconv.Atoi(os.Getenv("MAX_VERSIONS"))
+	ConflictPolicy = os.Getenv("CONFLICT_POLICY")
+	PrefixPolicies = os.Getenv("CONFLICT_POLICIES")
+	CDCDir = os.Getenv("CDC_DIR")
+	CDCMaxFileSize, _ = strconv.ParseInt(os.Getenv("CDC_MAX_FILE_SIZE"), 10, 64)
+	CDCMaxFiles, _ = strconv.Atoi(os.Getenv("CDC_MAX_FILES"))
+	MaxValueSize, _ = strconv.Atoi(os.Getenv("MAX_VALUE_SIZE"))
+	ChunkSize, _ = strconv.Atoi(os.Getenv("CHUNK_SIZE"))
+	DocumentMode = os.Getenv("DOCUMENT_MODE") == "YES"
+	BaseHTTPPort, _ = strconv.Atoi(os.Getenv("BASE_HTTP_PORT"))
+	BaseGRPCPort, _ = strconv.Atoi(os.Getenv("BASE_GRPC_PORT"))
+	BaseRESPPort, _ = strconv.Atoi(os.Getenv("BASE_RESP_PORT"))
+	// Il server net/rpc è abilitato se non indicato diversamente
+	NetRPCEnabled = os.Getenv("NET_RPC") != "NO"
 	if os.Getenv("CONTAINER") == "YES" {
 		Container = true
 	} else {
@@ -68,10 +99,17 @@ func main() {
 		dbSequential := &DbSequential{
 			ID: serverIndex,
 			DbStore: DbStore{
-				Store: make(map[string]string),
-				mutex: sync.Mutex{},
+				Store:      make(map[string]utils.VersionedValue),
+				History:    make(map[string][]utils.VersionedValue),
+				Siblings:   make(map[string][]utils.VersionedValue),
+				Tombstones: make(map[string]utils.VersionedValue),
+				Paths:      make(map[string]map[string]utils.Version),
+				TTLs:       make(map[string]utils.Version),
+				Indexes:    make(map[string]*SecondaryIndex),
+				mutex:      sync.Mutex{},
 			},
 			MessageQueue: utils.MessageQueue{},
+			Blobs:        NewBlobStore(),
 			Clock: Clock{
 				value: 0,
 				mutex: sync.Mutex{},
@@ -105,17 +143,36 @@ func main() {
 			}
 		}
 
+		// Apre il flusso CDC delle modifiche applicate dalla replica
+		dbSequential.CDC = openCDCLog(serverIndex)
+
+		// Registra il namespace di default e avvia il controllo periodico delle entry scadute
+		dbSequential.Namespaces = NewNamespaces(&dbSequential.DbStore, serverIndex, dbSequential.expire)
+
 		dataStore = dbSequential
 
 	} else if ConsistencyType == "CAUSAL" {
+		// Costruisce le politiche di risoluzione dei conflitti tra scritture concorrenti
+		policies, err := NewConflictPolicies(ConflictPolicy, PrefixPolicies)
+		if err != nil {
+			log.Fatal("Invalid conflict policies in .env: ", err)
+		}
+
 		// crea un server con garanzie di consistenza causale
 		dbCausal := &DbCausal{
 			ID: serverIndex,
 			DbStore: DbStore{
-				Store: make(map[string]string),
-				mutex: sync.Mutex{},
+				Store:      make(map[string]utils.VersionedValue),
+				History:    make(map[string][]utils.VersionedValue),
+				Siblings:   make(map[string][]utils.VersionedValue),
+				Tombstones: make(map[string]utils.VersionedValue),
+				Paths:      make(map[string]map[string]utils.Version),
+				TTLs:       make(map[string]utils.Version),
+				Indexes:    make(map[string]*SecondaryIndex),
+				mutex:      sync.Mutex{},
 			},
 			MessageQueue: utils.VectorMessageQueue{},
+			Blobs:        NewBlobStore(),
 			Clock: VectorClock{
 				value: make([]int, NumReplicas),
 				mutex: sync.Mutex{},
@@ -123,6 +180,8 @@ func main() {
 			Address:            GetServerAddress(serverIndex),
 			Addresses:          []utils.ServerAddress{},
 			AddressToClient:    GetServerAddressToClient(serverIndex),
+			Policies:           policies,
+			CRDTs:              NewCRDTStore(),
 			FIFOQueues:         make(map[int]*utils.VectorMessageQueue),
 			ExpectedNextSeqNum: make(map[int]*NextSeqNum),
 			NextSeqNum: NextSeqNum{
@@ -148,15 +207,48 @@ func main() {
 				dbCausal.Addresses = append(dbCausal.Addresses, newAddress)
 			}
 		}
+
+		// Apre il flusso CDC delle modifiche applicate dalla replica
+		dbCausal.CDC = openCDCLog(serverIndex)
+
+		// Registra il namespace di default e avvia il controllo periodico delle entry scadute
+		dbCausal.Namespaces = NewNamespaces(&dbCausal.DbStore, serverIndex, dbCausal.expire)
+
 		dataStore = dbCausal
 
 	} else {
 		log.Fatal("Invalid CONSISTENCY_TYPE in .env. It must be SEQUENTIAL or CAUSAL.")
 	}
 
+	// Avvia il gateway HTTP/JSON, il server gRPC e il server RESP, se configurati
+	if BaseHTTPPort > 0 {
+		go startHTTPGateway(dataStore, GetHTTPAddress(serverIndex))
+	}
+	if BaseGRPCPort > 0 {
+		go startGRPCServer(dataStore, GetGRPCAddress(serverIndex))
+	}
+	if BaseRESPPort > 0 {
+		go startRESPServer(dataStore, GetRESPAddress(serverIndex))
+	}
+	if !NetRPCEnabled && BaseHTTPPort <= 0 && BaseGRPCPort <= 0 && BaseRESPPort <= 0 {
+		log.Fatal("NET_RPC is NO but none of BASE_HTTP_PORT, BASE_GRPC_PORT and BASE_RESP_PORT is set, clients could not reach the replica")
+	}
+
 	startRPCServer(dataStore)
 }
 
+// openCDCLog apre il flusso CDC della replica in una sottocartella di CDC_DIR, oppure restituisce nil se il flusso è disabilitato
+func openCDCLog(serverIndex int) *CDCLog {
+	if CDCDir == "" {
+		return nil
+	}
+	cdc, err := NewCDCLog(filepath.Join(CDCDir, fmt.Sprintf("%s-%d", BaseName, serverIndex)), CDCMaxFileSize, CDCMaxFiles)
+	if err != nil {
+		log.Fatal("Error while opening CDC log: ", err)
+	}
+	return cdc
+}
+
 // startRPCServer avvia il server RPC, che potrà quindi essere contattato dai client
 func startRPCServer(dataStore DataStore) {
 	// Inizializza il timer di inattività
@@ -173,11 +265,11 @@ func startRPCServer(dataStore DataStore) {
 		<-timer.C
 		fmt.Println("Timeout reached, shutting down server...")
 
-		// Stampa il contenuto del datastore prima di terminare
+		// Riporta la dimensione dei namespace prima di terminare
 		if db, ok := dataStore.(*DbSequential); ok {
-			db.DbStore.printDbStore()
+			logNamespaceUsage(db.Namespaces)
 		} else if db, ok := dataStore.(*DbCausal); ok {
-			db.DbStore.printDbStore()
+			logNamespaceUsage(db.Namespaces)
 		}
 
 		os.Exit(0)
@@ -206,6 +298,11 @@ func startRPCServer(dataStore DataStore) {
 			}
 		}(listener)
 
+		// Senza il server net/rpc la replica serve i client solo tramite i gateway HTTP e gRPC
+		if !NetRPCEnabled {
+			select {}
+		}
+
 		// Registra un nuovo server RPC
 		server := rpc.NewServer()
 		err = server.RegisterName("Datastore", dataStore)
@@ -253,6 +350,11 @@ func startRPCServer(dataStore DataStore) {
 			}
 		}(listener)
 
+		// Senza il server net/rpc la replica serve i client solo tramite i gateway HTTP e gRPC
+		if !NetRPCEnabled {
+			select {}
+		}
+
 		// Registra un nuovo server RPC
 		server := rpc.NewServer()
 		err = server.RegisterName("Datastore", dataStore)
@@ -294,3 +396,30 @@ func GetServerAddressToClient(serverIndex int) utils.ServerAddress {
 		return utils.ServerAddress{IP: "localhost", Port: strconv.Itoa(BasePortToClient + serverIndex)}
 	}
 }
+
+// GetHTTPAddress restituisce l'indirizzo su cui la replica espone il gateway HTTP/JSON
+func GetHTTPAddress(serverIndex int) utils.ServerAddress {
+	if Container {
+		return utils.ServerAddress{IP: BaseName + "-" + strconv.Itoa(serverIndex), Port: strconv.Itoa(BaseHTTPPort)}
+	} else {
+		return utils.ServerAddress{IP: "localhost", Port: strconv.Itoa(BaseHTTPPort + serverIndex)}
+	}
+}
+
+// GetGRPCAddress restituisce l'indirizzo su cui la replica espone il server gRPC
+func GetGRPCAddress(serverIndex int) utils.ServerAddress {
+	if Container {
+		return utils.ServerAddress{IP: BaseName + "-" + strconv.Itoa(serverIndex), Port: strconv.Itoa(BaseGRPCPort)}
+	} else {
+		return utils.ServerAddress{IP: "localhost", Port: strconv.Itoa(BaseGRPCPort + serverIndex)}
+	}
+}
+
+// GetRESPAddress restituisce l'indirizzo su cui la replica espone il server RESP
+func GetRESPAddress(serverIndex int) utils.ServerAddress {
+	if Container {
+		return utils.ServerAddress{IP: BaseName + "-" + strconv.Itoa(serverIndex), Port: strconv.Itoa(BaseRESPPort)}
+	} else {
+		return utils.ServerAddress{IP: "localhost", Port: strconv.Itoa(BaseRESPPort + serverIndex)}
+	}
+}
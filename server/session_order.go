@@ -0,0 +1,120 @@
+package main
+
+import (
+	"dbService/utils"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	sessionOrderTimeout = 5 * time.Second // Tempo massimo di attesa di una richiesta precedente della sessione non ancora ricevuta dalla replica
+	sessionIdleTimeout  = time.Minute     // Intervallo di inattività dopo cui la replica dimentica lo stato di una sessione
+)
+
+// SessionOrders fa sì che la replica esegua le richieste di ogni sessione nell'ordine con cui il client le ha inviate (program order).
+// Il server RPC esegue ogni richiesta in una goroutine distinta, quindi richieste inviate da un client senza attenderne la risposta
+// potrebbero altrimenti essere eseguite in un ordine diverso da quello di invio.
+type SessionOrders struct {
+	sessions map[string]*sessionRequests
+	mutex    sync.Mutex
+}
+
+// sessionRequests tiene traccia delle richieste di una sessione successive a quelle di cui il client ha già ricevuto la risposta
+type sessionRequests struct {
+	received  map[int]bool  // Richieste ricevute dalla replica, in attesa delle precedenti o in esecuzione
+	completed map[int]bool  // Richieste eseguite dalla replica
+	changed   chan struct{} // Chiuso, e sostituito, ogni volta che una richiesta della sessione viene completata
+	floor     int           // Massimo numero di sequenza fino al quale, secondo le richieste ricevute, il client ha ricevuto la risposta di tutte le richieste
+	lastUsed  time.Time
+}
+
+// enter attende che la replica abbia completato le richieste della sessione che precedono quella indicata, e restituisce la funzione con cui segnalarne il completamento,
+// che può essere chiamata più volte. Una richiesta precedente non ancora ricevuta è attesa al più per sessionOrderTimeout: il client potrebbe averla inoltrata a un'altra replica, prima di cambiare replica a causa di un errore di connessione.
+func (orders *SessionOrders) enter(order utils.SessionOrder) func() {
+	if order.Session == "" {
+		return func() {}
+	}
+	deadline := time.Now().Add(sessionOrderTimeout)
+
+	orders.mutex.Lock()
+	requests := orders.session(order)
+	requests.received[order.Seq] = true
+	for {
+		received, missing := false, false
+		for seq := max(order.Completed, requests.floor) + 1; seq < order.Seq; seq++ {
+			if requests.completed[seq] {
+				continue
+			}
+			if requests.received[seq] {
+				received = true
+			} else {
+				missing = true
+			}
+		}
+		expired := !time.Now().Before(deadline)
+		if !received && (!missing || expired) {
+			if missing {
+				log.Printf("Session %s: executing request %d without waiting for the previous requests, not received within %v", order.Session, order.Seq, sessionOrderTimeout)
+			}
+			break
+		}
+
+		changed := requests.changed
+		orders.mutex.Unlock()
+		if expired {
+			<-changed
+		} else {
+			timer := time.NewTimer(time.Until(deadline))
+			select {
+			case <-changed:
+			case <-timer.C:
+			}
+			timer.Stop()
+		}
+		orders.mutex.Lock()
+	}
+	orders.mutex.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			orders.mutex.Lock()
+			defer orders.mutex.Unlock()
+			delete(requests.received, order.Seq)
+			requests.completed[order.Seq] = true
+			requests.lastUsed = time.Now()
+			close(requests.changed)
+			requests.changed = make(chan struct{})
+		})
+	}
+}
+
+// session restituisce lo stato della sessione della richiesta, creandolo se necessario, e rimuove le richieste completate di cui il client ha già ricevuto la risposta.
+// Alla creazione di una nuova sessione vengono rimosse quelle inattive da più di sessionIdleTimeout. Va chiamata con il mutex acquisito.
+func (orders *SessionOrders) session(order utils.SessionOrder) *sessionRequests {
+	if orders.sessions == nil {
+		orders.sessions = make(map[string]*sessionRequests)
+	}
+	requests, exist := orders.sessions[order.Session]
+	if !exist {
+		for id, idle := range orders.sessions {
+			if len(idle.received) == 0 && time.Since(idle.lastUsed) > sessionIdleTimeout {
+				delete(orders.sessions, id)
+			}
+		}
+		requests = &sessionRequests{received: make(map[int]bool), completed: make(map[int]bool), changed: make(chan struct{})}
+		orders.sessions[order.Session] = requests
+	}
+	// Una richiesta inviata prima può arrivare dopo una successiva che indica più richieste completate, quindi si usa il massimo tra quelli ricevuti
+	if order.Completed > requests.floor {
+		requests.floor = order.Completed
+	}
+	for seq := range requests.completed {
+		if seq <= requests.floor {
+			delete(requests.completed, seq)
+		}
+	}
+	requests.lastUsed = time.Now()
+	return requests
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"dbService/utils"
+	"testing"
+	"time"
+)
+
+// blockedFor è l'intervallo dopo cui una richiesta che non è ancora stata eseguita è considerata in attesa
+const blockedFor = 100 * time.Millisecond
+
+// previousRequest è una richiesta della sessione ricevuta dalla replica prima di quella verificata
+type previousRequest struct {
+	seq       int
+	completed int
+	release   bool // Indica se la richiesta viene completata prima dell'invio di quella verificata
+}
+
+func TestEnter(t *testing.T) {
+	tests := []struct {
+		name      string
+		previous  []previousRequest
+		order     utils.SessionOrder
+		wantBlock bool
+	}{
+		{
+			name:  "no session",
+			order: utils.SessionOrder{},
+		},
+		{
+			name:  "first request",
+			order: utils.SessionOrder{Session: "s", Seq: 1},
+		},
+		{
+			name:     "previous request completed",
+			previous: []previousRequest{{seq: 1, release: true}},
+			order:    utils.SessionOrder{Session: "s", Seq: 2},
+		},
+		{
+			name:      "previous request in execution",
+			previous:  []previousRequest{{seq: 1}},
+			order:     utils.SessionOrder{Session: "s", Seq: 2},
+			wantBlock: true,
+		},
+		{
+			name:      "pipelined requests",
+			previous:  []previousRequest{{seq: 1, release: true}, {seq: 2}},
+			order:     utils.SessionOrder{Session: "s", Seq: 3},
+			wantBlock: true,
+		},
+		{
+			name:     "previous request replied to the client",
+			previous: []previousRequest{{seq: 1}},
+			order:    utils.SessionOrder{Session: "s", Seq: 2, Completed: 1},
+		},
+		{
+			name:     "previous request replied according to a later request",
+			previous: []previousRequest{{seq: 1}, {seq: 3, completed: 2}},
+			order:    utils.SessionOrder{Session: "s", Seq: 2},
+		},
+		{
+			name:     "requests of another session",
+			previous: []previousRequest{{seq: 1}},
+			order:    utils.SessionOrder{Session: "other", Seq: 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			orders := &SessionOrders{}
+			var pending []func()
+			for _, request := range test.previous {
+				release := orders.enter(utils.SessionOrder{Session: "s", Seq: request.seq, Completed: request.completed})
+				if request.release {
+					release()
+				} else {
+					pending = append(pending, release)
+				}
+			}
+
+			entered := make(chan func(), 1)
+			go func() {
+				entered <- orders.enter(test.order)
+			}()
+
+			select {
+			case release := <-entered:
+				if test.wantBlock {
+					t.Fatalf("enter(%+v) did not wait for the previous requests", test.order)
+				}
+				release()
+			case <-time.After(blockedFor):
+				if !test.wantBlock {
+					t.Fatalf("enter(%+v) is waiting, want no wait", test.order)
+				}
+				for _, release := range pending {
+					release()
+				}
+				select {
+				case release := <-entered:
+					release()
+				case <-time.After(time.Second):
+					t.Fatalf("enter(%+v) is still waiting after the previous requests completed", test.order)
+				}
+			}
+		})
+	}
+}
+
+func TestEnterMissingRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		arrives bool // Indica se la richiesta mancante arriva alla replica ed è completata
+	}{
+		{name: "missing request arrives", arrives: true},
+		{name: "missing request never arrives"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			orders := &SessionOrders{}
+			start := time.Now()
+			entered := make(chan func(), 1)
+			go func() {
+				entered <- orders.enter(utils.SessionOrder{Session: "s", Seq: 2})
+			}()
+
+			select {
+			case <-entered:
+				t.Fatal("request 2 did not wait for the missing request 1")
+			case <-time.After(blockedFor):
+			}
+			if test.arrives {
+				orders.enter(utils.SessionOrder{Session: "s", Seq: 1})()
+			}
+
+			select {
+			case release := <-entered:
+				release()
+				if elapsed := time.Since(start); !test.arrives && elapsed < sessionOrderTimeout {
+					t.Fatalf("request 2 executed after %v, want at least %v", elapsed, sessionOrderTimeout)
+				}
+			case <-time.After(sessionOrderTimeout + time.Second):
+				t.Fatal("request 2 is still waiting")
+			}
+		})
+	}
+}
+
+func TestEnterReleaseTwice(t *testing.T) {
+	orders := &SessionOrders{}
+	release := orders.enter(utils.SessionOrder{Session: "s", Seq: 1})
+	release()
+	release()
+
+	entered := make(chan func(), 1)
+	go func() {
+		entered <- orders.enter(utils.SessionOrder{Session: "s", Seq: 2})
+	}()
+	select {
+	case release := <-entered:
+		release()
+	case <-time.After(time.Second):
+		t.Fatal("request 2 is waiting for request 1, already completed")
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"dbService/utils"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	watchHistorySize    = 1000             // Numero di modifiche mantenute per permettere di riprendere un'osservazione
+	defaultWatchTimeout = 30 * time.Second // Tempo massimo di attesa di una Watch, se non indicato dal client
+)
+
+// WatchHub mantiene le ultime modifiche applicate dalla replica e risveglia le richieste di Watch in attesa.
+// Le revisioni sono locali alla replica: un client che riprende un'osservazione da una revisione deve contattare la stessa replica.
+// Per riprenderla su un'altra replica il client indica invece la versione restituita dalla Watch precedente:
+// tutte le repliche applicano le scritture nello stesso ordine totale (sequenziale) o rispettando le relazioni causa-effetto (causale),
+// quindi le modifiche non ancora osservate sono quelle prodotte da scritture non incluse nella versione.
+type WatchHub struct {
+	events       []utils.WatchEvent // Ultime watchHistorySize modifiche, in ordine di revisione
+	nextRevision int                // Revisione da assegnare alla prossima modifica
+	applied      utils.Version      // Versione di tutte le modifiche pubblicate
+	evicted      utils.Version      // Versione delle modifiche rimosse dalla storia, con cui una Watch non può più riprendere
+	notify       chan struct{}      // Chiuso e sostituito a ogni modifica, per risvegliare le Watch in attesa
+	mutex        sync.Mutex
+}
+
+// publish registra una modifica applicata dallo store e risveglia le Watch in attesa
+func (hub *WatchHub) publish(event utils.WatchEvent) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	hub.init()
+
+	event.Revision = hub.nextRevision
+	hub.nextRevision++
+	hub.events = append(hub.events, event)
+	hub.applied = hub.applied.Merge(event.Version)
+	if len(hub.events) > watchHistorySize {
+		for _, evicted := range hub.events[:len(hub.events)-watchHistorySize] {
+			hub.evicted = hub.evicted.Merge(evicted.Version)
+		}
+		hub.events = hub.events[len(hub.events)-watchHistorySize:]
+	}
+
+	close(hub.notify)
+	hub.notify = make(chan struct{})
+}
+
+// wait attende le modifiche richieste, a partire dalla revisione o dalla versione indicata, finché almeno una non è disponibile o scade il timeout
+func (hub *WatchHub) wait(args utils.WatchArgs) (utils.WatchResult, error) {
+	timeout := args.Timeout
+	if timeout <= 0 {
+		timeout = defaultWatchTimeout
+	}
+	deadline := time.After(timeout)
+
+	hub.mutex.Lock()
+	hub.init()
+	from := args.FromRevision
+	if from <= 0 {
+		from = hub.nextRevision
+	}
+	hub.mutex.Unlock()
+
+	for {
+		hub.mutex.Lock()
+		if args.FromVersion != nil {
+			// Le modifiche rimosse dalla storia devono essere già state osservate dal client
+			if hasVersion(hub.evicted) && !hub.evicted.LessOrEqual(*args.FromVersion) {
+				hub.mutex.Unlock()
+				return utils.WatchResult{}, fmt.Errorf("version %s is no longer available on replica history", *args.FromVersion)
+			}
+		} else if len(hub.events) > 0 && from < hub.events[0].Revision {
+			hub.mutex.Unlock()
+			return utils.WatchResult{}, fmt.Errorf("revision %d is no longer available, oldest available revision is %d", from, hub.events[0].Revision)
+		}
+
+		result := utils.WatchResult{Revision: hub.nextRevision, Version: hub.applied}
+		for _, event := range hub.events {
+			if !matchesWatch(args, event.Key) {
+				continue
+			}
+			if args.FromVersion != nil && !event.Version.LessOrEqual(*args.FromVersion) || args.FromVersion == nil && event.Revision >= from {
+				result.Events = append(result.Events, event)
+			}
+		}
+		notify := hub.notify
+		hub.mutex.Unlock()
+
+		if len(result.Events) > 0 {
+			return result, nil
+		}
+
+		// Attende la prossima modifica oppure lo scadere del timeout
+		select {
+		case <-notify:
+		case <-deadline:
+			return result, nil
+		}
+	}
+}
+
+// init inizializza il canale di notifica e la prima revisione.
+// Deve essere invocata mantenendo il lock.
+func (hub *WatchHub) init() {
+	if hub.notify == nil {
+		hub.notify = make(chan struct{})
+		hub.nextRevision = 1
+	}
+}
+
+// matchesWatch indica se la chiave modificata è tra quelle osservate dalla richiesta
+func matchesWatch(args utils.WatchArgs, key string) bool {
+	if args.Prefix {
+		return strings.HasPrefix(key, args.Key)
+	}
+	return key == args.Key
+}
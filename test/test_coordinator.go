@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
+	"dbService/dbclient"
 	"dbService/utils"
+	"errors"
 	"fmt"
 	"github.com/joho/godotenv"
 	"log"
 	"math/rand"
-	"net/rpc"
 	"os"
 	"strconv"
 	"sync"
@@ -14,9 +16,9 @@ import (
 )
 
 type Client struct {
-	ID        int         // ID univoco del client
-	rpcClient *rpc.Client // Client RPC che permette di interagire con il datastore
-	requests  []Request   // Lista di richieste che il client inoltra alla replica a cui è connesso
+	ID       int              // ID univoco del client
+	dbClient *dbclient.Client // Client che permette di interagire con il datastore
+	requests []Request        // Lista di richieste che il client inoltra alla replica a cui è connesso
 }
 
 type Request struct {
@@ -32,6 +34,7 @@ var (
 	Test             string
 	BaseName         string
 	Container        bool
+	Pipelined        bool // Indica se i client inviano le richieste senza attendere la risposta delle precedenti
 )
 
 func init() {
@@ -65,8 +68,12 @@ func main() {
 		} else if Test == "COMPLEX" {
 			fmt.Println("Running complex sequential test...")
 			runComplexSequentialTest()
+		} else if Test == "PIPELINED" {
+			fmt.Println("Running complex sequential test with pipelined requests...")
+			Pipelined = true
+			runComplexSequentialTest()
 		} else {
-			log.Fatal("Wrong test required, please use SIMPLE or COMPLEX")
+			log.Fatal("Wrong test required, please use SIMPLE, COMPLEX or PIPELINED")
 		}
 	} else if ConsistencyType == "CAUSAL" {
 		// Esegue i test per la consistenza causale
@@ -76,8 +83,12 @@ func main() {
 		} else if Test == "COMPLEX" {
 			fmt.Println("Running complex causal test...")
 			runComplexCausalTest()
+		} else if Test == "PIPELINED" {
+			fmt.Println("Running complex causal test with pipelined requests...")
+			Pipelined = true
+			runComplexCausalTest()
 		} else {
-			log.Fatal("Wrong test required, please use SIMPLE or COMPLEX")
+			log.Fatal("Wrong test required, please use SIMPLE, COMPLEX or PIPELINED")
 		}
 	} else {
 		log.Fatal("Wrong consistency required, please use SEQUENTIAL or CAUSAL")
@@ -90,16 +101,66 @@ func (client *Client) LaunchRequest(wg *sync.WaitGroup) {
 	for _, request := range client.requests {
 		// delay random prima di richiedere l'operazione successiva del client corrente
 		randomDelay()
-		args := utils.Args{
-			Key:   request.key,
-			Value: request.value,
+		ctx := context.Background()
+
+		// Richiede l'operazione al db
+		var err error
+		switch request.op {
+		case utils.GET:
+			var reply utils.Result
+			reply, err = client.dbClient.Get(ctx, request.key)
+			if errors.Is(err, dbclient.ErrNotFound) {
+				err = nil
+			}
+			if err == nil {
+				fmt.Printf("[CLIENT %d] GET key %s value %s\n", client.ID, request.key, reply.Value)
+			}
+		case utils.PUT:
+			err = client.dbClient.Put(ctx, request.key, []byte(request.value))
+		case utils.DELETE:
+			err = client.dbClient.Delete(ctx, request.key)
+		default:
+			log.Fatal("Unsupported op: ", request.op)
 		}
+		if err != nil {
+			log.Fatal("Error while executing op: ", err)
+		}
+	}
+
+	// Attende 10 secondi così da garantire che tutte le repliche abbiano terminato di propagare i messaggi
+	time.Sleep(time.Duration(10000) * time.Millisecond)
 
-		var reply utils.Result
+}
+
+// LaunchPipelinedRequest esegue la lista di richieste definita per ogni client, come LaunchRequest.
+// Ogni richiesta è però inviata senza attendere la risposta della precedente: la replica le esegue comunque nell'ordine di invio.
+func (client *Client) LaunchPipelinedRequest(wg *sync.WaitGroup) {
+	defer wg.Done()
+	ctx := context.Background()
+	futures := make([]*dbclient.Future[utils.Result], len(client.requests))
+	for i, request := range client.requests {
+		// delay random prima di richiedere l'operazione successiva del client corrente
+		randomDelay()
 
-		var serviceMethod string = string("Datastore." + request.op)
 		// Richiede l'operazione al db
-		err := client.rpcClient.Call(serviceMethod, args, &reply)
+		switch request.op {
+		case utils.GET:
+			futures[i] = client.dbClient.GetAsync(ctx, request.key)
+		case utils.PUT:
+			futures[i] = client.dbClient.PutAsync(ctx, request.key, []byte(request.value))
+		case utils.DELETE:
+			futures[i] = client.dbClient.DeleteAsync(ctx, request.key)
+		default:
+			log.Fatal("Unsupported op: ", request.op)
+		}
+	}
+
+	// Attende le risposte, nell'ordine di invio delle richieste
+	for i, request := range client.requests {
+		reply, err := futures[i].Wait()
+		if request.op == utils.GET && errors.Is(err, dbclient.ErrNotFound) {
+			err = nil
+		}
 		if err != nil {
 			log.Fatal("Error while executing op: ", err)
 		}
@@ -124,16 +185,16 @@ func createClients() []*Client {
 			serverAddress = utils.ServerAddress{IP: "localhost", Port: strconv.Itoa(BasePortToClient + i)}
 		}
 		// Il client si collega al server RPC
-		rpcClient, err := rpc.Dial("tcp", serverAddress.GetFullAddress())
+		dbClient, err := dbclient.Dial(context.Background(), dbclient.Config{Endpoints: []string{serverAddress.GetFullAddress()}})
 		if err != nil {
 			log.Fatal("Error in dialing: ", err)
 		}
 
 		fmt.Printf("Client %d connesso al server %s\n", i, serverAddress.GetFullAddress())
 		client := &Client{
-			ID:        i,
-			rpcClient: rpcClient,
-			requests:  nil,
+			ID:       i,
+			dbClient: dbClient,
+			requests: nil,
 		}
 
 		//aggiunge il client alla lista dei client
@@ -147,7 +208,11 @@ func launchClients(clients []*Client) {
 	var wg sync.WaitGroup
 	for i := 0; i < NumReplicas; i++ {
 		wg.Add(1)
-		go clients[i].LaunchRequest(&wg)
+		if Pipelined {
+			go clients[i].LaunchPipelinedRequest(&wg)
+		} else {
+			go clients[i].LaunchRequest(&wg)
+		}
 	}
 
 	// Attende che i diversi client abbiano inviato le operazioni richieste
@@ -155,7 +220,7 @@ func launchClients(clients []*Client) {
 
 	// Chiude le connessioni dei client
 	for i := 0; i < NumReplicas; i++ {
-		err := clients[i].rpcClient.Close()
+		err := clients[i].dbClient.Close()
 		if err != nil {
 			log.Fatal("Error while closing connection:", err)
 		}
@@ -0,0 +1,103 @@
+package utils
+
+import "time"
+
+// StatusArgs rappresenta la richiesta dello stato interno di una replica
+type StatusArgs struct{}
+
+// ReplicaStatus descrive lo stato interno di una replica, restituito dalla RPC di amministrazione Status
+type ReplicaStatus struct {
+	ServerID     int              `json:"server_id"`
+	Consistency  string           `json:"consistency"`            // SEQUENTIAL o CAUSAL
+	Clock        int              `json:"clock,omitempty"`        // Clock scalare di Lamport (consistenza sequenziale)
+	VectorClock  []int            `json:"vector_clock,omitempty"` // Clock vettoriale (consistenza causale)
+	NextSeqNum   int              `json:"next_seq_num"`           // Numero di sequenza che sarà assegnato al prossimo messaggio inviato alle altre repliche
+	MessageQueue QueueStatus      `json:"message_queue"`          // Messaggi ricevuti e non ancora consegnati all'applicativo
+	Peers        []PeerStatus     `json:"peers"`                  // Stato della comunicazione con ogni altra replica, in ordine di ID
+	Namespaces   []NamespaceUsage `json:"namespaces"`             // Dimensione dei namespace presenti sulla replica, in ordine lessicografico
+}
+
+// QueueStatus descrive una coda di messaggi di una replica
+type QueueStatus struct {
+	Length int            `json:"length"`
+	Head   *QueuedMessage `json:"head,omitempty"` // Messaggio in testa alla coda, assente se la coda è vuota
+}
+
+// QueuedMessage descrive un messaggio in attesa in una coda
+type QueuedMessage struct {
+	Type        MessageType `json:"type,omitempty"` // REQUEST o ACK (consistenza sequenziale)
+	Op          Operation   `json:"op"`
+	Namespace   string      `json:"namespace,omitempty"`
+	Key         string      `json:"key,omitempty"`
+	ServerID    int         `json:"server_id"`              // ID della replica che ha inviato il messaggio
+	MessageID   int         `json:"message_id,omitempty"`   // ID della REQUEST a cui il messaggio si riferisce (consistenza sequenziale)
+	Clock       int         `json:"clock,omitempty"`        // Clock scalare del messaggio (consistenza sequenziale)
+	VectorClock []int       `json:"vector_clock,omitempty"` // Clock vettoriale del messaggio (consistenza causale)
+	SeqNum      int         `json:"seq_num"`
+}
+
+// PeerStatus descrive la comunicazione di una replica con un'altra replica
+type PeerStatus struct {
+	ServerID       int         `json:"server_id"`
+	Address        string      `json:"address"`
+	ExpectedSeqNum int         `json:"expected_seq_num"`        // Numero di sequenza del prossimo messaggio atteso dalla replica (comunicazione FIFO order)
+	FIFOQueue      QueueStatus `json:"fifo_queue"`              // Messaggi ricevuti fuori ordine, in attesa di quelli con numero di sequenza precedente
+	LastReceived   *time.Time  `json:"last_received,omitempty"` // Istante di ricezione dell'ultimo messaggio, nil se non ne sono stati ricevuti
+}
+
+// NamespaceUsage descrive la dimensione di un namespace
+type NamespaceUsage struct {
+	Name string `json:"name"`
+	Keys int    `json:"keys"`
+	Size int64  `json:"size"` // Dimensione in byte di chiavi e valori
+}
+
+// DumpArgs rappresenta la richiesta del contenuto di un namespace di una replica
+type DumpArgs struct {
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// DumpResult contiene tutte le entry di un namespace di una replica, in ordine lessicografico
+type DumpResult struct {
+	Entries []Result
+}
+
+// Status restituisce la lunghezza della coda e il messaggio in testa
+func (mq *MessageQueue) Status() QueueStatus {
+	mq.mutex.Lock()
+	defer mq.mutex.Unlock()
+	status := QueueStatus{Length: len(mq.messages)}
+	if len(mq.messages) > 0 {
+		head := mq.messages[0]
+		status.Head = &QueuedMessage{
+			Type:      head.Type,
+			Op:        head.Op,
+			Namespace: head.Namespace,
+			Key:       head.Key,
+			ServerID:  head.ServerID,
+			MessageID: head.MessageID.ID,
+			Clock:     head.Clock,
+			SeqNum:    head.SeqNum,
+		}
+	}
+	return status
+}
+
+// Status restituisce la lunghezza della coda e il messaggio in testa
+func (mq *VectorMessageQueue) Status() QueueStatus {
+	mq.mutex.Lock()
+	defer mq.mutex.Unlock()
+	status := QueueStatus{Length: len(mq.messages)}
+	if len(mq.messages) > 0 {
+		head := mq.messages[0]
+		status.Head = &QueuedMessage{
+			Op:          head.Op,
+			Namespace:   head.Namespace,
+			Key:         head.Key,
+			ServerID:    head.ServerID,
+			VectorClock: append([]int(nil), head.Clock...),
+			SeqNum:      head.SeqNum,
+		}
+	}
+	return status
+}
@@ -0,0 +1,36 @@
+package utils
+
+// BackupArgs rappresenta la richiesta di una snapshot di backup di una replica
+type BackupArgs struct{}
+
+// BackupResult contiene una snapshot consistente di tutti i namespace di una replica, presa senza interrompere le scritture.
+// Cut individua le scritture incluse nella snapshot: con consistenza sequenziale è la versione dell'ultima scrittura applicata nell'ordine totale,
+// comune a tutte le repliche, mentre con consistenza causale è il clock vettoriale delle scritture applicate dalla replica.
+type BackupResult struct {
+	ServerID    int
+	Consistency string // SEQUENTIAL o CAUSAL
+	Cut         Version
+	TakenAt     int64             // Istante della snapshot in millisecondi
+	Namespaces  []BackupNamespace // Namespace presenti sulla replica, in ordine lessicografico
+	CRDTs       *BackupCRDTs      // Valori dei tipi di dato CRDT, presenti solo con consistenza causale
+}
+
+// BackupNamespace contiene la configurazione, gli indici secondari e le entry di un namespace incluso in una snapshot di backup
+type BackupNamespace struct {
+	Config  NamespaceArgs
+	Indexes []IndexArgs // Definizioni degli indici secondari del namespace, in ordine lessicografico
+	Entries []Result    // Entry del namespace, in ordine lessicografico
+}
+
+// BackupCRDTs contiene i valori correnti dei tipi di dato CRDT di una replica, inclusi in una snapshot di backup
+type BackupCRDTs struct {
+	Counters  map[string]int                 `json:"counters,omitempty"`
+	Sets      map[string][]string            `json:"sets,omitempty"`      // Elementi di ogni OR-set, in ordine lessicografico
+	Registers map[string][]byte              `json:"registers,omitempty"` // Valore corrente di ogni registro, codificato in base64
+	Maps      map[string]map[string][]string `json:"maps,omitempty"`      // Valori di ogni campo delle mappe, più di uno per i campi scritti in concorrenza
+}
+
+// Includes indica se la scrittura con la versione indicata è inclusa nella snapshot, ossia se precede o coincide con Cut
+func (backup *BackupResult) Includes(version Version) bool {
+	return version.LessOrEqual(backup.Cut)
+}
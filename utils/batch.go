@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"errors"
+)
+
+// Operazioni su più chiavi, trasportate da un unico messaggio
+const (
+	MULTI_GET    Operation = "MultiGet"
+	MULTI_PUT    Operation = "MultiPut"
+	MULTI_DELETE Operation = "MultiDelete"
+)
+
+// BatchArgs rappresenta una richiesta su più chiavi.
+// Per MultiPut ogni elemento indica chiave, valore ed eventuale TTL, per MultiGet e MultiDelete è sufficiente la chiave.
+type BatchArgs struct {
+	Namespace string // Namespace di tutte le chiavi della richiesta, il campo Namespace dei singoli elementi è ignorato
+	Entries   []Args
+	After     []int        // Clock della sessione del client: la replica esegue la richiesta solo dopo aver applicato le scritture che esso include (solo consistenza causale)
+	Order     SessionOrder // Posizione della richiesta nella sessione del client, con cui la replica ne rispetta l'ordine di invio
+}
+
+// BatchEntry rappresenta una singola scrittura all'interno di un messaggio di MultiPut o MultiDelete
+type BatchEntry struct {
+	Key       string `json:"key"`
+	Value     []byte `json:"value"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+	Context   []int  `json:"context,omitempty"`
+}
+
+// BatchResult contiene il risultato di una richiesta su più chiavi.
+// Results ed Errors sono allineati con gli elementi della richiesta: Errors[i] è vuoto se l'operazione sulla chiave i-esima è andata a buon fine.
+type BatchResult struct {
+	Results []Result
+	Errors  []string
+}
+
+// ErrEmptyKey è l'errore associato a un elemento della richiesta privo di chiave
+var ErrEmptyKey = errors.New("empty key")
+
+// ErrDuplicateKey è l'errore associato a un elemento di una MultiPut o di una MultiDelete con la stessa chiave di un elemento precedente
+var ErrDuplicateKey = errors.New("duplicate key in the request")
+
+// Validate controlla ogni elemento della richiesta e restituisce gli errori allineati con gli elementi.
+// valid indica se almeno un elemento può essere eseguito.
+func (args *BatchArgs) Validate() (errs []string, valid bool) {
+	errs = make([]string, len(args.Entries))
+	for i, entry := range args.Entries {
+		if entry.Key == "" {
+			errs[i] = ErrEmptyKey.Error()
+		} else {
+			valid = true
+		}
+	}
+	return errs, valid
+}
+
+// ValidateWrites controlla gli elementi di una MultiPut o di una MultiDelete come Validate, e rifiuta gli elementi con la stessa chiave di un elemento precedente.
+// Tutte le scritture della richiesta assumono la stessa versione, quindi due scritture della stessa chiave non sarebbero ordinate tra loro.
+func (args *BatchArgs) ValidateWrites() (errs []string, valid bool) {
+	errs = make([]string, len(args.Entries))
+	seen := make(map[string]bool)
+	for i, entry := range args.Entries {
+		if entry.Key == "" {
+			errs[i] = ErrEmptyKey.Error()
+		} else if seen[entry.Key] {
+			errs[i] = ErrDuplicateKey.Error() + ": " + entry.Key
+		} else {
+			seen[entry.Key] = true
+			valid = true
+		}
+	}
+	return errs, valid
+}
+
+// BatchEntries converte gli elementi validi della richiesta nelle scritture da propagare
+func (args *BatchArgs) BatchEntries(errs []string) []BatchEntry {
+	var entries []BatchEntry
+	for i, entry := range args.Entries {
+		if errs[i] == "" {
+			entries = append(entries, BatchEntry{Key: entry.Key, Value: entry.Value, ExpiresAt: entry.ExpiresAt(), Context: entry.Context})
+		}
+	}
+	return entries
+}
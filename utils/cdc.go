@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	CDCSegmentExt   = ".ndjson"   // Estensione dei file di segmento del flusso CDC
+	CDCConsumersDir = "consumers" // Cartella, interna a quella del flusso CDC, con gli offset salvati dai consumer
+)
+
+// CDCRecord descrive una modifica applicata allo store da una replica.
+// I record sono scritti in formato JSON, uno per riga, nell'ordine in cui la replica applica le modifiche.
+type CDCRecord struct {
+	Offset      int64     `json:"offset"` // Posizione del record nel flusso della replica, cresce di 1 a ogni record
+	Op          Operation `json:"op"`     // Operazione applicata (PUT, DELETE, EXPIRE, un'operazione su un documento, CRDT o di amministrazione dei namespace e degli indici)
+	Namespace   string    `json:"namespace,omitempty"`
+	Key         string    `json:"key"`
+	Path        string    `json:"path,omitempty"`         // Percorso del documento scritto o rimosso (SET_PATH e DELETE_PATH)
+	Value       []byte    `json:"value,omitempty"`        // Valore scritto, codificato in base64
+	Clock       int       `json:"clock,omitempty"`        // Timestamp di Lamport della scrittura (consistenza sequenziale)
+	VectorClock []int     `json:"vector_clock,omitempty"` // Clock vettoriale della scrittura (consistenza causale)
+	ServerID    int       `json:"origin"`                 // ID della replica che ha originato la scrittura
+	ExpiresAt   int64     `json:"expires_at,omitempty"`
+	CRDT        *CRDTOp   `json:"crdt,omitempty"`
+	// Configurazione del namespace creato (CREATE_NAMESPACE)
+	NamespaceConfig *NamespaceArgs `json:"namespace_config,omitempty"`
+	Index           *IndexArgs     `json:"index,omitempty"` // Definizione dell'indice creato (CREATE_INDEX), la chiave del record è il nome dell'indice
+	AppliedAt       int64          `json:"applied_at"`      // Istante in cui la replica ha applicato la modifica, in millisecondi
+}
+
+// Version restituisce la versione della scrittura che ha prodotto il record
+func (record CDCRecord) Version() Version {
+	return Version{Clock: record.Clock, ServerID: record.ServerID, VectorClock: record.VectorClock}
+}
+
+// CDCSegments restituisce i file di segmento presenti nella cartella del flusso CDC, ordinati per offset del primo record.
+// Il nome di ogni segmento è l'offset del suo primo record.
+func CDCSegments(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var segments []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), CDCSegmentExt) {
+			segments = append(segments, entry.Name())
+		}
+	}
+	sort.Strings(segments)
+	return segments, nil
+}
+
+// CDCSegmentOffset restituisce l'offset del primo record del segmento indicato
+func CDCSegmentOffset(segment string) (int64, error) {
+	return strconv.ParseInt(strings.TrimSuffix(filepath.Base(segment), CDCSegmentExt), 10, 64)
+}
+
+// CDCSegmentName restituisce il nome del segmento il cui primo record ha l'offset indicato.
+// L'offset è completato con zeri, così che l'ordine lessicografico dei segmenti coincida con quello degli offset.
+func CDCSegmentName(offset int64) string {
+	return fmt.Sprintf("%020d%s", offset, CDCSegmentExt)
+}
+
+// ReadCDC legge dalla cartella del flusso CDC al più limit record, a partire dall'offset indicato (tutti i record se limit non è positivo).
+// Se i segmenti che contenevano l'offset sono già stati rimossi dalla rotazione, la lettura riprende dal record più vecchio disponibile.
+func ReadCDC(dir string, from int64, limit int) ([]CDCRecord, error) {
+	segments, err := CDCSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []CDCRecord
+	for i, segment := range segments {
+		// Salta i segmenti i cui record precedono tutti l'offset richiesto
+		if i+1 < len(segments) {
+			next, err := CDCSegmentOffset(segments[i+1])
+			if err == nil && next <= from {
+				continue
+			}
+		}
+
+		file, err := os.Open(filepath.Join(dir, segment))
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			var record CDCRecord
+			// Una riga incompleta (scrittura interrotta) chiude il segmento
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				break
+			}
+			if record.Offset < from {
+				continue
+			}
+			records = append(records, record)
+			if limit > 0 && len(records) >= limit {
+				file.Close()
+				return records, nil
+			}
+		}
+		file.Close()
+	}
+	return records, nil
+}
+
+// LoadCDCCheckpoint restituisce l'offset del prossimo record da leggere salvato dal consumer indicato, 0 se il consumer non ha mai salvato un offset
+func LoadCDCCheckpoint(dir string, consumer string) (int64, error) {
+	data, err := os.ReadFile(filepath.Join(dir, CDCConsumersDir, consumer))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// SaveCDCCheckpoint salva l'offset del prossimo record da leggere per il consumer indicato.
+// Il file è scritto in una copia temporanea e poi rinominato, così che un'interruzione non lasci un offset parziale.
+func SaveCDCCheckpoint(dir string, consumer string, offset int64) error {
+	consumersDir := filepath.Join(dir, CDCConsumersDir)
+	if err := os.MkdirAll(consumersDir, 0755); err != nil {
+		return err
+	}
+	tmp := filepath.Join(consumersDir, consumer+".tmp")
+	if err := os.WriteFile(tmp, []byte(strconv.FormatInt(offset, 10)+"\n"), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(consumersDir, consumer))
+}
@@ -0,0 +1,26 @@
+package utils
+
+// CHUNK è l'operazione dei messaggi che trasportano un frammento di un valore di grandi dimensioni.
+// I frammenti sono inviati prima del messaggio che scrive il valore, e non entrano nelle code di ordinamento.
+const CHUNK Operation = "Chunk"
+
+// Chunk rappresenta un frammento di un valore trasferito tra le repliche
+type Chunk struct {
+	BlobID string `json:"blob_id"` // Identificatore del valore a cui appartiene il frammento
+	Offset int    `json:"offset"`  // Posizione del frammento all'interno del valore
+	Size   int    `json:"size"`    // Dimensione complessiva del valore
+	Data   []byte `json:"data"`
+}
+
+// SplitChunks divide il valore in frammenti di al più chunkSize byte
+func SplitChunks(blobID string, value []byte, chunkSize int) []Chunk {
+	var chunks []Chunk
+	for offset := 0; offset < len(value); offset += chunkSize {
+		end := offset + chunkSize
+		if end > len(value) {
+			end = len(value)
+		}
+		chunks = append(chunks, Chunk{BlobID: blobID, Offset: offset, Size: len(value), Data: value[offset:end]})
+	}
+	return chunks
+}
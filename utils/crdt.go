@@ -0,0 +1,59 @@
+package utils
+
+// Operazioni sui tipi di dato CRDT, propagate tramite multicast causalmente ordinato
+const (
+	INCREMENT    Operation = "Increment"
+	SET_ADD      Operation = "SetAdd"
+	SET_REMOVE   Operation = "SetRemove"
+	REGISTER_SET Operation = "RegisterSet"
+	MAP_PUT      Operation = "MapPut"
+	MAP_REMOVE   Operation = "MapRemove"
+)
+
+// CRDTOp contiene i parametri di un'operazione su un tipo di dato CRDT.
+// Viene calcolata dalla replica che riceve la richiesta del client, e applicata identica da tutte le repliche al momento della delivery.
+type CRDTOp struct {
+	Delta    int      `json:"delta,omitempty"`    // Incremento (o decremento, se negativo) del PN-counter
+	Element  string   `json:"element,omitempty"`  // Elemento dell'OR-set
+	Tag      string   `json:"tag,omitempty"`      // Tag univoco associato all'aggiunta di un elemento all'OR-set
+	Observed []string `json:"observed,omitempty"` // Tag dell'elemento osservati dalla replica al momento della rimozione
+	Field    string   `json:"field,omitempty"`    // Campo della mappa multi-valore
+}
+
+// CounterArgs rappresenta la richiesta di incremento di un PN-counter
+type CounterArgs struct {
+	Key   string
+	Delta int
+}
+
+// CounterResult contiene il valore corrente di un PN-counter
+type CounterResult struct {
+	Key   string
+	Value int
+}
+
+// SetArgs rappresenta la richiesta di aggiunta o rimozione di un elemento da un OR-set
+type SetArgs struct {
+	Key     string
+	Element string
+}
+
+// SetResult contiene gli elementi correnti di un OR-set, in ordine lessicografico
+type SetResult struct {
+	Key      string
+	Elements []string
+}
+
+// MapArgs rappresenta la richiesta di scrittura o rimozione di un campo di una mappa multi-valore
+type MapArgs struct {
+	Key   string
+	Field string
+	Value string
+}
+
+// MapResult contiene i campi correnti di una mappa multi-valore.
+// Un campo scritto in concorrenza da più repliche ha più valori, in ordine lessicografico.
+type MapResult struct {
+	Key    string
+	Fields map[string][]string
+}
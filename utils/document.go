@@ -0,0 +1,15 @@
+package utils
+
+// Operazioni sui documenti JSON, propagate come operazioni sul percorso indicato e non come valori interi
+const (
+	SET_PATH    Operation = "SetPath"
+	DELETE_PATH Operation = "DeletePath"
+)
+
+// PathArgs rappresenta una richiesta di lettura o scrittura di un percorso all'interno di un documento JSON
+type PathArgs struct {
+	Namespace string
+	Key       string
+	Path      string // JSON pointer (RFC 6901) del valore all'interno del documento, vuoto per indicare l'intero documento
+	Value     []byte // Valore JSON da scrivere nel percorso, presente solo per SetPath
+}
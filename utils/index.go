@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+// Operazioni sugli indici secondari. CREATE_INDEX e DROP_INDEX sono propagate secondo il protocollo di consistenza attivo,
+// QUERY_INDEX è una lettura e con consistenza sequenziale è ordinata come una GET.
+const (
+	CREATE_INDEX Operation = "CreateIndex"
+	DROP_INDEX   Operation = "DropIndex"
+	QUERY_INDEX  Operation = "QueryIndex"
+)
+
+// IndexArgs rappresenta la definizione di un indice secondario su un campo dei documenti JSON di un namespace
+type IndexArgs struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Keys      string `json:"keys"`  // Chiavi indicizzate: una chiave esatta, oppure un prefisso seguito da * (ad esempio user:*)
+	Field     string `json:"field"` // Campo indicizzato, nella forma $.campo o $.campo.sottocampo
+}
+
+// IndexInfo descrive un indice secondario presente su una replica
+type IndexInfo struct {
+	Config IndexArgs
+	Keys   int // Numero di chiavi indicizzate, ossia di documenti che contengono il campo
+}
+
+// IndexList contiene gli indici secondari di un namespace, in ordine lessicografico
+type IndexList struct {
+	Indexes []IndexInfo
+}
+
+// QueryArgs rappresenta la ricerca delle chiavi il cui campo indicizzato ha un dato valore
+type QueryArgs struct {
+	Namespace string
+	Index     string
+	Value     []byte // Valore JSON cercato. Un valore che non è JSON valido è cercato come stringa
+}
+
+// QueryResult contiene le chiavi il cui campo indicizzato ha il valore cercato, in ordine lessicografico
+type QueryResult struct {
+	Keys []string
+}
+
+// Validate controlla che il nome dell'indice, le chiavi indicizzate e il campo siano validi
+func (args *IndexArgs) Validate() error {
+	if args.Name == "" {
+		return errors.New("index name can not be empty")
+	}
+	if i := strings.Index(args.Keys, "*"); args.Keys == "" || (i >= 0 && i != len(args.Keys)-1) {
+		return errors.New("indexed keys must be a key or a prefix followed by *")
+	}
+	if !strings.HasPrefix(args.Field, "$.") {
+		return errors.New("indexed field must start with $.")
+	}
+	for _, name := range args.FieldPath() {
+		if name == "" {
+			return errors.New("indexed field can not contain empty names")
+		}
+	}
+	return nil
+}
+
+// FieldPath restituisce i nomi dei campi annidati che portano al campo indicizzato
+func (args *IndexArgs) FieldPath() []string {
+	return strings.Split(strings.TrimPrefix(args.Field, "$."), ".")
+}
+
+// Matches indica se la chiave è tra quelle indicizzate
+func (args *IndexArgs) Matches(key string) bool {
+	if strings.HasSuffix(args.Keys, "*") {
+		return strings.HasPrefix(key, strings.TrimSuffix(args.Keys, "*"))
+	}
+	return key == args.Keys
+}
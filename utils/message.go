@@ -12,11 +12,20 @@ type MessageType string
 
 // Operazioni che possono essere richieste al server
 const (
-	GET    Operation = "Get"
-	PUT    Operation = "Put"
-	DELETE Operation = "Delete"
+	GET     Operation = "Get"
+	PUT     Operation = "Put"
+	DELETE  Operation = "Delete"
+	TXN     Operation = "Txn"
+	EXPIRE  Operation = "Expire"
+	SCAN    Operation = "Scan"
+	SET_TTL Operation = "SetTTL"
 )
 
+// IsRead indica se l'operazione è una lettura, che con consistenza sequenziale è un evento interno alla replica e non viene propagata
+func (op Operation) IsRead() bool {
+	return op == GET || op == SCAN || op == MULTI_GET || op == QUERY_INDEX
+}
+
 // Tipologia dei messaggi
 const (
 	REQUEST MessageType = "REQUEST"
@@ -30,15 +39,38 @@ type MessageIdentifier struct {
 
 // Message rappresenta struttura del messaggio di REQUEST o di ACK
 type Message struct {
-	MessageID    MessageIdentifier `json:"identifier"` // Identificatore univoco del messaggio, permette di associare gli ACK alle REQUEST
-	Key          string            `json:"key"`
-	Value        string            `json:"value"`
-	Op           Operation         `json:"op"`
-	Clock        int               `json:"clock"`
-	Type         MessageType       `json:"type"`
-	ServerID     int               `json:"server_id"` // ID del processo che propaga la REQUEST o l' ACK
-	SeqNum       int               `json:"seq_num"`   // Numero di sequenza che identifica l'ordine con cui partono i messaggi da un server
-	ResponseChan chan string       `json:"-"`
+	MessageID       MessageIdentifier `json:"identifier"` // Identificatore univoco del messaggio, permette di associare gli ACK alle REQUEST
+	Key             string            `json:"key"`
+	Value           []byte            `json:"value"`
+	Op              Operation         `json:"op"`
+	Clock           int               `json:"clock"`
+	Type            MessageType       `json:"type"`
+	ServerID        int               `json:"server_id"`                  // ID del processo che propaga la REQUEST o l' ACK
+	SeqNum          int               `json:"seq_num"`                    // Numero di sequenza che identifica l'ordine con cui partono i messaggi da un server
+	Txn             *TxnArgs          `json:"txn,omitempty"`              // Transazione multi-chiave, presente solo se Op è TXN
+	ExpiresAt       int64             `json:"expires_at,omitempty"`       // Istante di scadenza della entry scritta da una PUT, o impostato da una SET_TTL, in millisecondi
+	Expired         *Version          `json:"expired,omitempty"`          // Versione scaduta che deve essere rimossa, presente solo se Op è EXPIRE
+	Scan            *ScanArgs         `json:"scan,omitempty"`             // Intervallo di chiavi richiesto, presente solo se Op è SCAN
+	Batch           []BatchEntry      `json:"batch,omitempty"`            // Chiavi lette o scritte, presenti solo per le operazioni su più chiavi
+	Namespace       string            `json:"namespace,omitempty"`        // Namespace delle chiavi lette o scritte dal messaggio
+	Path            string            `json:"path,omitempty"`             // Percorso del documento scritto o rimosso, presente solo per SET_PATH e DELETE_PATH
+	BlobID          string            `json:"blob_id,omitempty"`          // Identificatore del valore trasferito a frammenti, che sostituisce Value
+	Chunk           *Chunk            `json:"chunk,omitempty"`            // Frammento di un valore, presente solo se Op è CHUNK
+	NamespaceConfig *NamespaceArgs    `json:"namespace_config,omitempty"` // Configurazione del namespace creato, presente solo se Op è CREATE_NAMESPACE
+	Index           *IndexArgs        `json:"index,omitempty"`            // Definizione dell'indice creato, presente solo se Op è CREATE_INDEX
+	Query           *QueryArgs        `json:"query,omitempty"`            // Valore cercato nell'indice, presente solo se Op è QUERY_INDEX
+	ResponseChan    chan Result       `json:"-"`
+	TxnChan         chan TxnOutcome   `json:"-"` // Canale su cui restituire l'esito della transazione (o della SET_TTL) al client
+	ScanChan        chan ScanResult   `json:"-"` // Canale su cui restituire il risultato della scansione al client
+	BatchChan       chan []Result     `json:"-"` // Canale su cui restituire il risultato della MultiGet al client
+	NamespaceChan   chan error        `json:"-"` // Canale su cui restituire l'esito della creazione o rimozione di un namespace o di un indice al client
+	QueryChan       chan QueryResult  `json:"-"` // Canale su cui restituire il risultato della ricerca nell'indice al client
+}
+
+// Version restituisce la versione assegnata alle scritture prodotte dal messaggio.
+// La coppia (clock, ID del server) identifica univocamente la REQUEST nell'ordine totale.
+func (msg *Message) Version() Version {
+	return Version{Clock: msg.Clock, ServerID: msg.ServerID}
 }
 
 // MessageQueue rappresenta la coda di messaggi mantenuta da ogni server
@@ -110,8 +142,8 @@ func (mq *MessageQueue) PopMessage(idRequester int, numReplicas int) *Message {
 	return nil
 }
 
-// PopGetMessage estrae il messaggio in testa solo se è di tipo GET
-func (mq *MessageQueue) PopGetMessage() *Message {
+// PopReadMessage estrae il messaggio in testa solo se è una lettura (GET o SCAN)
+func (mq *MessageQueue) PopReadMessage() *Message {
 	mq.mutex.Lock()
 	defer mq.mutex.Unlock()
 
@@ -123,8 +155,8 @@ func (mq *MessageQueue) PopGetMessage() *Message {
 	// Prende il messaggio in testa
 	headMessage := mq.messages[0]
 
-	// Verifica se il messaggio in testa è di tipo GET
-	if headMessage.Op == GET {
+	// Verifica se il messaggio in testa è una lettura
+	if headMessage.Op.IsRead() {
 		// Rimuove il messaggio in testa dalla coda
 		mq.messages = mq.messages[1:]
 
@@ -132,7 +164,7 @@ func (mq *MessageQueue) PopGetMessage() *Message {
 		return &headMessage
 	}
 
-	// Se il messaggio in testa non è di tipo GET, ritorna nil
+	// Se il messaggio in testa non è una lettura, ritorna nil
 	return nil
 }
 
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Operazioni di amministrazione dei namespace, propagate secondo il protocollo di consistenza attivo
+const (
+	CREATE_NAMESPACE Operation = "CreateNamespace"
+	DROP_NAMESPACE   Operation = "DropNamespace"
+)
+
+// DefaultNamespace è il namespace delle richieste che non ne indicano uno, sempre presente e non rimovibile
+const DefaultNamespace = ""
+
+// NamespaceArgs rappresenta la configurazione di un namespace, indicata alla sua creazione
+type NamespaceArgs struct {
+	Name           string        `json:"name"`
+	TTL            time.Duration `json:"ttl,omitempty"`             // TTL applicato alle Put che non ne indicano uno, 0 se le entry non scadono
+	Quota          int64         `json:"quota,omitempty"`           // Dimensione massima in byte di chiavi e valori del namespace, 0 se illimitata
+	ConflictPolicy string        `json:"conflict_policy,omitempty"` // Politica di risoluzione dei conflitti del namespace, vuota per usare quella della replica (solo consistenza causale)
+	Documents      bool          `json:"documents,omitempty"`       // Modalità documento: i valori devono essere documenti JSON, modificabili per percorso con SetPath e DeletePath
+}
+
+// NamespaceInfo descrive un namespace presente su una replica
+type NamespaceInfo struct {
+	Config NamespaceArgs
+	Keys   int   // Numero di chiavi presenti nel namespace
+	Size   int64 // Dimensione in byte di chiavi e valori presenti nel namespace
+}
+
+// NamespaceList contiene i namespace presenti su una replica, in ordine lessicografico
+type NamespaceList struct {
+	Namespaces []NamespaceInfo
+}
+
+// Validate controlla che il nome del namespace sia valido e che la configurazione sia ammessa
+func (args *NamespaceArgs) Validate() error {
+	if args.Name == DefaultNamespace {
+		return errors.New("the default namespace can not be created or dropped")
+	}
+	if strings.TrimSpace(args.Name) != args.Name {
+		return errors.New("namespace name can not start or end with spaces")
+	}
+	if args.TTL < 0 || args.Quota < 0 {
+		return errors.New("namespace TTL and quota can not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package utils
+
+// ScanArgs rappresenta la richiesta di lettura di un intervallo di chiavi, in ordine lessicografico.
+// Se Prefix è indicato vengono restituite le chiavi con quel prefisso, altrimenti quelle comprese tra Start (incluso) ed End (escluso).
+// Start ed End vuoti indicano un intervallo illimitato.
+type ScanArgs struct {
+	Namespace string `json:"namespace,omitempty"`
+	Start     string
+	End       string
+	Prefix    string
+	Limit     int    // Numero massimo di entry restituite, se non positivo viene usato un limite di default
+	Cursor    string // Cursore restituito dalla pagina precedente, da cui riprendere la scansione
+}
+
+// ScanResult contiene una pagina di una scansione.
+// Cursor è vuoto se la scansione è terminata, altrimenti va indicato nella richiesta successiva per ottenere la pagina seguente.
+type ScanResult struct {
+	Entries []Result
+	Cursor  string
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+)
+
+type CompareOp string
+
+// Condizioni che possono essere verificate su una chiave all'interno di una transazione
+const (
+	EQUAL     CompareOp = "Equal"    // La chiave esiste e il valore coincide con quello indicato
+	NOT_EQUAL CompareOp = "NotEqual" // La chiave non esiste oppure il valore è diverso da quello indicato
+	EXISTS    CompareOp = "Exists"   // La chiave è presente nello store
+	ABSENT    CompareOp = "Absent"   // La chiave non è presente nello store
+)
+
+// Compare rappresenta una condizione su una chiave, valutata al momento dell'applicazione della transazione
+type Compare struct {
+	Key   string    `json:"key"`
+	Op    CompareOp `json:"op"`
+	Value []byte    `json:"value"`
+}
+
+// TxnOp rappresenta una singola operazione (GET, PUT o DELETE) eseguita all'interno di una transazione
+type TxnOp struct {
+	Op        Operation `json:"op"`
+	Key       string    `json:"key"`
+	Value     []byte    `json:"value"`
+	ExpiresAt int64     `json:"expires_at,omitempty"` // Istante di scadenza della entry scritta da una PUT in millisecondi, 0 se la entry non scade
+}
+
+// TxnArgs rappresenta una transazione multi-chiave.
+// Se tutte le condizioni in Compare sono verificate vengono eseguite le operazioni in Success, altrimenti quelle in Failure.
+type TxnArgs struct {
+	Namespace string    `json:"namespace,omitempty"` // Namespace di tutte le chiavi della transazione
+	Compare   []Compare `json:"compare"`
+	Success   []TxnOp   `json:"success"`
+	Failure   []TxnOp   `json:"failure"`
+}
+
+// TxnResult contiene l'esito della transazione e il risultato delle GET eseguite, nell'ordine in cui sono state richieste
+type TxnResult struct {
+	Succeeded bool
+	Responses []Result
+}
+
+// TxnOutcome è l'esito dell'applicazione di una transazione, restituito dalla replica che l'ha originata al client in attesa
+type TxnOutcome struct {
+	Result TxnResult
+	Err    error // Errore che ha impedito di applicare la transazione, ad esempio la rimozione del namespace prima della delivery
+}
+
+// Validate controlla che la transazione contenga solo condizioni e operazioni ammesse
+func (txn *TxnArgs) Validate() error {
+	if len(txn.Compare) == 0 && len(txn.Success) == 0 && len(txn.Failure) == 0 {
+		return errors.New("empty transaction")
+	}
+	for _, cmp := range txn.Compare {
+		switch cmp.Op {
+		case EQUAL, NOT_EQUAL, EXISTS, ABSENT:
+		default:
+			return fmt.Errorf("invalid compare operation %q on key %s", cmp.Op, cmp.Key)
+		}
+	}
+	for _, ops := range [][]TxnOp{txn.Success, txn.Failure} {
+		for _, op := range ops {
+			switch op.Op {
+			case GET, PUT, DELETE:
+			default:
+				return fmt.Errorf("invalid transaction operation %q on key %s", op.Op, op.Key)
+			}
+		}
+	}
+	return nil
+}
+
+// CASArgs rappresenta una richiesta di compare-and-swap: Value viene scritto solo se il valore corrente di Key coincide con Expected
+type CASArgs struct {
+	Namespace string
+	Key       string
+	Expected  []byte
+	Value     []byte
+}
+
+// CondResult contiene l'esito di una scrittura condizionale e il valore corrente della chiave dopo la sua applicazione
+type CondResult struct {
+	Succeeded bool
+	Key       string
+	Value     []byte
+}
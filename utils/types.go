@@ -1,13 +1,34 @@
 package utils
 
+import (
+	"time"
+)
+
 type Args struct {
-	Key   string
-	Value string
+	Namespace string // Namespace della chiave, vuoto per il namespace di default
+	Key       string
+	Value     []byte
+	TTL       time.Duration // Durata dopo cui la entry scritta da una Put (o aggiornata da una SetTTL) scade e viene rimossa, 0 se la entry non scade
+	Context   []int         // Contesto causale restituito da una Get precedente: la scrittura risolve le versioni concorrenti che esso include (solo consistenza causale)
+	After     []int         // Clock della sessione del client: la replica esegue l'operazione solo dopo aver applicato le scritture che esso include (solo consistenza causale)
+	Order     SessionOrder  // Posizione della richiesta nella sessione del client, con cui la replica ne rispetta l'ordine di invio
+}
+
+// SessionOrder identifica la posizione di una richiesta tra quelle inviate dalla sessione di un client.
+// Il client può inviare più richieste senza attenderne la risposta: la replica esegue ognuna solo dopo aver completato quelle che la precedono nella sessione.
+type SessionOrder struct {
+	Session   string // Identificatore univoco della sessione, vuoto se la richiesta non deve essere ordinata
+	Seq       int    // Numero di sequenza della richiesta nella sessione, a partire da 1
+	Completed int    // Numero di sequenza fino al quale il client ha ricevuto la risposta di tutte le richieste della sessione
 }
 
 type Result struct {
-	Key   string
-	Value string
+	Key       string
+	Value     []byte
+	Version   Version          // Versione della scrittura che ha prodotto il valore restituito
+	Siblings  []VersionedValue // Versioni concorrenti della chiave, presenti solo se le scritture non sono ancora state riconciliate (solo consistenza causale)
+	Context   []int            // Contesto causale che unisce i clock di tutte le versioni restituite (solo consistenza causale)
+	ExpiresAt int64            // Istante di scadenza della entry in millisecondi, 0 se la entry non scade
 }
 
 type ServerAddress struct {
@@ -15,6 +36,22 @@ type ServerAddress struct {
 	Port string
 }
 
+// ExpiresAt restituisce l'istante di scadenza in millisecondi di una entry scritta ora con il TTL indicato, 0 se la entry non scade
+func (args Args) ExpiresAt() int64 {
+	if args.TTL <= 0 {
+		return 0
+	}
+	return time.Now().Add(args.TTL).UnixMilli()
+}
+
+// WithDefaultTTL restituisce la richiesta con il TTL indicato, se il client non ne ha indicato uno
+func (args Args) WithDefaultTTL(ttl time.Duration) Args {
+	if args.TTL <= 0 {
+		args.TTL = ttl
+	}
+	return args
+}
+
 // GetFullAddress restituisce IP e Port separati da ":"
 func (s ServerAddress) GetFullAddress() string {
 	return s.IP + ":" + s.Port
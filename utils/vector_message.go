@@ -7,12 +7,29 @@ import (
 
 // VectorMessage rappresenta la struttura del messaggio scambiato nel multicast causalmente ordinato
 type VectorMessage struct {
-	Key      string    `json:"key"`
-	Value    string    `json:"value"`
-	Op       Operation `json:"op"`
-	Clock    []int     `json:"clock"`
-	ServerID int       `json:"server_id"` // ID del processo che propaga il messaggio
-	SeqNum   int       `json:"seq_num"`   // Numero di sequenza che identifica l'ordine con cui partono i messaggi da un server
+	Key             string          `json:"key"`
+	Value           []byte          `json:"value"`
+	Op              Operation       `json:"op"`
+	Clock           []int           `json:"clock"`
+	Timestamp       HybridTimestamp `json:"timestamp"`                  // Timestamp ibrido della scrittura, usato dalle politiche di risoluzione dei conflitti
+	Context         []int           `json:"context,omitempty"`          // Contesto causale indicato dal client, determina quali versioni concorrenti sono risolte dalla scrittura
+	ExpiresAt       int64           `json:"expires_at,omitempty"`       // Istante di scadenza della entry scritta da una PUT, o impostato da una SET_TTL, in millisecondi
+	Batch           []BatchEntry    `json:"batch,omitempty"`            // Scritture trasportate dal messaggio, presenti solo per MultiPut e MultiDelete
+	CRDT            *CRDTOp         `json:"crdt,omitempty"`             // Parametri dell'operazione, presenti solo per le operazioni sui tipi di dato CRDT
+	Namespace       string          `json:"namespace,omitempty"`        // Namespace delle chiavi scritte dal messaggio
+	Path            string          `json:"path,omitempty"`             // Percorso del documento scritto o rimosso, presente solo per SET_PATH e DELETE_PATH
+	BlobID          string          `json:"blob_id,omitempty"`          // Identificatore del valore trasferito a frammenti, che sostituisce Value
+	Chunk           *Chunk          `json:"chunk,omitempty"`            // Frammento di un valore, presente solo se Op è CHUNK
+	NamespaceConfig *NamespaceArgs  `json:"namespace_config,omitempty"` // Configurazione del namespace creato, presente solo se Op è CREATE_NAMESPACE
+	Index           *IndexArgs      `json:"index,omitempty"`            // Definizione dell'indice creato, presente solo se Op è CREATE_INDEX
+	Expired         *Version        `json:"expired,omitempty"`          // Versione scaduta che deve essere rimossa, presente solo se Op è EXPIRE
+	ServerID        int             `json:"server_id"`                  // ID del processo che propaga il messaggio
+	SeqNum          int             `json:"seq_num"`                    // Numero di sequenza che identifica l'ordine con cui partono i messaggi da un server
+}
+
+// Version restituisce la versione assegnata alla scrittura prodotta dal messaggio, ossia il suo clock vettoriale
+func (msg *VectorMessage) Version() Version {
+	return Version{ServerID: msg.ServerID, VectorClock: msg.Clock, Timestamp: msg.Timestamp}
 }
 
 // VectorMessageQueue rappresenta la coda di messaggi mantenuta da ogni server
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"fmt"
+)
+
+// Version identifica la scrittura che ha prodotto il valore di una entry dello store.
+// Con consistenza sequenziale è data dalla coppia (timestamp di Lamport, ID della replica), che ordina totalmente le scritture.
+// Con consistenza causale è data dal clock vettoriale associato al messaggio di update.
+type Version struct {
+	Clock       int             // Timestamp di Lamport del messaggio di update (consistenza sequenziale)
+	ServerID    int             // ID della replica che ha originato la scrittura
+	VectorClock []int           // Clock vettoriale del messaggio di update (consistenza causale)
+	Timestamp   HybridTimestamp // Timestamp ibrido del messaggio di update, usato per risolvere le scritture concorrenti (consistenza causale)
+}
+
+// HybridTimestamp rappresenta un timestamp di un hybrid logical clock.
+// Combina il tempo fisico con un contatore logico, così da rispettare la causalità anche in presenza di clock fisici non sincronizzati.
+type HybridTimestamp struct {
+	WallTime int64 `json:"wall_time"` // Tempo fisico in millisecondi
+	Logical  int   `json:"logical"`   // Contatore logico, distingue eventi con lo stesso tempo fisico
+}
+
+// Before indica se il timestamp ts precede il timestamp other
+func (ts HybridTimestamp) Before(other HybridTimestamp) bool {
+	if ts.WallTime == other.WallTime {
+		return ts.Logical < other.Logical
+	}
+	return ts.WallTime < other.WallTime
+}
+
+// VersionedValue rappresenta il valore di una chiave insieme alla versione che lo ha prodotto.
+// Una DELETE è rappresentata da un valore con Deleted pari a true.
+type VersionedValue struct {
+	Value     []byte
+	Version   Version
+	Deleted   bool
+	ExpiresAt int64 // Istante di scadenza della entry in millisecondi, 0 se la entry non scade
+}
+
+// GetAtArgs rappresenta la richiesta del valore di una chiave in corrispondenza di una data versione o timestamp
+type GetAtArgs struct {
+	Namespace string
+	Key       string
+	Version   Version // Viene restituito il valore della versione più recente che precede o coincide con Version
+	Timestamp int     // In alternativa a Version, timestamp di Lamport di riferimento (solo consistenza sequenziale)
+}
+
+// IsVector indica se la versione è espressa tramite clock vettoriale
+func (v Version) IsVector() bool {
+	return v.VectorClock != nil
+}
+
+// LessOrEqual indica se la versione v precede o coincide con la versione other.
+// Le versioni scalari sono confrontate per timestamp e, a parità di timestamp, per ID della replica.
+// Le versioni vettoriali sono confrontate componente per componente, quindi due versioni concorrenti non sono ordinate.
+func (v Version) LessOrEqual(other Version) bool {
+	if v.IsVector() || other.IsVector() {
+		if len(v.VectorClock) != len(other.VectorClock) {
+			return false
+		}
+		for k := range v.VectorClock {
+			if v.VectorClock[k] > other.VectorClock[k] {
+				return false
+			}
+		}
+		return true
+	}
+	if v.Clock == other.Clock {
+		return v.ServerID <= other.ServerID
+	}
+	return v.Clock < other.Clock
+}
+
+// Equal indica se v e other identificano la stessa versione, ossia la stessa scrittura
+func (v Version) Equal(other Version) bool {
+	if v.ServerID != other.ServerID || v.Clock != other.Clock || len(v.VectorClock) != len(other.VectorClock) {
+		return false
+	}
+	for k := range v.VectorClock {
+		if v.VectorClock[k] != other.VectorClock[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// Merge restituisce la versione che include sia v sia other:
+// l'unione componente per componente dei clock vettoriali, oppure la maggiore tra due versioni scalari
+func (v Version) Merge(other Version) Version {
+	if !other.IsVector() && other.Clock == 0 {
+		return v
+	}
+	if !v.IsVector() && v.Clock == 0 {
+		return other
+	}
+	if v.IsVector() {
+		return Version{VectorClock: MergeClocks(v.VectorClock, other.VectorClock)}
+	}
+	if other.LessOrEqual(v) {
+		return v
+	}
+	return other
+}
+
+// MergeClocks restituisce il clock vettoriale ottenuto come massimo componente per componente dei clock indicati
+func MergeClocks(clocks ...[]int) []int {
+	var merged []int
+	for _, clock := range clocks {
+		if merged == nil {
+			merged = make([]int, len(clock))
+		}
+		for k := 0; k < len(clock) && k < len(merged); k++ {
+			if clock[k] > merged[k] {
+				merged[k] = clock[k]
+			}
+		}
+	}
+	return merged
+}
+
+// String restituisce una rappresentazione leggibile della versione
+func (v Version) String() string {
+	if v.IsVector() {
+		return fmt.Sprintf("%v@%d", v.VectorClock, v.ServerID)
+	}
+	return fmt.Sprintf("(%d,%d)", v.Clock, v.ServerID)
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"time"
+)
+
+// WatchArgs rappresenta la richiesta di osservare le modifiche di una chiave, o di tutte le chiavi con un dato prefisso.
+// La richiesta attende finché non si verifica almeno una modifica, oppure finché non scade il Timeout.
+type WatchArgs struct {
+	Namespace    string
+	Key          string
+	Prefix       bool          // Se true, Key è interpretata come prefisso
+	FromRevision int           // Revisione da cui riprendere l'osservazione, 0 per osservare solo le modifiche successive alla richiesta
+	FromVersion  *Version      // Se presente, l'osservazione riprende dalle modifiche non incluse nella versione, anche su una replica diversa, e FromRevision è ignorata
+	Timeout      time.Duration // Tempo massimo di attesa, se non positivo viene usato un timeout di default
+}
+
+// WatchEvent descrive una modifica applicata dalla replica a una chiave
+type WatchEvent struct {
+	Revision int       // Revisione locale alla replica, cresce di 1 a ogni modifica applicata
+	Op       Operation // PUT oppure DELETE
+	Key      string
+	OldValue []byte  // Valore precedente della chiave, "NOT FOUND" se la chiave non esisteva
+	NewValue []byte  // Nuovo valore della chiave, "NOT FOUND" se la chiave è stata rimossa
+	Version  Version // Versione della scrittura che ha prodotto la modifica
+}
+
+// WatchResult contiene le modifiche osservate.
+// Revision è la revisione da indicare nella richiesta successiva per riprendere l'osservazione senza perdere modifiche sulla stessa replica,
+// mentre Version, indicata come FromVersion, permette di riprenderla su qualsiasi replica.
+type WatchResult struct {
+	Events   []WatchEvent
+	Revision int
+	Version  Version // Versione delle modifiche applicate dalla replica (ultima nell'ordine totale, oppure unione dei clock vettoriali)
+}